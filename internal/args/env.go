@@ -1,30 +1,102 @@
 package args
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-func EnvOrDefault[T any](key string, defaultValue T) T {
+var (
+	mu   sync.Mutex
+	errs []error
+)
+
+// Env returns the environment variable key parsed as T, or defaultValue when it isn't set. T is one of
+// string, int, int64, float64, bool, time.Duration or []string (comma separated).
+func Env[T any](key string, defaultValue T) (T, error) {
 	value, exists := os.LookupEnv(key)
 	if !exists {
-		return defaultValue
+		return defaultValue, nil
+	}
+	v, err := Parse[T](value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s: %w", key, err)
+	}
+	return v, nil
+}
+
+// EnvOrDefault is Env for flag defaults: a value that doesn't parse keeps defaultValue and its error is
+// kept for Err, so startup can fail with every bad variable at once.
+func EnvOrDefault[T any](key string, defaultValue T) T {
+	v, err := Env(key, defaultValue)
+	if err != nil {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
 	}
+	return v
+}
+
+// Err returns the errors of the EnvOrDefault calls so far, or nil.
+func Err() error {
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// Parse parses s as T, see Env for the supported types.
+func Parse[T any](s string) (T, error) {
 	var v T
+	var parsed any
+	var err error
 	switch any(v).(type) {
 	case string:
-		return any(value).(T)
+		parsed = s
 	case int:
-		//nolint:errcheck
-		var intValue int
-		_, _ = fmt.Sscanf(value, "%d", &intValue)
-		return any(intValue).(T)
+		parsed, err = strconv.Atoi(strings.TrimSpace(s))
+	case int64:
+		parsed, err = strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case float64:
+		parsed, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
 	case bool:
-		//nolint:errcheck
-		var boolValue bool
-		_, _ = fmt.Sscanf(value, "%t", &boolValue)
-		return any(boolValue).(T)
+		parsed, err = strconv.ParseBool(strings.TrimSpace(s))
+	case time.Duration:
+		parsed, err = ParseDuration(s)
+	case []string:
+		parsed = SplitList(s)
 	default:
-		return defaultValue
+		return v, fmt.Errorf("unsupported type %T", v)
+	}
+	if err != nil {
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+		return v, fmt.Errorf("invalid %T %q: %w", v, s, err)
+	}
+	return parsed.(T), nil
+}
+
+// ParseDuration parses a Go duration like 1m30s, or a plain number of seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// SplitList splits a comma separated list, dropping blank entries.
+func SplitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
 	}
+	return list
 }
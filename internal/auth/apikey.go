@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type apiKey struct {
+	name   string
+	scopes []string
+}
+
+// HashKey returns the hex SHA-256 of key as stored in the API keys file. API keys are random, so a fast
+// hash is enough to keep a leaked file from being usable.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateKey returns a new random API key.
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "gf_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// loadAPIKeys reads lines of "name sha256-hex scope,scope". Blank lines and lines starting with # are skipped.
+func loadAPIKeys(path string) (map[string]apiKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := make(map[string]apiKey)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: want name, sha256 and scopes", path, n)
+		}
+		hash := strings.ToLower(fields[1])
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%s:%d: %q is not a hex sha256", path, n, fields[1])
+		}
+		scopes, err := ParseScopes(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		keys[hash] = apiKey{name: fields[0], scopes: scopes}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ParseScopes parses a comma separated list of scopes.
+func ParseScopes(s string) ([]string, error) {
+	var scopes []string
+	for _, v := range strings.Split(s, ",") {
+		switch Scope(v) {
+		case ScopeConvert, ScopeStore, ScopeAdmin:
+			scopes = append(scopes, v)
+		default:
+			return nil, fmt.Errorf("unknown scope %q", v)
+		}
+	}
+	return scopes, nil
+}
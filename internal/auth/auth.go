@@ -0,0 +1,157 @@
+// Package auth authenticates API requests with static API keys or JWT bearer tokens and checks
+// that the caller has the scope a route requires.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+)
+
+// Scope is a permission a route requires.
+type Scope string
+
+const (
+	ScopeConvert Scope = "convert" // Convert, validate and edit uploads
+	ScopeStore   Scope = "store"   // Read and write the activity store
+	ScopeAdmin   Scope = "admin"   // Operational endpoints, implies every other scope
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject string // API key name or JWT subject
+	Method  string // api_key or jwt
+	Scopes  []string
+}
+
+// Has reports whether p was granted scope, admin grants all of them.
+func (p Principal) Has(scope Scope) bool {
+	return slices.Contains(p.Scopes, string(scope)) || slices.Contains(p.Scopes, string(ScopeAdmin))
+}
+
+type principalKey struct{}
+
+// FromContext returns the principal of an authenticated request.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Athlete returns the athlete a request acts for. Authenticated callers act for themselves, admins for
+// whichever athlete they name in requested. Without authentication requested is used as is. ok is false
+// when a caller who is not an admin names another athlete.
+func Athlete(ctx context.Context, requested string) (athlete string, ok bool) {
+	p, authenticated := FromContext(ctx)
+	switch {
+	case !authenticated || p.Has(ScopeAdmin):
+		return requested, true
+	case requested == "" || requested == p.Subject:
+		return p.Subject, true
+	}
+	return "", false
+}
+
+// Config selects the accepted credentials. Empty values disable that kind of credential.
+type Config struct {
+	APIKeysFile string // Lines of "name sha256-hex scope,scope"
+	JWKSFile    string // JSON Web Key Set with the public keys of the token issuer
+	JWTSecret   string // Shared secret of HS256/384/512 tokens
+	JWTIssuer   string // Required iss claim
+	JWTAudience string // Required aud claim
+}
+
+var (
+	errNoCredentials = errors.New("no credentials")
+	errInvalidKey    = errors.New("invalid API key")
+)
+
+// Authenticator verifies the credentials of requests.
+type Authenticator struct {
+	keys map[string]apiKey // by sha256 hex of the key
+	jwt  *jwtVerifier
+}
+
+// New loads the key files of cfg. With nothing configured the returned Authenticator is disabled
+// and lets every request through.
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{}
+	if cfg.APIKeysFile != "" {
+		keys, err := loadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("api keys: %w", err)
+		}
+		a.keys = keys
+	}
+	if cfg.JWKSFile != "" || cfg.JWTSecret != "" {
+		v, err := newJWTVerifier(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %w", err)
+		}
+		a.jwt = v
+	}
+	return a, nil
+}
+
+// Enabled reports whether any credentials are configured.
+func (a *Authenticator) Enabled() bool {
+	return a.keys != nil || a.jwt != nil
+}
+
+// Require returns a middleware that answers 401 to requests without valid credentials and 403 to
+// callers without scope. The principal is put in the request context.
+func (a *Authenticator) Require(scope Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !a.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := a.authenticate(r)
+			if err != nil {
+				challenge, code := `Bearer realm="go-fitter"`, problem.CodeUnauthorized
+				if !errors.Is(err, errNoCredentials) {
+					challenge, code = challenge+`, error="invalid_token"`, problem.CodeInvalidToken
+				}
+				w.Header().Set("WWW-Authenticate", challenge)
+				problem.Write(w, r, http.StatusUnauthorized, code, err.Error())
+				return
+			}
+			if !p.Has(scope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="go-fitter", error="insufficient_scope", scope="%s"`, scope))
+				problem.Write(w, r, http.StatusForbidden, problem.CodeInsufficientScope, fmt.Sprintf("scope %q required", scope))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+		})
+	}
+}
+
+// authenticate checks the X-API-Key header or the bearer token of the Authorization header.
+func (a *Authenticator) authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.checkAPIKey(key)
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, errNoCredentials
+	}
+	token = strings.TrimSpace(token)
+	// A JWT has three dot separated parts, anything else sent as bearer token is taken for an API key.
+	if strings.Count(token, ".") == 2 && a.jwt != nil {
+		return a.jwt.verify(token)
+	}
+	return a.checkAPIKey(token)
+}
+
+func (a *Authenticator) checkAPIKey(key string) (Principal, error) {
+	k, ok := a.keys[HashKey(key)]
+	if !ok {
+		return Principal{}, errInvalidKey
+	}
+	return Principal{Subject: k.name, Method: "api_key", Scopes: k.scopes}, nil
+}
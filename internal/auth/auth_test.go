@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	secret       = "0123456789abcdef0123456789abcdef"
+	convertKey   = "gf_convert"
+	adminKey     = "gf_admin"
+	testIssuer   = "https://issuer.example"
+	testAudience = "go-fitter"
+)
+
+func writeKeys(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	lines := "# comment\n\nci " + HashKey(convertKey) + " convert\nops " + HashKey(adminKey) + " admin\n"
+	if err := os.WriteFile(path, []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func token(t *testing.T, method jwt.SigningMethod, claims jwt.MapClaims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRequire(t *testing.T) {
+	a, err := New(Config{APIKeysFile: writeKeys(t), JWTSecret: secret, JWTIssuer: testIssuer, JWTAudience: testAudience})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := jwt.MapClaims{"sub": "jane", "iss": testIssuer, "aud": testAudience, "exp": exp, "scope": "store convert"}
+	scp := jwt.MapClaims{"sub": "bob", "iss": testIssuer, "aud": testAudience, "exp": exp, "scp": []any{"convert"}}
+
+	tests := []struct {
+		name          string
+		header        string
+		value         string
+		scope         Scope
+		wantStatus    int
+		wantPrincipal Principal
+	}{
+		{name: "no credentials", scope: ScopeConvert, wantStatus: 401},
+		{name: "API key header", header: "X-API-Key", value: convertKey, scope: ScopeConvert, wantStatus: 200,
+			wantPrincipal: Principal{Subject: "ci", Method: "api_key"}},
+		{name: "API key as bearer token", header: "Authorization", value: "Bearer " + convertKey, scope: ScopeConvert, wantStatus: 200,
+			wantPrincipal: Principal{Subject: "ci", Method: "api_key"}},
+		{name: "unknown API key", header: "X-API-Key", value: "gf_nope", scope: ScopeConvert, wantStatus: 401},
+		{name: "missing scope", header: "X-API-Key", value: convertKey, scope: ScopeStore, wantStatus: 403},
+		{name: "admin implies every scope", header: "X-API-Key", value: adminKey, scope: ScopeStore, wantStatus: 200,
+			wantPrincipal: Principal{Subject: "ops", Method: "api_key"}},
+		{name: "JWT", header: "Authorization", value: "Bearer " + token(t, jwt.SigningMethodHS256, valid), scope: ScopeStore, wantStatus: 200,
+			wantPrincipal: Principal{Subject: "jane", Method: "jwt"}},
+		{name: "JWT with scp array", header: "Authorization", value: "Bearer " + token(t, jwt.SigningMethodHS256, scp), scope: ScopeConvert, wantStatus: 200,
+			wantPrincipal: Principal{Subject: "bob", Method: "jwt"}},
+		{name: "JWT without scope", header: "Authorization", scope: ScopeAdmin, wantStatus: 403,
+			value: "Bearer " + token(t, jwt.SigningMethodHS256, valid)},
+		{name: "expired JWT", header: "Authorization", scope: ScopeConvert, wantStatus: 401,
+			value: "Bearer " + token(t, jwt.SigningMethodHS256, jwt.MapClaims{"sub": "jane", "iss": testIssuer, "aud": testAudience, "exp": time.Now().Add(-time.Hour).Unix(), "scope": "convert"})},
+		{name: "JWT without exp", header: "Authorization", scope: ScopeConvert, wantStatus: 401,
+			value: "Bearer " + token(t, jwt.SigningMethodHS256, jwt.MapClaims{"sub": "jane", "iss": testIssuer, "aud": testAudience, "scope": "convert"})},
+		{name: "JWT of another issuer", header: "Authorization", scope: ScopeConvert, wantStatus: 401,
+			value: "Bearer " + token(t, jwt.SigningMethodHS256, jwt.MapClaims{"sub": "jane", "iss": "https://other.example", "aud": testAudience, "exp": exp, "scope": "convert"})},
+		{name: "JWT for another audience", header: "Authorization", scope: ScopeConvert, wantStatus: 401,
+			value: "Bearer " + token(t, jwt.SigningMethodHS256, jwt.MapClaims{"sub": "jane", "iss": testIssuer, "aud": "other", "exp": exp, "scope": "convert"})},
+		{name: "unsigned JWT", header: "Authorization", scope: ScopeConvert, wantStatus: 401,
+			value: "Bearer eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJzdWIiOiJqYW5lIiwic2NvcGUiOiJjb252ZXJ0In0."},
+		{name: "basic auth", header: "Authorization", value: "Basic amFuZTpzZWNyZXQ=", scope: ScopeConvert, wantStatus: 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Principal
+			h := a.Require(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = FromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/fit", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("no WWW-Authenticate challenge")
+			}
+			if got.Subject != tt.wantPrincipal.Subject || got.Method != tt.wantPrincipal.Method {
+				t.Errorf("principal = %s %s, want %s %s", got.Method, got.Subject, tt.wantPrincipal.Method, tt.wantPrincipal.Subject)
+			}
+		})
+	}
+}
+
+func TestRequireDisabled(t *testing.T) {
+	a, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Enabled() {
+		t.Fatal("Enabled() = true without credentials")
+	}
+	w := httptest.NewRecorder()
+	a.Require(ScopeAdmin)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	hash := HashKey(convertKey)
+	tests := []struct {
+		name    string
+		lines   string
+		want    int
+		wantErr bool
+	}{
+		{name: "keys, comments and blank lines", lines: "# keys\n\nci " + hash + " convert,store\n", want: 1},
+		{name: "missing scopes", lines: "ci " + hash + "\n", wantErr: true},
+		{name: "not a sha256", lines: "ci abc convert\n", wantErr: true},
+		{name: "unknown scope", lines: "ci " + hash + " delete\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.txt")
+			if err := os.WriteFile(path, []byte(tt.lines), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			keys, err := loadAPIKeys(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadAPIKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(keys) != tt.want {
+				t.Errorf("loadAPIKeys() = %d keys, want %d", len(keys), tt.want)
+			}
+		})
+	}
+}
+
+func TestAthlete(t *testing.T) {
+	jane := Principal{Subject: "jane", Method: "jwt", Scopes: []string{"store"}}
+	ops := Principal{Subject: "ops", Method: "api_key", Scopes: []string{"admin"}}
+	tests := []struct {
+		name      string
+		principal *Principal
+		requested string
+		want      string
+		wantOK    bool
+	}{
+		{name: "without authentication", requested: "john", want: "john", wantOK: true},
+		{name: "without authentication or athlete", requested: "", want: "", wantOK: true},
+		{name: "caller", principal: &jane, requested: "", want: "jane", wantOK: true},
+		{name: "caller by name", principal: &jane, requested: "jane", want: "jane", wantOK: true},
+		{name: "another athlete", principal: &jane, requested: "john", wantOK: false},
+		{name: "admin for another athlete", principal: &ops, requested: "john", want: "john", wantOK: true},
+		{name: "admin for every athlete", principal: &ops, requested: "", want: "", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = context.WithValue(ctx, principalKey{}, *tt.principal)
+			}
+			got, ok := Athlete(ctx, tt.requested)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Athlete(%q) = %q, %v, want %q, %v", tt.requested, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
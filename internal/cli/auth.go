@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/auth"
+	"github.com/kyzrfranz/go-fitter/internal/config"
+)
+
+// AuthFlags registers the authentication flags on fs, defaulting to the AUTH_* environment variables and
+// then to c. The JWT secret is only read from AUTH_JWT_SECRET, so it doesn't show up in the process list.
+func AuthFlags(fs *flag.FlagSet, c *config.Auth) {
+	fs.StringVar(&c.APIKeysFile, "api-keys", args.EnvOrDefault("AUTH_API_KEYS_FILE", c.APIKeysFile), "File of hashed API keys, see go-fitter apikey")
+	fs.StringVar(&c.JWKSFile, "jwks", args.EnvOrDefault("AUTH_JWKS_FILE", c.JWKSFile), "JSON Web Key Set file to verify JWT bearer tokens")
+	fs.StringVar(&c.JWTIssuer, "jwt-issuer", args.EnvOrDefault("AUTH_JWT_ISSUER", c.JWTIssuer), "Required issuer of JWT bearer tokens")
+	fs.StringVar(&c.JWTAudience, "jwt-audience", args.EnvOrDefault("AUTH_JWT_AUDIENCE", c.JWTAudience), "Required audience of JWT bearer tokens")
+	c.JWTSecret = args.EnvOrDefault("AUTH_JWT_SECRET", "")
+}
+
+// APIKey generates a new API key and prints it along with the line to add to the API keys file.
+func APIKey(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	name := fs.String("name", "default", "Name of the key, shown in logs")
+	scopes := fs.String("scopes", string(auth.ScopeConvert), "Comma separated scopes: convert, store, admin")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go-fitter apikey [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageError(stderr, fs, err)
+	}
+	if strings.ContainsAny(*name, " \t") || *name == "" {
+		return usageError(stderr, fs, fmt.Errorf("name %q must be a single word", *name))
+	}
+	if _, err := auth.ParseScopes(*scopes); err != nil {
+		return usageError(stderr, fs, err)
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	fmt.Fprintf(stdout, "key:  %s\nline: %s %s %s\n", key, *name, auth.HashKey(key), *scopes)
+	return ExitOK
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyzrfranz/go-fitter/pkg/archive"
+	"github.com/kyzrfranz/go-fitter/pkg/importer"
+)
+
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+
+	stdinName = "-"
+)
+
+// input is a single file given on the command line, or stdin.
+type input struct {
+	name string
+	data []byte
+}
+
+// parseInterspersed parses fs but, unlike flag.FlagSet.Parse, keeps going after positional arguments
+// so that `convert in.fit -o out.json` works the same as `convert -o out.json in.fit`. Everything
+// after "--" is positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// expandInputs resolves glob patterns and reads every input. No arguments or "-" read from stdin.
+func expandInputs(args []string, stdin io.Reader) ([]input, error) {
+	if len(args) == 0 {
+		args = []string{stdinName}
+	}
+
+	var names []string
+	for _, arg := range args {
+		if arg == stdinName || !strings.ContainsAny(arg, "*?[") {
+			names = append(names, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no files match", arg)
+		}
+		names = append(names, matches...)
+	}
+
+	inputs := make([]input, 0, len(names))
+	for _, name := range names {
+		var data []byte
+		var err error
+		if name == stdinName {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// .fit.gz, zip and tar.gz inputs are unpacked, several FIT entries become one chained file.
+		// GPX and TCX inputs are converted to FIT.
+		if data, err = archive.Unpack(name, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if data, err = importer.ToFIT(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		inputs = append(inputs, input{name: name, data: data})
+	}
+	return inputs, nil
+}
+
+func (in input) reader() io.Reader {
+	return bytes.NewReader(in.data)
+}
+
+// baseName is the input file name without directory and extension, "stdin" for stdin.
+func (in input) baseName() string {
+	if in.name == stdinName {
+		return "stdin"
+	}
+	base := filepath.Base(in.name)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func usageError(stderr io.Writer, fs *flag.FlagSet, err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	fmt.Fprintln(stderr, err)
+	fs.Usage()
+	return ExitUsage
+}
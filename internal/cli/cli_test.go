@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		wantPositional []string
+		wantOutput     string
+		wantPretty     bool
+		wantErr        bool
+	}{
+		{name: "flags first", args: []string{"-o", "out.json", "-pretty", "in.fit"}, wantPositional: []string{"in.fit"}, wantOutput: "out.json", wantPretty: true},
+		{name: "flags last", args: []string{"in.fit", "-o", "out.json"}, wantPositional: []string{"in.fit"}, wantOutput: "out.json"},
+		{name: "flags between", args: []string{"a.fit", "-pretty", "b.fit"}, wantPositional: []string{"a.fit", "b.fit"}, wantPretty: true},
+		{name: "stdin", args: []string{"-", "-o", "out.json"}, wantPositional: []string{"-"}, wantOutput: "out.json"},
+		{name: "files after --", args: []string{"a.fit", "--", "-o", "b.fit"}, wantPositional: []string{"a.fit", "-o", "b.fit"}},
+		{name: "no arguments", args: nil},
+		{name: "unknown flag", args: []string{"in.fit", "-x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			output := fs.String("o", "", "")
+			pretty := fs.Bool("pretty", false, "")
+
+			got, err := parseInterspersed(fs, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseInterspersed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(got, tt.wantPositional) || *output != tt.wantOutput || *pretty != tt.wantPretty {
+				t.Errorf("parseInterspersed() = %q, -o %q, -pretty %v, want %q, -o %q, -pretty %v",
+					got, *output, *pretty, tt.wantPositional, tt.wantOutput, tt.wantPretty)
+			}
+		})
+	}
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.fit", "b.fit", "c.gpx"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data of "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantNames []string
+		wantErr   string
+	}{
+		{name: "no arguments read stdin", args: nil, wantNames: []string{"-"}},
+		{name: "stdin", args: []string{"-"}, wantNames: []string{"-"}},
+		{name: "files in order", args: []string{filepath.Join(dir, "b.fit"), filepath.Join(dir, "a.fit")}, wantNames: []string{"b.fit", "a.fit"}},
+		{name: "glob", args: []string{filepath.Join(dir, "*.fit")}, wantNames: []string{"a.fit", "b.fit"}},
+		{name: "glob without matches", args: []string{filepath.Join(dir, "*.tcx")}, wantErr: "no files match"},
+		{name: "missing file", args: []string{filepath.Join(dir, "d.fit")}, wantErr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := expandInputs(tt.args, strings.NewReader("data of stdin"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandInputs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandInputs() error = %v", err)
+			}
+
+			var names []string
+			for _, in := range inputs {
+				names = append(names, filepath.Base(in.name))
+				if want := "data of " + in.baseName() + filepath.Ext(in.name); string(in.data) != want {
+					t.Errorf("%s = %q, want %q", in.name, in.data, want)
+				}
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("expandInputs() = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestOutputPaths(t *testing.T) {
+	dir := t.TempDir()
+	inputs := func(names ...string) []input {
+		var in []input
+		for _, name := range names {
+			in = append(in, input{name: name})
+		}
+		return in
+	}
+
+	tests := []struct {
+		name    string
+		inputs  []input
+		output  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single input to stdout", inputs: inputs("rides/a.fit"), want: []string{""}},
+		{name: "single input to a file", inputs: inputs("rides/a.fit"), output: "out/ride.gpx", want: []string{"out/ride.gpx"}},
+		{name: "single input into a directory", inputs: inputs("rides/a.fit"), output: dir, want: []string{filepath.Join(dir, "a.gpx")}},
+		{name: "several inputs next to their source", inputs: inputs("rides/a.fit", "runs/b.fit"), want: []string{"rides/a.gpx", "runs/b.gpx"}},
+		{name: "several inputs into a new directory", inputs: inputs("rides/a.fit", "runs/b.fit"), output: "out/", want: []string{"out/a.gpx", "out/b.gpx"}},
+		{name: "several inputs without an extension", inputs: inputs("a.fit", "b.fit"), output: "out", want: []string{"out/a.gpx", "out/b.gpx"}},
+		{name: "several inputs to stdout", inputs: inputs("a.fit", "b.fit"), output: "-", want: []string{"", ""}},
+		{name: "stdin among several inputs", inputs: inputs("a.fit", "-"), want: []string{"a.gpx", ""}},
+		{name: "same names get a counter", inputs: inputs("rides/a.fit", "runs/a.fit", "a.fit.gz"), output: "out/",
+			want: []string{"out/a.gpx", "out/a-2.gpx", "out/a.fit.gpx"}},
+		{name: "same name twice next to the source", inputs: inputs("a.fit", "a.FIT"), want: []string{"a.gpx", "a-2.gpx"}},
+		{name: "several inputs to a file", inputs: inputs("a.fit", "b.fit"), output: "out.gpx", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := outputPaths(tt.inputs, converters.FormatGPX, tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("outputPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("outputPaths() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnique(t *testing.T) {
+	taken := make(map[string]bool)
+	var got []string
+	for _, path := range []string{"a.gpx", "a.gpx", "a-2.gpx", "a.gpx", "b"} {
+		got = append(got, unique(path, taken))
+	}
+	if want := []string{"a.gpx", "a-2.gpx", "a-2-2.gpx", "a-3.gpx", "b"}; !slices.Equal(got, want) {
+		t.Errorf("unique() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,15 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/config"
+)
+
+// CompressionFlags registers the response compression flags on fs, defaulting to the COMPRESS_*
+// environment variables and then to c.
+func CompressionFlags(fs *flag.FlagSet, c *config.Compression) {
+	listVar(fs, &c.Encodings, "compress", "COMPRESS_ENCODINGS", "Response encodings in order of preference: zstd, gzip, deflate; empty turns compression off")
+	fs.IntVar(&c.MinSize, "compress-min-size", args.EnvOrDefault("COMPRESS_MIN_SIZE", c.MinSize), "Smallest response in bytes that is compressed")
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/config"
+)
+
+// ServeConfig registers the server flags on fs, parses args and returns the configuration made of the
+// defaults, the file given with -config or CONFIG_FILE, the environment and the flags, each overriding the
+// ones before. It fails on a config file or environment variable that doesn't parse and on settings that
+// don't validate.
+func ServeConfig(fs *flag.FlagSet, arguments []string) (config.Config, error) {
+	path := configPath(arguments)
+	c := config.Default()
+	if path != "" {
+		var err error
+		if c, err = config.Load(path); err != nil {
+			return c, err
+		}
+	}
+	fs.String("config", path, "YAML or TOML config file, overridden by environment variables and flags")
+
+	s := &c.Server
+	fs.IntVar(&s.Port, "port", args.EnvOrDefault("SERVER_PORT", s.Port), "Port for the API server")
+	fs.IntVar(&s.Workers, "workers", args.EnvOrDefault("WORKERS", s.Workers), "Maximum number of concurrent conversions")
+	fs.IntVar(&s.MaxUploadMB, "max-upload-mb", args.EnvOrDefault("MAX_UPLOAD_MB", s.MaxUploadMB), "Largest accepted upload in MiB, 0 for no limit")
+	durationVar(fs, &s.ShutdownDelay, "shutdown-delay", "SHUTDOWN_DELAY", "How long to keep serving after a shutdown signal while /readyz fails")
+	fs.StringVar(&s.DocsDir, "docs-dir", args.EnvOrDefault("DOCS_DIR", s.DocsDir), "Directory of openapi.yaml and the API docs, empty to not serve them")
+	fs.StringVar(&c.Storage.DataDir, "data-dir", args.EnvOrDefault("DATA_DIR", c.Storage.DataDir), "Directory of the activity store")
+
+	fs.BoolVar(&c.Convert.Records, "records", args.EnvOrDefault("CONVERT_RECORDS", c.Convert.Records), "Include records in converted JSON unless a request sets ?records=")
+	fs.BoolVar(&c.Convert.Degrees, "degrees", args.EnvOrDefault("CONVERT_DEGREES", c.Convert.Degrees), "Positions in degrees instead of semicircles unless a request sets ?degrees=")
+	fs.BoolVar(&c.Convert.VerifyChecksum, "verify-checksum", args.EnvOrDefault("CONVERT_VERIFY_CHECKSUM", c.Convert.VerifyChecksum), "Reject files with a bad CRC unless a request sets ?verify_checksum=")
+
+	a := &c.Athlete
+	fs.Float64Var(&a.FTP, "ftp", args.EnvOrDefault("ATHLETE_FTP", a.FTP), "Default functional threshold power in watts")
+	fs.Float64Var(&a.LTHR, "lthr", args.EnvOrDefault("ATHLETE_LTHR", a.LTHR), "Default lactate threshold heart rate in bpm")
+	fs.Float64Var(&a.MaxHR, "max-hr", args.EnvOrDefault("ATHLETE_MAX_HR", a.MaxHR), "Default maximum heart rate in bpm")
+	fs.Float64Var(&a.RestHR, "rest-hr", args.EnvOrDefault("ATHLETE_REST_HR", a.RestHR), "Default resting heart rate in bpm")
+
+	AuthFlags(fs, &c.Auth)
+	RateLimitFlags(fs, &c.Limits)
+	CORSFlags(fs, &c.Server.CORS)
+	CompressionFlags(fs, &c.Server.Compression)
+	TLSFlags(fs, &c.Server.TLS)
+	privacyErr := privacyConfigFlags(fs, &c.Privacy)
+
+	if err := errors.Join(args.Err(), privacyErr); err != nil {
+		return c, fmt.Errorf("environment: %w", err)
+	}
+	if err := fs.Parse(arguments); err != nil {
+		return c, err
+	}
+	return c, c.Validate()
+}
+
+// DumpConfig implements `go-fitter dump-config [flags]`. It takes the flags of serve and prints the
+// configuration the server would run with, which makes a good starting point for a config file.
+func DumpConfig(arguments []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dump-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "yaml", "Output format: yaml or toml")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go-fitter dump-config [-format yaml|toml] [serve flags]")
+		fs.PrintDefaults()
+	}
+
+	c, err := ServeConfig(fs, arguments)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+	if err := c.Dump(stdout, *format); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+// configPath finds -config in arguments before they are parsed, since the file provides the defaults of
+// the other flags. It falls back to CONFIG_FILE.
+func configPath(arguments []string) string {
+	for i, arg := range arguments {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(arguments) {
+			return arguments[i+1]
+		}
+	}
+	return args.EnvOrDefault("CONFIG_FILE", "")
+}
+
+// listVar registers a comma separated list flag defaulting to env, then to the current value of p.
+func listVar(fs *flag.FlagSet, p *[]string, name, env string, usage string) {
+	*p = args.EnvOrDefault(env, *p)
+	fs.Func(name, usage+" (default "+strings.Join(*p, ",")+")", func(s string) error {
+		*p = args.SplitList(s)
+		return nil
+	})
+}
+
+// durationVar registers a duration flag defaulting to env, then to the current value of p. Values are
+// written like 1m30s, a plain number is seconds.
+func durationVar(fs *flag.FlagSet, p *config.Duration, name, env string, usage string) {
+	*p = config.Duration(args.EnvOrDefault(env, time.Duration(*p)))
+	fs.Func(name, fmt.Sprintf("%s, like 30s or 5m (default %s)", usage, time.Duration(*p)), func(s string) error {
+		d, err := args.ParseDuration(s)
+		*p = config.Duration(d)
+		return err
+	})
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	"github.com/muktihari/fit/decoder"
+)
+
+type convertOptions struct {
+	format   string
+	output   string
+	checksum bool
+	converters.Options
+}
+
+// Convert implements `go-fitter convert [flags] [file|glob|-]...`.
+//
+// A single input is written to stdout unless -o names a file. Several inputs are written next to
+// their source, or into the directory given with -o. Outputs that would overwrite each other get a
+// counter appended, a-2.json after a.json.
+func Convert(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var opts convertOptions
+
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.format, "format", "json", "Output format: json, ndjson, gpx, tcx, csv or geojson")
+	fs.StringVar(&opts.output, "o", "", "Output file or directory, - for stdout")
+	fs.BoolVar(&opts.Records, "records", false, "Include records in the JSON output")
+	fs.BoolVar(&opts.Degrees, "degrees", false, "Print positions in degrees instead of semicircles")
+	fs.BoolVar(&opts.Pretty, "pretty", true, "Pretty-print JSON and GPX output")
+	fs.BoolVar(&opts.checksum, "checksum", false, "Fail on CRC checksum mismatch")
+	if err := PrivacyFlags(fs, &opts.Privacy); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go-fitter convert [flags] [file|glob|-]...")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return usageError(stderr, fs, err)
+	}
+	format, err := converters.ParseFormat(opts.format)
+	if err != nil {
+		return usageError(stderr, fs, err)
+	}
+
+	inputs, err := expandInputs(positional, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	paths, err := outputPaths(inputs, format, opts.output)
+	if err != nil {
+		return usageError(stderr, fs, err)
+	}
+
+	code := ExitOK
+	for i, in := range inputs {
+		result, err := convertInput(in, format, opts)
+		if err == nil {
+			err = writeOutput(paths[i], result, stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", in.name, err)
+			code = ExitFailure
+		}
+	}
+	return code
+}
+
+func convertInput(in input, format converters.Format, opts convertOptions) (string, error) {
+	var decoderOptions []decoder.Option
+	if !opts.checksum {
+		decoderOptions = append(decoderOptions, decoder.WithIgnoreChecksum())
+	}
+
+	options := opts.Options
+	options.Name = in.baseName()
+	return converters.Convert(in.reader(), format, decoderOptions, options)
+}
+
+// outputPaths decides where the result of every input goes, "" standing for stdout. With several inputs
+// -o must be a directory, a name with an extension is taken for a file and rejected.
+func outputPaths(inputs []input, format converters.Format, output string) ([]string, error) {
+	many := len(inputs) > 1
+	dir := isDir(output) || strings.HasSuffix(output, string(filepath.Separator))
+	if many && output != "" && output != stdinName && !dir && filepath.Ext(output) != "" {
+		return nil, fmt.Errorf("-o %s names a file, several inputs need a directory", output)
+	}
+
+	paths := make([]string, len(inputs))
+	taken := make(map[string]bool)
+	for i, in := range inputs {
+		var path string
+		switch {
+		case output == stdinName || (output == "" && (!many || in.name == stdinName)):
+			continue
+		case output == "":
+			path = filepath.Join(filepath.Dir(in.name), in.baseName()+format.Extension())
+		case many || dir:
+			path = filepath.Join(output, in.baseName()+format.Extension())
+		default:
+			path = filepath.Clean(output)
+		}
+		paths[i] = unique(path, taken)
+	}
+	return paths, nil
+}
+
+// unique appends a counter to path until it isn't taken yet, and takes it.
+func unique(path string, taken map[string]bool) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for n := 2; taken[path]; n++ {
+		path = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+	taken[path] = true
+	return path
+}
+
+func writeOutput(path string, result string, stdout io.Writer) error {
+	if path == "" {
+		_, err := io.WriteString(stdout, result)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(result), 0o644)
+}
@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/config"
+)
+
+// CORSFlags registers the CORS policy flags on fs, defaulting to the CORS_* environment variables and
+// then to c. Lists are comma separated.
+func CORSFlags(fs *flag.FlagSet, c *config.CORS) {
+	listVar(fs, &c.Origins, "cors-origins", "CORS_ALLOWED_ORIGINS", "Allowed origins, * for any or https://*.example.com for subdomains")
+	listVar(fs, &c.Methods, "cors-methods", "CORS_ALLOWED_METHODS", "Allowed methods")
+	listVar(fs, &c.Headers, "cors-headers", "CORS_ALLOWED_HEADERS", "Allowed request headers, * for any")
+	listVar(fs, &c.Expose, "cors-expose", "CORS_EXPOSED_HEADERS", "Response headers readable by scripts")
+	fs.BoolVar(&c.Credentials, "cors-credentials", args.EnvOrDefault("CORS_ALLOW_CREDENTIALS", c.Credentials), "Allow cookies and authorization headers, not with origin *")
+	durationVar(fs, &c.MaxAge, "cors-max-age", "CORS_MAX_AGE", "How long browsers may cache a preflight, 0 leaves it to the browser")
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+)
+
+// inspection is what inspect prints for one file.
+type inspection struct {
+	File            string            `json:"file"`
+	Size            int               `json:"size"`
+	Sequences       int               `json:"sequences"`
+	ProtocolVersion string            `json:"protocol_version"`
+	ProfileVersion  uint16            `json:"profile_version"`
+	Type            string            `json:"type"`
+	Manufacturer    string            `json:"manufacturer"`
+	Product         uint16            `json:"product"`
+	SerialNumber    uint32            `json:"serial_number,omitempty"`
+	TimeCreated     time.Time         `json:"time_created"`
+	Messages        map[string]int    `json:"messages"`
+	Summary         *activity.Summary `json:"summary,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// Inspect implements `go-fitter inspect [--json] [file|glob|-]...`.
+func Inspect(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var asJSON bool
+
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go-fitter inspect [flags] [file|glob|-]...")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return usageError(stderr, fs, err)
+	}
+	inputs, err := expandInputs(positional, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+
+	code := ExitOK
+	reports := make([]inspection, 0, len(inputs))
+	for _, in := range inputs {
+		report := inspect(in)
+		if report.Error != "" {
+			code = ExitFailure
+		}
+		reports = append(reports, report)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(reports)
+		return code
+	}
+	for _, report := range reports {
+		printInspection(stdout, report)
+	}
+	return code
+}
+
+func inspect(in input) inspection {
+	report := inspection{
+		File:     in.name,
+		Size:     len(in.data),
+		Messages: make(map[string]int),
+	}
+
+	act := filedef.NewActivity()
+	dec := decoder.New(in.reader(), decoder.WithIgnoreChecksum())
+	for dec.Next() {
+		fit, err := dec.Decode()
+		if err != nil {
+			report.Error = err.Error()
+			break
+		}
+		if report.Sequences == 0 {
+			v := fit.FileHeader.ProtocolVersion
+			report.ProtocolVersion = fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+			report.ProfileVersion = fit.FileHeader.ProfileVersion
+		}
+		report.Sequences++
+		for i := range fit.Messages {
+			mesg := fit.Messages[i]
+			report.Messages[typedef.MesgNum(mesg.Num).String()]++
+			if mesg.Num == mesgnum.FileId && report.Sequences == 1 {
+				fileId := mesgdef.NewFileId(&mesg)
+				report.Type = fileId.Type.String()
+				report.Manufacturer = fileId.Manufacturer.String()
+				report.Product = fileId.Product
+				report.SerialNumber = fileId.SerialNumber
+				report.TimeCreated = fileId.TimeCreated
+			}
+			act.Add(mesg)
+		}
+	}
+
+	if len(act.Sessions) > 0 || len(act.Records) > 0 {
+		summary := activity.Summarize(act)
+		report.Summary = &summary
+	}
+	return report
+}
+
+func printInspection(w io.Writer, r inspection) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "File:\t%s (%d bytes)\n", r.File, r.Size)
+	if r.Error != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", r.Error)
+	}
+	fmt.Fprintf(tw, "Sequences:\t%d\n", r.Sequences)
+	fmt.Fprintf(tw, "Protocol / profile:\t%s / %d\n", r.ProtocolVersion, r.ProfileVersion)
+	fmt.Fprintf(tw, "Type:\t%s\n", r.Type)
+	fmt.Fprintf(tw, "Device:\t%s %d (serial %d)\n", r.Manufacturer, r.Product, r.SerialNumber)
+	fmt.Fprintf(tw, "Created:\t%s\n", r.TimeCreated.Format(time.RFC3339))
+	if s := r.Summary; s != nil {
+		fmt.Fprintf(tw, "Sport:\t%s %s\n", s.Sport, s.SubSport)
+		fmt.Fprintf(tw, "Start:\t%s\n", s.StartTime.Format(time.RFC3339))
+		fmt.Fprintf(tw, "Duration:\t%s\n", time.Duration(s.Duration*float64(time.Second)).Round(time.Second))
+		fmt.Fprintf(tw, "Distance:\t%.2f km\n", s.Distance/1000)
+	}
+	fmt.Fprintln(tw, "Messages:")
+	for _, name := range slices.Sorted(maps.Keys(r.Messages)) {
+		fmt.Fprintf(tw, "  %s\t%d\n", name, r.Messages[name])
+	}
+	_ = tw.Flush()
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/config"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+)
+
+// PrivacyFlags registers the redaction flags on fs, defaulting to the PRIVACY_* environment variables.
+// Zones given with -zone are added to those from PRIVACY_ZONES. It fails when the environment is invalid.
+func PrivacyFlags(fs *flag.FlagSet, p *privacy.Policy) error {
+	env, err := privacyEnv(config.Privacy{})
+	if err != nil {
+		return err
+	}
+	policy, err := config.Config{Privacy: env}.PrivacyPolicy()
+	if err != nil {
+		return err
+	}
+	*p = policy
+
+	fs.Func("zone", "Privacy zone as lat,lon,radius in degrees and metres, repeatable", func(s string) error {
+		zones, err := privacy.ParseZones(s)
+		p.Zones = append(p.Zones, zones...)
+		return err
+	})
+	fs.Float64Var(&p.HideStart, "hide-start", p.HideStart, "Metres of track to hide after the start")
+	fs.Float64Var(&p.HideEnd, "hide-end", p.HideEnd, "Metres of track to hide before the end")
+	fs.Func("privacy-mode", "Positions inside a zone: remove or fuzz (default "+string(p.Mode)+")", func(s string) error {
+		mode, err := privacy.ParseMode(s)
+		p.Mode = mode
+		return err
+	})
+	return nil
+}
+
+// privacyConfigFlags is PrivacyFlags for the server configuration, defaulting to c after the environment.
+// Zones stay strings until the configuration is validated.
+func privacyConfigFlags(fs *flag.FlagSet, c *config.Privacy) error {
+	var err error
+	*c, err = privacyEnv(*c)
+	fs.Func("zone", "Privacy zone as lat,lon,radius in degrees and metres, repeatable", func(s string) error {
+		zones, err := zoneStrings(s)
+		c.Zones = append(c.Zones, zones...)
+		return err
+	})
+	fs.Float64Var(&c.HideStart, "hide-start", c.HideStart, "Metres of track to hide after the start")
+	fs.Float64Var(&c.HideEnd, "hide-end", c.HideEnd, "Metres of track to hide before the end")
+	fs.StringVar(&c.Mode, "privacy-mode", c.Mode, "Positions inside a zone: remove or fuzz")
+	return err
+}
+
+// privacyEnv overrides c with the PRIVACY_* environment variables. PRIVACY_ZONES adds to the zones of c.
+func privacyEnv(c config.Privacy) (config.Privacy, error) {
+	var zones []string
+	env, zonesErr := args.Env("PRIVACY_ZONES", "")
+	if zonesErr == nil {
+		zones, zonesErr = zoneStrings(env)
+		c.Zones = append(c.Zones, zones...)
+	}
+	var modeErr, startErr, endErr error
+	c.Mode, modeErr = args.Env("PRIVACY_MODE", c.Mode)
+	c.HideStart, startErr = args.Env("PRIVACY_HIDE_START", c.HideStart)
+	c.HideEnd, endErr = args.Env("PRIVACY_HIDE_END", c.HideEnd)
+	return c, errors.Join(zonesErr, modeErr, startErr, endErr)
+}
+
+// zoneStrings splits semicolon separated zones, checking each one.
+func zoneStrings(s string) ([]string, error) {
+	zones, err := privacy.ParseZones(s)
+	list := make([]string, len(zones))
+	for i, z := range zones {
+		list[i] = z.String()
+	}
+	return list, err
+}
@@ -0,0 +1,18 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/config"
+)
+
+// RateLimitFlags registers the rate limit and quota flags on fs, defaulting to the RATE_LIMIT_* and QUOTA_*
+// environment variables and then to c.
+func RateLimitFlags(fs *flag.FlagSet, c *config.Limits) {
+	fs.Float64Var(&c.Rate, "rate-limit", args.EnvOrDefault("RATE_LIMIT", c.Rate), "Requests per second per client, 0 disables the limit")
+	fs.IntVar(&c.Burst, "rate-burst", args.EnvOrDefault("RATE_LIMIT_BURST", c.Burst), "Requests a client may send at once, defaults to the rate")
+	fs.Int64Var(&c.QuotaBytes, "quota-bytes", args.EnvOrDefault("QUOTA_DAILY_BYTES", c.QuotaBytes), "Upload bytes per client and UTC day, 0 disables the quota")
+	fs.IntVar(&c.QuotaConversions, "quota-conversions", args.EnvOrDefault("QUOTA_DAILY_CONVERSIONS", c.QuotaConversions), "Conversions per client and UTC day, 0 disables the quota")
+	fs.StringVar(&c.StateFile, "rate-limit-state", args.EnvOrDefault("RATE_LIMIT_STATE_FILE", c.StateFile), "File to keep rate limit and quota state in across restarts")
+}
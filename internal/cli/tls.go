@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/config"
+)
+
+// TLSFlags registers the TLS flags on fs, defaulting to the TLS_* environment variables and then to c.
+// TLS stays off until a certificate and key are given.
+func TLSFlags(fs *flag.FlagSet, c *config.TLS) {
+	fs.StringVar(&c.Cert, "tls-cert", args.EnvOrDefault("TLS_CERT_FILE", c.Cert), "PEM certificate chain, enables TLS together with -tls-key")
+	fs.StringVar(&c.Key, "tls-key", args.EnvOrDefault("TLS_KEY_FILE", c.Key), "PEM private key of the certificate")
+	fs.StringVar(&c.ClientCA, "tls-client-ca", args.EnvOrDefault("TLS_CLIENT_CA_FILE", c.ClientCA), "PEM CA bundle to verify client certificates against, enables mTLS")
+	fs.StringVar(&c.ClientAuth, "tls-client-auth", args.EnvOrDefault("TLS_CLIENT_AUTH", c.ClientAuth), "require or optional client certificates when a client CA is set")
+	fs.StringVar(&c.MinVersion, "tls-min-version", args.EnvOrDefault("TLS_MIN_VERSION", c.MinVersion), "Lowest accepted TLS version, 1.2 or 1.3")
+	durationVar(fs, &c.Reload, "tls-reload", "TLS_RELOAD_INTERVAL", "How often the certificate files are checked for changes, 0 disables reloading")
+}
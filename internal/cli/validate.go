@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/kyzrfranz/go-fitter/pkg/validate"
+)
+
+type validation struct {
+	File string `json:"file"`
+	validate.Report
+}
+
+// Validate implements `go-fitter validate [--json] [file|glob|-]...`. It prints the lint report of every
+// input and exits non-zero when any input has errors.
+func Validate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var (
+		asJSON   bool
+		warnings bool
+		opts     []validate.Option
+	)
+
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&asJSON, "json", false, "Print the reports as JSON")
+	fs.BoolVar(&warnings, "warnings", true, "List warnings and infos, not only errors")
+	gap := fs.Duration("gap", 0, "Report record gaps longer than this (default 1m)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go-fitter validate [flags] [file|glob|-]...")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return usageError(stderr, fs, err)
+	}
+	inputs, err := expandInputs(positional, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	opts = append(opts, validate.WithGapThreshold(*gap))
+
+	code := ExitOK
+	reports := make([]validation, 0, len(inputs))
+	for _, in := range inputs {
+		report := validate.Validate(in.data, opts...)
+		if !report.Valid {
+			code = ExitFailure
+		}
+		reports = append(reports, validation{File: in.name, Report: report})
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(reports)
+		return code
+	}
+
+	for _, r := range reports {
+		status := "OK"
+		if !r.Valid {
+			status = "FAIL"
+		}
+		fmt.Fprintf(stdout, "%s\t%s (%d sequences, %d errors, %d warnings)\n", status, r.File, len(r.Sequences), r.Errors, r.Warnings)
+		for _, issue := range r.Issues {
+			if !warnings && issue.Severity != validate.SeverityError {
+				continue
+			}
+			fmt.Fprintf(stdout, "\t%s\t%s\t%s%s\n", issue.Severity, issue.Code, location(issue), issue.Message)
+		}
+	}
+	return code
+}
+
+// location renders where an issue was found, e.g. "record[12].heart_rate: ".
+func location(issue validate.Issue) string {
+	if issue.Mesg == "" {
+		return ""
+	}
+	loc := issue.Mesg
+	if issue.Index != nil {
+		loc += fmt.Sprintf("[%d]", *issue.Index)
+	}
+	if issue.Field != "" {
+		loc += "." + issue.Field
+	}
+	return loc + ": "
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/watch"
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+)
+
+// Watch implements `go-fitter watch --dir <dir> [flags]` and runs until interrupted.
+func Watch(cmdArgs []string, stdout, stderr io.Writer) int {
+	var (
+		opts    watch.Options
+		formats string
+	)
+
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.Dir, "dir", args.EnvOrDefault[string]("WATCH_DIR", ""), "Directory to watch for .fit files")
+	fs.StringVar(&opts.OutDir, "out", args.EnvOrDefault[string]("WATCH_OUT_DIR", ""), "Output directory, next to the input when empty")
+	fs.StringVar(&formats, "format", args.EnvOrDefault[string]("WATCH_FORMATS", "json"), "Comma separated output formats: json, ndjson, gpx, tcx, csv, geojson")
+	fs.StringVar(&opts.StateFile, "state", args.EnvOrDefault[string]("WATCH_STATE_FILE", ""), "State file of processed files, defaults to "+watch.DefaultStateFile+" in -dir")
+	fs.DurationVar(&opts.Interval, "interval", 2*time.Second, "How often the directory is scanned")
+	fs.DurationVar(&opts.StableFor, "stable", 5*time.Second, "How long a file must stay unchanged before it is converted")
+	fs.BoolVar(&opts.Convert.Records, "records", false, "Include records in the JSON output")
+	fs.BoolVar(&opts.Convert.Degrees, "degrees", false, "Print positions in degrees instead of semicircles")
+	fs.BoolVar(&opts.Convert.Pretty, "pretty", true, "Pretty-print JSON and GPX output")
+	if err := PrivacyFlags(fs, &opts.Convert.Privacy); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: go-fitter watch --dir <dir> [flags]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(cmdArgs); err != nil {
+		return usageError(stderr, fs, err)
+	}
+	for _, f := range strings.Split(formats, ",") {
+		format, err := converters.ParseFormat(strings.TrimSpace(f))
+		if err != nil {
+			return usageError(stderr, fs, err)
+		}
+		opts.Formats = append(opts.Formats, format)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(stdout, nil))
+	w, err := watch.New(logger, opts)
+	if err != nil {
+		return usageError(stderr, fs, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := w.Run(ctx); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	return ExitOK
+}
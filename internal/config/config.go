@@ -0,0 +1,339 @@
+// Package config is the configuration of the server. It is layered: built-in defaults, then a YAML or
+// TOML file, then environment variables, then command line flags, each overriding the ones before.
+// The first two are handled here, env and flags by the cli package, which registers a flag for every
+// setting with the layers below it as its default.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/auth"
+	"github.com/kyzrfranz/go-fitter/internal/http"
+	"github.com/kyzrfranz/go-fitter/internal/ratelimit"
+	"github.com/kyzrfranz/go-fitter/internal/training"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server   Server             `yaml:"server" toml:"server"`
+	Limits   Limits             `yaml:"limits" toml:"limits"`
+	Convert  Convert            `yaml:"convert" toml:"convert"`
+	Privacy  Privacy            `yaml:"privacy" toml:"privacy"`
+	Athlete  Athlete            `yaml:"athlete" toml:"athlete"`
+	Profiles map[string]Athlete `yaml:"profiles,omitempty" toml:"profiles,omitempty"` // Named thresholds, picked with ?profile=
+	Storage  Storage            `yaml:"storage" toml:"storage"`
+	Auth     Auth               `yaml:"auth" toml:"auth"`
+}
+
+type Server struct {
+	Port          int         `yaml:"port" toml:"port"`
+	Workers       int         `yaml:"workers" toml:"workers"`             // Concurrent conversions
+	MaxUploadMB   int         `yaml:"max_upload_mb" toml:"max_upload_mb"` // 0 for no limit
+	ShutdownDelay Duration    `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	DocsDir       string      `yaml:"docs_dir" toml:"docs_dir"` // OpenAPI document and API docs, empty to not serve them
+	TLS           TLS         `yaml:"tls" toml:"tls"`
+	CORS          CORS        `yaml:"cors" toml:"cors"`
+	Compression   Compression `yaml:"compression" toml:"compression"`
+}
+
+type TLS struct {
+	Cert       string   `yaml:"cert" toml:"cert"`
+	Key        string   `yaml:"key" toml:"key"`
+	ClientCA   string   `yaml:"client_ca" toml:"client_ca"`
+	ClientAuth string   `yaml:"client_auth" toml:"client_auth"`
+	MinVersion string   `yaml:"min_version" toml:"min_version"`
+	Reload     Duration `yaml:"reload" toml:"reload"`
+}
+
+type Compression struct {
+	Encodings []string `yaml:"encodings" toml:"encodings"` // In order of preference, empty turns compression off
+	MinSize   int      `yaml:"min_size" toml:"min_size"`   // Smallest response in bytes that is compressed
+}
+
+type CORS struct {
+	Origins     []string `yaml:"origins" toml:"origins"`
+	Methods     []string `yaml:"methods" toml:"methods"`
+	Headers     []string `yaml:"headers" toml:"headers"`
+	Expose      []string `yaml:"expose" toml:"expose"`
+	Credentials bool     `yaml:"credentials" toml:"credentials"`
+	MaxAge      Duration `yaml:"max_age" toml:"max_age"`
+}
+
+type Limits struct {
+	Rate             float64 `yaml:"rate" toml:"rate"`
+	Burst            int     `yaml:"burst" toml:"burst"`
+	QuotaBytes       int64   `yaml:"quota_bytes" toml:"quota_bytes"`
+	QuotaConversions int     `yaml:"quota_conversions" toml:"quota_conversions"`
+	StateFile        string  `yaml:"state_file" toml:"state_file"`
+}
+
+// Convert are the defaults of conversion requests that don't set them.
+type Convert struct {
+	Records        bool `yaml:"records" toml:"records"`
+	Degrees        bool `yaml:"degrees" toml:"degrees"`
+	VerifyChecksum bool `yaml:"verify_checksum" toml:"verify_checksum"`
+}
+
+type Privacy struct {
+	Zones     []string `yaml:"zones" toml:"zones"` // lat,lon,radius
+	Mode      string   `yaml:"mode" toml:"mode"`
+	HideStart float64  `yaml:"hide_start" toml:"hide_start"`
+	HideEnd   float64  `yaml:"hide_end" toml:"hide_end"`
+}
+
+type Athlete struct {
+	FTP    float64 `yaml:"ftp" toml:"ftp"`
+	LTHR   float64 `yaml:"lthr" toml:"lthr"`
+	MaxHR  float64 `yaml:"max_hr" toml:"max_hr"`
+	RestHR float64 `yaml:"rest_hr" toml:"rest_hr"`
+}
+
+type Storage struct {
+	DataDir string `yaml:"data_dir" toml:"data_dir"`
+}
+
+// Auth has the files of the credentials. The JWT secret is only read from AUTH_JWT_SECRET, so it is never
+// written to a file or dumped.
+type Auth struct {
+	APIKeysFile string `yaml:"api_keys_file" toml:"api_keys_file"`
+	JWKSFile    string `yaml:"jwks_file" toml:"jwks_file"`
+	JWTIssuer   string `yaml:"jwt_issuer" toml:"jwt_issuer"`
+	JWTAudience string `yaml:"jwt_audience" toml:"jwt_audience"`
+	JWTSecret   string `yaml:"-" toml:"-"`
+}
+
+// Duration is a time.Duration written like 1m30s in config files. A plain number is seconds.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := args.ParseDuration(string(b))
+	*d = Duration(v)
+	return err
+}
+
+// Default returns the built-in defaults.
+func Default() Config {
+	cors := http.DefaultCORSPolicy()
+	compression := http.DefaultCompressionPolicy()
+	return Config{
+		Server: Server{
+			Port:        8080,
+			Workers:     runtime.NumCPU(),
+			MaxUploadMB: 64,
+			DocsDir:     "./docs",
+			TLS: TLS{
+				ClientAuth: http.ClientAuthRequire,
+				MinVersion: "1.2",
+				Reload:     Duration(time.Minute),
+			},
+			CORS: CORS{
+				Origins: cors.AllowedOrigins,
+				Methods: cors.AllowedMethods,
+				Headers: cors.AllowedHeaders,
+				Expose:  cors.ExposedHeaders,
+			},
+			Compression: Compression{
+				Encodings: compression.Encodings,
+				MinSize:   compression.MinSize,
+			},
+		},
+		Privacy: Privacy{Mode: string(privacy.ModeRemove)},
+		Athlete: Athlete{MaxHR: 190, RestHR: 60},
+		Storage: Storage{DataDir: "./data"},
+	}
+}
+
+// Load returns the defaults overridden by the file at path, YAML or TOML going by its extension.
+// Unknown keys are an error, so typos don't go unnoticed.
+func Load(path string) (Config, error) {
+	c := Default()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), &c)
+		if err != nil {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return c, fmt.Errorf("%s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return c, fmt.Errorf("%s: unknown config format %q, want .yaml, .yml or .toml", path, ext)
+	}
+	return c, nil
+}
+
+// Validate checks every setting and reports all problems at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, a ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, a...))
+		}
+	}
+	check(c.Server.Port > 0 && c.Server.Port < 1<<16, "server.port: %d is not a valid port", c.Server.Port)
+	check(c.Server.Workers > 0, "server.workers: must be at least 1")
+	check(c.Server.MaxUploadMB >= 0, "server.max_upload_mb: must not be negative")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay: must not be negative")
+	check(c.Server.TLS.Reload >= 0, "server.tls.reload: must not be negative")
+	check(c.Server.CORS.MaxAge >= 0, "server.cors.max_age: must not be negative")
+	check(c.Limits.Rate >= 0, "limits.rate: must not be negative")
+	check(c.Limits.Burst >= 0, "limits.burst: must not be negative")
+	check(c.Limits.QuotaBytes >= 0, "limits.quota_bytes: must not be negative")
+	check(c.Limits.QuotaConversions >= 0, "limits.quota_conversions: must not be negative")
+	check(c.Privacy.HideStart >= 0, "privacy.hide_start: must not be negative")
+	check(c.Privacy.HideEnd >= 0, "privacy.hide_end: must not be negative")
+	check(c.Storage.DataDir != "", "storage.data_dir: must not be empty")
+	if err := c.Athlete.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("athlete: %w", err))
+	}
+	for name, p := range c.Profiles {
+		if err := p.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("profiles.%s: %w", name, err))
+		}
+	}
+	if err := c.TLSConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("server.%w", err))
+	}
+	if err := c.CORSPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("server.%w", err))
+	}
+	if err := c.CompressionPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("server.%w", err))
+	}
+	if _, err := c.PrivacyPolicy(); err != nil {
+		errs = append(errs, fmt.Errorf("privacy: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (a Athlete) validate() error {
+	if a.FTP < 0 || a.LTHR < 0 || a.MaxHR < 0 || a.RestHR < 0 {
+		return errors.New("thresholds must not be negative")
+	}
+	if a.MaxHR > 0 && a.RestHR >= a.MaxHR {
+		return fmt.Errorf("rest_hr %g must be below max_hr %g", a.RestHR, a.MaxHR)
+	}
+	return nil
+}
+
+func (c Config) TLSConfig() http.TLSConfig {
+	t := c.Server.TLS
+	return http.TLSConfig{
+		CertFile:       t.Cert,
+		KeyFile:        t.Key,
+		ClientCAFile:   t.ClientCA,
+		ClientAuth:     t.ClientAuth,
+		MinVersion:     t.MinVersion,
+		ReloadInterval: time.Duration(t.Reload),
+	}
+}
+
+func (c Config) CORSPolicy() http.CORSPolicy {
+	p := c.Server.CORS
+	return http.CORSPolicy{
+		AllowedOrigins:   p.Origins,
+		AllowedMethods:   p.Methods,
+		AllowedHeaders:   p.Headers,
+		ExposedHeaders:   p.Expose,
+		AllowCredentials: p.Credentials,
+		MaxAge:           time.Duration(p.MaxAge),
+	}
+}
+
+func (c Config) CompressionPolicy() http.CompressionPolicy {
+	return http.CompressionPolicy{
+		Encodings: c.Server.Compression.Encodings,
+		MinSize:   c.Server.Compression.MinSize,
+	}
+}
+
+func (c Config) RateLimit() ratelimit.Config {
+	return ratelimit.Config{
+		Rate:             c.Limits.Rate,
+		Burst:            c.Limits.Burst,
+		DailyBytes:       c.Limits.QuotaBytes,
+		DailyConversions: c.Limits.QuotaConversions,
+		StateFile:        c.Limits.StateFile,
+	}
+}
+
+func (c Config) AuthConfig() auth.Config {
+	return auth.Config{
+		APIKeysFile: c.Auth.APIKeysFile,
+		JWKSFile:    c.Auth.JWKSFile,
+		JWTSecret:   c.Auth.JWTSecret,
+		JWTIssuer:   c.Auth.JWTIssuer,
+		JWTAudience: c.Auth.JWTAudience,
+	}
+}
+
+func (c Config) PrivacyPolicy() (privacy.Policy, error) {
+	p := privacy.Policy{HideStart: c.Privacy.HideStart, HideEnd: c.Privacy.HideEnd}
+	var err error
+	if p.Mode, err = privacy.ParseMode(c.Privacy.Mode); err != nil {
+		return p, err
+	}
+	for _, z := range c.Privacy.Zones {
+		zone, err := privacy.ParseZone(z)
+		if err != nil {
+			return p, err
+		}
+		p.Zones = append(p.Zones, zone)
+	}
+	return p, nil
+}
+
+func (a Athlete) Thresholds() training.Thresholds {
+	return training.Thresholds{FTP: a.FTP, LTHR: a.LTHR, MaxHR: a.MaxHR, RestHR: a.RestHR}
+}
+
+// ProfileThresholds returns the thresholds of every profile.
+func (c Config) ProfileThresholds() map[string]training.Thresholds {
+	profiles := make(map[string]training.Thresholds, len(c.Profiles))
+	for name, p := range c.Profiles {
+		profiles[name] = p.Thresholds()
+	}
+	return profiles
+}
+
+// Dump writes c to w as "yaml" or "toml".
+func (c Config) Dump(w io.Writer, format string) error {
+	switch format {
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(c)
+	default:
+		return fmt.Errorf("unknown config format %q, want yaml or toml", format)
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		check   func(c Config) bool
+		wantErr string
+	}{
+		{
+			name:    "yaml",
+			file:    "config.yaml",
+			content: "server:\n  port: 9090\n  shutdown_delay: 5s\nathlete:\n  ftp: 250\n",
+			check: func(c Config) bool {
+				return c.Server.Port == 9090 && c.Server.ShutdownDelay == Duration(5*time.Second) && c.Athlete.FTP == 250
+			},
+		},
+		{
+			name:    "toml",
+			file:    "config.toml",
+			content: "[server]\nport = 9090\n[limits]\nrate = 2.5\n[profiles.jane]\nftp = 200\n",
+			check: func(c Config) bool {
+				return c.Server.Port == 9090 && c.Limits.Rate == 2.5 && c.Profiles["jane"].FTP == 200
+			},
+		},
+		{
+			name:    "defaults are kept",
+			file:    "config.yml",
+			content: "storage:\n  data_dir: /var/lib/go-fitter\n",
+			check: func(c Config) bool {
+				return c.Storage.DataDir == "/var/lib/go-fitter" && c.Server.Port == 8080 && c.Athlete.MaxHR == 190
+			},
+		},
+		{
+			name:  "empty file",
+			file:  "config.yaml",
+			check: func(c Config) bool { return c.Server.Port == 8080 },
+		},
+		{
+			name:    "duration in seconds",
+			file:    "config.yaml",
+			content: "server:\n  tls:\n    reload: 90\n",
+			check:   func(c Config) bool { return c.Server.TLS.Reload == Duration(90*time.Second) },
+		},
+		{name: "unknown yaml key", file: "config.yaml", content: "server:\n  prot: 9090\n", wantErr: "prot"},
+		{name: "unknown toml key", file: "config.toml", content: "[server]\nprot = 9090\n", wantErr: "unknown key server.prot"},
+		{name: "unknown format", file: "config.json", content: "{}", wantErr: "unknown config format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Load(writeConfig(t, tt.file, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !tt.check(c) {
+				t.Errorf("Load() = %+v", c)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr []string
+	}{
+		{name: "defaults", modify: func(c *Config) {}},
+		{name: "port", modify: func(c *Config) { c.Server.Port = 70000 }, wantErr: []string{"server.port"}},
+		{name: "workers", modify: func(c *Config) { c.Server.Workers = 0 }, wantErr: []string{"server.workers"}},
+		{name: "negative limits", modify: func(c *Config) { c.Limits.Rate, c.Limits.QuotaBytes = -1, -1 },
+			wantErr: []string{"limits.rate", "limits.quota_bytes"}},
+		{name: "rest above max heart rate", modify: func(c *Config) { c.Athlete.RestHR = 200 }, wantErr: []string{"athlete: rest_hr"}},
+		{name: "profile", modify: func(c *Config) { c.Profiles = map[string]Athlete{"jane": {FTP: -1}} },
+			wantErr: []string{"profiles.jane"}},
+		{name: "privacy zone", modify: func(c *Config) { c.Privacy.Zones = []string{"91,0,100"} }, wantErr: []string{"privacy: zone"}},
+		{name: "privacy mode", modify: func(c *Config) { c.Privacy.Mode = "blur" }, wantErr: []string{"privacy: unknown privacy mode"}},
+		{name: "compression", modify: func(c *Config) { c.Server.Compression.Encodings = []string{"br"} },
+			wantErr: []string{"server.compression"}},
+		{name: "data dir", modify: func(c *Config) { c.Storage.DataDir = "" }, wantErr: []string{"storage.data_dir"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.modify(&c)
+			err := c.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() error = nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDump(t *testing.T) {
+	tests := []struct {
+		format  string
+		file    string
+		wantErr bool
+	}{
+		{format: "yaml", file: "config.yaml"},
+		{format: "toml", file: "config.toml"},
+		{format: "json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			want := Default()
+			want.Server.Port = 9090
+			want.Server.ShutdownDelay = Duration(10 * time.Second)
+			want.Privacy.Zones = []string{"48.2,16.37,500"}
+			want.Auth.JWTSecret = "secret"
+
+			var buf bytes.Buffer
+			err := want.Dump(&buf, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if strings.Contains(buf.String(), "secret") {
+				t.Error("Dump() wrote the JWT secret")
+			}
+
+			got, err := Load(writeConfig(t, tt.file, buf.String()))
+			if err != nil {
+				t.Fatalf("Load() of the dump: %v", err)
+			}
+			if got.Server.Port != 9090 || got.Server.ShutdownDelay != want.Server.ShutdownDelay || got.Privacy.Zones[0] != "48.2,16.37,500" {
+				t.Errorf("Load() of the dump = %+v", got)
+			}
+		})
+	}
+}
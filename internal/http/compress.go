@@ -0,0 +1,225 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/kyzrfranz/go-fitter/internal/negotiate"
+)
+
+// Content codings the server can compress responses with. Deflate is the zlib format, as HTTP defines it.
+const (
+	EncodingZstd    = "zstd"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressionPolicy decides which responses are compressed and how.
+type CompressionPolicy struct {
+	Encodings []string // offered in order of preference, none turns compression off
+	MinSize   int      // bytes a response must reach to be compressed, smaller ones gain little
+}
+
+// DefaultCompressionPolicy offers zstd, gzip and deflate for responses of 1 KiB and more.
+func DefaultCompressionPolicy() CompressionPolicy {
+	return CompressionPolicy{
+		Encodings: []string{EncodingZstd, EncodingGzip, EncodingDeflate},
+		MinSize:   1024,
+	}
+}
+
+// Validate checks the encodings and the threshold.
+func (p CompressionPolicy) Validate() error {
+	for _, e := range p.Encodings {
+		if _, ok := encoderPools[e]; !ok {
+			return fmt.Errorf("compression: unknown encoding %q, want %s, %s or %s", e, EncodingZstd, EncodingGzip, EncodingDeflate)
+		}
+	}
+	if p.MinSize < 0 {
+		return fmt.Errorf("compression: min size must not be negative")
+	}
+	return nil
+}
+
+// MiddlewareCompress compresses responses with the encoding of p the client's Accept-Encoding prefers.
+// Responses are held back until they reach p.MinSize, so small ones go out as they are, and responses that
+// are compressed already, like zip files, or that set their own Content-Encoding are left alone. A
+// handler that flushes, like a stream of NDJSON, is compressed from the first flush on.
+func MiddlewareCompress(p CompressionPolicy) Middleware {
+	return func(next http.Handler) http.Handler {
+		if len(p.Encodings) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiate.Encoding(r.Header.Get("Accept-Encoding"), p.Encodings...)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: p.MinSize, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+			// Not deferred: after a panic nothing may be written, so the recovery can still answer 500
+			_ = cw.close()
+		})
+	}
+}
+
+// encoder is what the writers of all encodings have in common.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingZstd: {New: func() any {
+		// A window of at most 8 MiB, as RFC 9659 asks for HTTP
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(8<<20))
+		return enc
+	}},
+	EncodingGzip: {New: func() any {
+		enc, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return enc
+	}},
+	EncodingDeflate: {New: func() any {
+		enc, _ := zlib.NewWriterLevel(nil, zlib.DefaultCompression)
+		return enc
+	}},
+}
+
+// compressWriter buffers the start of a response until it knows whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status    int
+	buf       []byte
+	committed bool    // the header is written, buf is drained
+	enc       encoder // nil when the response isn't compressed
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	if c.committed || code < http.StatusOK {
+		c.ResponseWriter.WriteHeader(code) // informational responses pass through
+		return
+	}
+	c.status = code
+	if !bodyAllowed(code) {
+		_ = c.commit(false)
+	}
+}
+
+func (c *compressWriter) Write(b []byte) (int, error) {
+	if !c.committed {
+		c.buf = append(c.buf, b...)
+		if len(c.buf) < c.minSize {
+			return len(b), nil
+		}
+		return len(b), c.commit(c.compressible())
+	}
+	if c.enc != nil {
+		return c.enc.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// FlushError sends what was written so far, compressed if it should be. http.ResponseController looks for
+// it before unwrapping the writer, which would skip the buffer.
+func (c *compressWriter) FlushError() error {
+	if !c.committed {
+		if err := c.commit(c.compressible()); err != nil {
+			return err
+		}
+	}
+	if c.enc != nil {
+		if err := c.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+func (c *compressWriter) Flush() { _ = c.FlushError() }
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *compressWriter) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+// commit writes the header and the buffered body, starting the encoder if compress is set.
+func (c *compressWriter) commit(compress bool) error {
+	c.committed = true
+	if compress {
+		h := c.Header()
+		if _, ok := h["Content-Type"]; !ok {
+			// net/http would sniff the compressed bytes
+			h.Set("Content-Type", http.DetectContentType(c.buf))
+		}
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", c.encoding)
+		c.enc = encoderPools[c.encoding].Get().(encoder)
+		c.enc.Reset(c.ResponseWriter)
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+
+	buf := c.buf
+	c.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if c.enc != nil {
+		_, err = c.enc.Write(buf)
+	} else {
+		_, err = c.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends a response that stayed below the threshold as it is and finishes a compressed one.
+func (c *compressWriter) close() error {
+	if !c.committed {
+		return c.commit(false)
+	}
+	if c.enc == nil {
+		return nil
+	}
+	err := c.enc.Close()
+	encoderPools[c.encoding].Put(c.enc)
+	c.enc = nil
+	return err
+}
+
+// compressible tells whether the response is worth compressing.
+func (c *compressWriter) compressible() bool {
+	h := c.Header()
+	if !bodyAllowed(c.status) || c.status == http.StatusPartialContent || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case slices.Contains(compressedTypes, mediaType),
+		strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return false
+	default:
+		return true
+	}
+}
+
+// compressedTypes are formats that are compressed already.
+var compressedTypes = []string{"application/zip", "application/gzip", "application/x-gzip", "application/zstd"}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
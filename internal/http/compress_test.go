@@ -0,0 +1,125 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "":
+		return string(body)
+	case EncodingGzip:
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case EncodingDeflate:
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(bytes.NewReader(body))
+		if err == nil {
+			defer d.Close()
+			r = d
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestMiddlewareCompress(t *testing.T) {
+	large := strings.Repeat(`{"heart_rate":140}`, 200)
+	tests := []struct {
+		name           string
+		policy         *CompressionPolicy // nil for the default
+		method         string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		wantEncoding   string
+	}{
+		{name: "preferred encoding", acceptEncoding: "gzip, zstd", body: large, wantEncoding: EncodingZstd},
+		{name: "quality", acceptEncoding: "zstd;q=0.1, gzip", body: large, wantEncoding: EncodingGzip},
+		{name: "deflate", acceptEncoding: "deflate", body: large, wantEncoding: EncodingDeflate},
+		{name: "no Accept-Encoding", body: large},
+		{name: "below the threshold", acceptEncoding: "gzip", body: "{}"},
+		{name: "compressed already", acceptEncoding: "gzip", contentType: "application/zip", body: large},
+		{name: "images", acceptEncoding: "gzip", contentType: "image/png", body: large},
+		{name: "svg is text", acceptEncoding: "gzip", contentType: "image/svg+xml", body: large, wantEncoding: EncodingGzip},
+		{name: "no content", acceptEncoding: "gzip", status: http.StatusNoContent},
+		{name: "HEAD", method: http.MethodHead, acceptEncoding: "gzip", body: large},
+		{name: "turned off", policy: &CompressionPolicy{}, acceptEncoding: "gzip", body: large},
+		{name: "encoding not offered", policy: &CompressionPolicy{Encodings: []string{EncodingGzip}}, acceptEncoding: "zstd", body: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultCompressionPolicy()
+			if tt.policy != nil {
+				policy = *tt.policy
+			}
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			handler := MiddlewareCompress(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+
+			r := httptest.NewRequest(method, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := decompress(t, tt.wantEncoding, w.Body.Bytes()); got != tt.body {
+				t.Errorf("body = %d bytes, want %d", len(got), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestCompressionPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CompressionPolicy
+		wantErr bool
+	}{
+		{name: "default", policy: DefaultCompressionPolicy()},
+		{name: "off", policy: CompressionPolicy{}},
+		{name: "unknown encoding", policy: CompressionPolicy{Encodings: []string{"br"}}, wantErr: true},
+		{name: "negative size", policy: CompressionPolicy{Encodings: []string{EncodingGzip}, MinSize: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy decides which cross-origin requests browsers may make.
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins like https://app.example.com, wildcard subdomains like
+	// https://*.example.com, or "*" for any origin.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string // "*" allows any request header
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // How long browsers may cache a preflight, 0 leaves it to the browser
+}
+
+// DefaultCORSPolicy allows any origin without credentials.
+func DefaultCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key"},
+		ExposedHeaders: []string{"Content-Disposition", "Content-Length", "X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
+	}
+}
+
+// Validate checks the origins. Credentials can't be combined with "*", that would let any site act with
+// the user's credentials.
+func (p CORSPolicy) Validate() error {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			if p.AllowCredentials {
+				return errors.New("cors: credentials can't be allowed for any origin")
+			}
+			continue
+		}
+		u, err := url.Parse(strings.Replace(o, "*.", "", 1))
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("cors: origin %q must look like scheme://host[:port]", o)
+		}
+		if strings.Count(o, "*") > 1 || (strings.Contains(o, "*") && !strings.Contains(o, "://*.")) {
+			return fmt.Errorf("cors: origin %q may only have a wildcard as its first label", o)
+		}
+	}
+	return nil
+}
+
+type corsHandler struct {
+	policy   CORSPolicy
+	any      bool
+	exact    map[string]bool
+	suffixes [][2]string // scheme:// and .domain of the wildcard origins
+	methods  string
+	headers  string
+	exposed  string
+	maxAge   string
+}
+
+func newCORSHandler(p CORSPolicy) *corsHandler {
+	c := &corsHandler{
+		policy:  p,
+		exact:   make(map[string]bool),
+		methods: strings.Join(p.AllowedMethods, ", "),
+		headers: strings.Join(p.AllowedHeaders, ", "),
+		exposed: strings.Join(p.ExposedHeaders, ", "),
+	}
+	for _, o := range p.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		switch {
+		case o == "*":
+			c.any = true
+		case strings.Contains(o, "://*."):
+			scheme, domain, _ := strings.Cut(o, "*")
+			c.suffixes = append(c.suffixes, [2]string{scheme, domain})
+		default:
+			c.exact[o] = true
+		}
+	}
+	if p.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return c
+}
+
+func (c *corsHandler) allowOrigin(origin string) bool {
+	if c.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.exact[origin] {
+		return true
+	}
+	for _, s := range c.suffixes {
+		sub, ok := strings.CutPrefix(origin, s[0])
+		if !ok {
+			continue
+		}
+		if sub, ok = strings.CutSuffix(sub, s[1]); ok && sub != "" && !strings.ContainsAny(sub, "/:") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *corsHandler) allowHeaders(requested string) bool {
+	if slices.Contains(c.policy.AllowedHeaders, "*") {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.TrimSpace(h)
+		if h != "" && !slices.ContainsFunc(c.policy.AllowedHeaders, func(a string) bool { return strings.EqualFold(a, h) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin answers with "*" when any origin is allowed without credentials and echoes the origin otherwise.
+func (c *corsHandler) setOrigin(h http.Header, origin string) {
+	if c.any && !c.policy.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.policy.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *corsHandler) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		origin := r.Header.Get("Origin")
+		// With a single "*" every origin gets the same answer, otherwise caches must key on it.
+		if !c.any || c.policy.AllowCredentials {
+			h.Add("Vary", "Origin")
+		}
+
+		method := r.Header.Get("Access-Control-Request-Method")
+		if r.Method == http.MethodOptions && origin != "" && method != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			requested := r.Header.Get("Access-Control-Request-Headers")
+			// A rejected preflight gets no CORS headers, the browser then blocks the actual request.
+			if c.allowOrigin(origin) && slices.Contains(c.policy.AllowedMethods, method) && c.allowHeaders(requested) {
+				c.setOrigin(h, origin)
+				h.Set("Access-Control-Allow-Methods", c.methods)
+				if slices.Contains(c.policy.AllowedHeaders, "*") {
+					h.Set("Access-Control-Allow-Headers", requested)
+				} else if c.headers != "" {
+					h.Set("Access-Control-Allow-Headers", c.headers)
+				}
+				if c.maxAge != "" {
+					h.Set("Access-Control-Max-Age", c.maxAge)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if origin != "" && c.allowOrigin(origin) {
+			c.setOrigin(h, origin)
+			if c.exposed != "" {
+				h.Set("Access-Control-Expose-Headers", c.exposed)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MiddlewareCORS applies p to the API routes. It runs inside the mux, so preflights are only answered
+// for registered routes; any other path gets the mux's 404.
+func MiddlewareCORS(p CORSPolicy) Middleware {
+	return newCORSHandler(p).wrap
+}
+
+// SetCORSPolicy sets the policy for static files, which skip the middleware.
+func (a *ApiServer) SetCORSPolicy(p CORSPolicy) {
+	a.cors = p
+}
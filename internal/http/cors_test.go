@@ -0,0 +1,218 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCORSPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CORSPolicy
+		wantErr bool
+	}{
+		{name: "default", policy: DefaultCORSPolicy()},
+		{name: "exact and wildcard origins", policy: CORSPolicy{AllowedOrigins: []string{"https://app.example.com", "http://localhost:3000", "https://*.example.com"}, AllowCredentials: true}},
+		{name: "any origin with credentials", policy: CORSPolicy{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}, wantErr: true},
+		{name: "no scheme", policy: CORSPolicy{AllowedOrigins: []string{"app.example.com"}}, wantErr: true},
+		{name: "path", policy: CORSPolicy{AllowedOrigins: []string{"https://example.com/app"}}, wantErr: true},
+		{name: "wildcard inside a label", policy: CORSPolicy{AllowedOrigins: []string{"https://app*.example.com"}}, wantErr: true},
+		{name: "two wildcards", policy: CORSPolicy{AllowedOrigins: []string{"https://*.*.example.com"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowOrigin(t *testing.T) {
+	c := newCORSHandler(CORSPolicy{AllowedOrigins: []string{"https://app.example.com/", "https://*.example.com", "http://localhost:3000"}})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "HTTPS://App.Example.com", want: true},
+		{origin: "https://api.example.com", want: true},
+		{origin: "https://eu.api.example.com", want: true},
+		{origin: "http://localhost:3000", want: true},
+		{origin: "https://example.com"},
+		{origin: "http://api.example.com"},
+		{origin: "https://evil.com/.example.com"},
+		{origin: "https://evil.com:1.example.com"},
+		{origin: "https://evilexample.com"},
+		{origin: "https://api.example.com.evil.com"},
+		{origin: "http://localhost:3001"},
+		{origin: "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := c.allowOrigin(tt.origin); got != tt.want {
+				t.Errorf("allowOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareCORS(t *testing.T) {
+	restricted := CORSPolicy{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	preflight := func(origin, method, headers string) *http.Request {
+		r := httptest.NewRequest(http.MethodOptions, "/fit", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		return r
+	}
+	post := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/fit", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name        string
+		policy      CORSPolicy
+		request     *http.Request
+		wantStatus  int
+		wantHeaders map[string]string // "" for headers that must be missing
+		wantVary    []string
+	}{
+		{
+			name:       "any origin",
+			policy:     DefaultCORSPolicy(),
+			request:    post("https://app.example.org"),
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name:       "allowed origin with credentials",
+			policy:     restricted,
+			request:    post("https://app.example.com"),
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-ID",
+			},
+			wantVary: []string{"Origin"},
+		},
+		{
+			name:        "other origin",
+			policy:      restricted,
+			request:     post("https://example.com"),
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Expose-Headers": ""},
+			wantVary:    []string{"Origin"},
+		},
+		{
+			name:        "same origin",
+			policy:      restricted,
+			request:     post(""),
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+			wantVary:    []string{"Origin"},
+		},
+		{
+			name:       "preflight",
+			policy:     restricted,
+			request:    preflight("https://app.example.com", http.MethodPost, "content-type"),
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Content-Type",
+				"Access-Control-Max-Age":           "600",
+			},
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:       "preflight with any header allowed",
+			policy:     CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodPost}, AllowedHeaders: []string{"*"}},
+			request:    preflight("https://app.example.org", http.MethodPost, "X-Custom, Content-Type"),
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "X-Custom, Content-Type",
+				"Access-Control-Max-Age":       "",
+			},
+			wantVary: []string{"Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:       "preflight of another origin",
+			policy:     restricted,
+			request:    preflight("https://evil.com/.example.com", http.MethodPost, ""),
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:       "preflight of another method",
+			policy:     restricted,
+			request:    preflight("https://app.example.com", http.MethodDelete, ""),
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Allow-Methods":     "",
+				"Access-Control-Max-Age":           "",
+			},
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:       "preflight of another header",
+			policy:     restricted,
+			request:    preflight("https://app.example.com", http.MethodPost, "Content-Type, X-Custom"),
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Headers": "",
+			},
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := MiddlewareCORS(tt.policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.request)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if got := rec.Header().Values("Vary"); !slices.Equal(got, tt.wantVary) {
+				t.Errorf("Vary = %q, want %q", got, tt.wantVary)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/version"
+)
+
+// Check reports whether a dependency of the server is ready to take requests.
+type Check func() CheckResult
+
+type CheckResult struct {
+	Ready  bool           `json:"ready"`
+	Detail map[string]any `json:"detail,omitempty"`
+}
+
+type readiness struct {
+	Status       string                 `json:"status"`
+	ShuttingDown bool                   `json:"shutting_down"`
+	Checks       map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// AddCheck adds a readiness check reported by /readyz under name.
+func (a *ApiServer) AddCheck(name string, check Check) {
+	a.checks[name] = check
+}
+
+// Pool is a bounded pool of workers, like worker.Pool.
+type Pool interface {
+	InUse() int
+	Size() int
+}
+
+// PoolCheck reports how saturated pool is. A busy pool queues requests, it doesn't make the server unready:
+// taking it out of rotation at peak load would only push the load onto the others.
+func PoolCheck(pool Pool) Check {
+	return func() CheckResult {
+		inUse, size := pool.InUse(), pool.Size()
+		return CheckResult{
+			Ready:  true,
+			Detail: map[string]any{"in_use": inUse, "size": size, "saturation": float64(inUse) / float64(size)},
+		}
+	}
+}
+
+// AddHealthHandlers registers /healthz, /readyz and /version. Like file handlers they skip the middleware,
+// probes would otherwise fill the request log.
+func (a *ApiServer) AddHealthHandlers() {
+	a.mux.HandleFunc("/healthz", getOnly(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}))
+	a.mux.HandleFunc("/readyz", getOnly(a.readyHandler))
+	a.mux.HandleFunc("/version", getOnly(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, version.Get())
+	}))
+}
+
+// readyHandler answers 503 once the server is shutting down or while any check fails.
+func (a *ApiServer) readyHandler(w http.ResponseWriter, r *http.Request) {
+	res := readiness{Status: "ready", ShuttingDown: a.shuttingDown.Load(), Checks: make(map[string]CheckResult)}
+	ready := !res.ShuttingDown
+	for name, check := range a.checks {
+		c := check()
+		res.Checks[name] = c
+		ready = ready && c.Ready
+	}
+
+	status := http.StatusOK
+	if !ready {
+		res.Status, status = "not_ready", http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, res)
+}
+
+func getOnly(hFunc HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			hFunc(w, r)
+		default:
+			problem.MethodNotAllowed(w, r)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
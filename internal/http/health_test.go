@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kyzrfranz/go-fitter/internal/worker"
+)
+
+func TestReady(t *testing.T) {
+	pool := worker.NewPool(4)
+	a := NewApiServer(0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	a.AddHealthHandlers()
+	a.AddCheck("workers", PoolCheck(pool))
+
+	tests := []struct {
+		name         string
+		acquire      int
+		shuttingDown bool
+		wantStatus   int
+		wantInUse    float64
+		wantSat      float64
+	}{
+		{name: "idle", wantStatus: http.StatusOK},
+		{name: "half busy", acquire: 2, wantStatus: http.StatusOK, wantInUse: 2, wantSat: 0.5},
+		{name: "saturated pool stays ready", acquire: 4, wantStatus: http.StatusOK, wantInUse: 4, wantSat: 1},
+		{name: "shutting down", shuttingDown: true, wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range tt.acquire {
+				if err := pool.Acquire(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				defer pool.Release()
+			}
+			a.shuttingDown.Store(tt.shuttingDown)
+
+			rec := httptest.NewRecorder()
+			a.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			var res struct {
+				Status       string `json:"status"`
+				ShuttingDown bool   `json:"shutting_down"`
+				Checks       map[string]struct {
+					Ready  bool               `json:"ready"`
+					Detail map[string]float64 `json:"detail"`
+				} `json:"checks"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			workers := res.Checks["workers"]
+			if res.ShuttingDown != tt.shuttingDown || (res.Status == "ready") != (tt.wantStatus == http.StatusOK) || !workers.Ready {
+				t.Errorf("readiness = %+v, want shutting down %v", res, tt.shuttingDown)
+			}
+			if d := workers.Detail; d["in_use"] != tt.wantInUse || d["size"] != 4 || d["saturation"] != tt.wantSat {
+				t.Errorf("workers = %v, want %v of 4 in use, saturation %v", d, tt.wantInUse, tt.wantSat)
+			}
+		})
+	}
+}
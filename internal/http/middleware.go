@@ -1,70 +1,198 @@
 package http
 
 import (
-	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"runtime"
+	"strconv"
 	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/logging"
+	"github.com/kyzrfranz/go-fitter/internal/metrics"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/reqid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func MiddlewareRecovery(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if rec := recover(); rec != nil {
-				stack := make([]byte, 1<<16)
-				length := runtime.Stack(stack, true)
+// MiddlewareRequestID gives every request an ID, the client's X-Request-ID if it is usable or a new one,
+// and returns it in the response header. The request context gets a logger with the ID attached, handlers
+// find it with logging.FromContext.
+func MiddlewareRequestID(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(reqid.Header)
+			if !reqid.Valid(id) {
+				id = reqid.New()
+			}
+			w.Header().Set(reqid.Header, id)
+			ctx := reqid.NewContext(r.Context(), id)
+			ctx = logging.NewContext(ctx, logger.With(slog.String("request_id", id)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-				// Convert the stack trace to a string
-				stackStr := string(stack[:length])
+// MiddlewareRecovery turns a panicking handler into a 500, logging the panic and the stack with the
+// request's logger. Nothing can be written once the handler has started its response.
+func MiddlewareRecovery(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				if v := recover(); v != nil {
+					stack := make([]byte, 1<<16)
+					length := runtime.Stack(stack, false)
 
-				// Log the error and the stack trace
-				slog.Log(context.Background(), slog.LevelError, "recovered from panic", slog.String("error", fmt.Sprintf("%v", r)), slog.String("stack", stackStr))
-				fmt.Println(fmt.Sprintf("%v", stackStr))
+					logging.FromContext(r.Context(), logger).Log(r.Context(), slog.LevelError, "recovered from panic",
+						slog.String("error", fmt.Sprint(v)),
+						slog.String("stack", string(stack[:length])))
 
-				// Return internal server error
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			}
-		}()
-		next.ServeHTTP(w, r)
-	})
+					if !rec.wroteHeader {
+						problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+					}
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
 }
 
-func MiddlewareLogging(logger *slog.Logger) func(http.Handler) http.Handler {
+// MiddlewareLogging logs every request with the request's logger, at debug level when it comes in and
+// with the status code and response size once it is done.
+func MiddlewareLogging(logger *slog.Logger) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			logger.Info("incoming request",
+			log := logging.FromContext(r.Context(), logger)
+			log.Log(r.Context(), slog.LevelDebug, "incoming request",
 				slog.String("method", r.Method),
 				slog.String("url", r.URL.String()),
 				slog.String("remote_addr", r.RemoteAddr),
 			)
 
-			next.ServeHTTP(w, r)
-			duration := time.Since(start)
-			logger.Info("completed request",
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			log.Log(r.Context(), slog.LevelInfo, "completed request",
 				slog.String("method", r.Method),
 				slog.String("url", r.URL.String()),
 				slog.String("remote_addr", r.RemoteAddr),
-				slog.Duration("duration", duration),
+				slog.Int("status", rec.status),
+				slog.Int64("size", rec.size),
+				slog.Duration("duration", time.Since(start)),
 			)
 		})
 	}
 }
 
-func MiddlewareCORS(next http.Handler) http.Handler {
+// MiddlewareMetrics records request counts, latency and upload sizes by route. The route is the pattern
+// the handler was registered with, not the request path, and methods outside the standard ones are
+// counted as "other", to keep the number of series bounded.
+func MiddlewareMetrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, Content-Length")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		var body *countingReader
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &countingReader{ReadCloser: r.Body}
+			r.Body = body
+		}
+
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(rec.status)
+		metrics.HTTPRequests.Inc(methodLabel(r.Method), route, status)
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), route, status)
+		if body != nil && body.n > 0 {
+			metrics.UploadSize.Observe(float64(body.n), route)
 		}
+	})
+}
 
-		next.ServeHTTP(w, r)
+// methodLabel returns method if it is a standard HTTP method and "other" otherwise, clients can send any token.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// statusRecorder remembers the status code written through it and counts the bytes of the body.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status, s.wroteHeader = code, true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.size += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
+// countingReader counts the bytes of a request body that the handler actually read.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+var tracer = otel.Tracer("github.com/kyzrfranz/go-fitter/internal/http")
+
+// MiddlewareTracing starts a server span per request, continuing the trace of an incoming traceparent header.
+// Handlers find the span in the request context.
+func MiddlewareTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := r.Pattern
+		if route == "" {
+			route = r.URL.Path
+		}
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", r.RemoteAddr),
+				attribute.Int64("http.request.body.size", r.ContentLength),
+			))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
 	})
 }
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kyzrfranz/go-fitter/internal/logging"
+	"github.com/kyzrfranz/go-fitter/internal/metrics"
+	"github.com/kyzrfranz/go-fitter/internal/reqid"
+)
+
+// requests returns the value of a series of the request counter, which counts across tests.
+func requests(t *testing.T, labels string) float64 {
+	t.Helper()
+	var b strings.Builder
+	if _, err := metrics.Default.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(b.String(), "\n") {
+		if v, ok := strings.CutPrefix(line, "gofitter_http_requests_total"+labels+" "); ok {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics-test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := MiddlewareMetrics(mux)
+	get, other := `{method="GET",route="/metrics-test",status="418"}`, `{method="other",route="/metrics-test",status="418"}`
+	gets, others := requests(t, get), requests(t, other)
+
+	for _, method := range []string{http.MethodGet, "PROPFIND", "X-ANYTHING"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/metrics-test", nil))
+	}
+
+	if n := requests(t, get) - gets; n != 1 {
+		t.Errorf("%d GET requests counted, want 1", int(n))
+	}
+	if n := requests(t, other) - others; n != 2 {
+		t.Errorf("%d requests of other methods counted, want 2", int(n))
+	}
+	if n := requests(t, `{method="PROPFIND",route="/metrics-test",status="418"}`); n != 0 {
+		t.Error("non-standard method recorded as its own series")
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		sent   string
+		wantID string // "" for a new one
+	}{
+		{name: "none sent"},
+		{name: "client ID", sent: "trace-42", wantID: "trace-42"},
+		{name: "unusable client ID", sent: "a\tb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+			var seen string
+			h := MiddlewareRequestID(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = reqid.FromContext(r.Context())
+				logging.FromContext(r.Context(), nil).Info("converted")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/fit", nil)
+			if tt.sent != "" {
+				r.Header.Set(reqid.Header, tt.sent)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			id := rec.Header().Get(reqid.Header)
+			if tt.wantID != "" && id != tt.wantID || tt.wantID == "" && (id == tt.sent || !reqid.Valid(id)) {
+				t.Errorf("%s = %q, sent %q", reqid.Header, id, tt.sent)
+			}
+			if seen != id {
+				t.Errorf("handler saw ID %q, response has %q", seen, id)
+			}
+			if !strings.Contains(buf.String(), "request_id="+id) {
+				t.Errorf("logged %q without the request ID", buf.String())
+			}
+		})
+	}
+}
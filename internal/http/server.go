@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -24,6 +25,12 @@ type ApiServer struct {
 	mux        *http.ServeMux
 	middleware []Middleware
 	logger     *slog.Logger
+	cors       CORSPolicy
+	tls        *certReloader
+
+	checks        map[string]Check
+	shuttingDown  atomic.Bool
+	shutdownDelay time.Duration
 }
 
 func NewApiServer(port int, logger *slog.Logger) *ApiServer {
@@ -36,9 +43,17 @@ func NewApiServer(port int, logger *slog.Logger) *ApiServer {
 			Handler: h2c.NewHandler(mux, &http2.Server{}),
 		},
 		logger: logger,
+		cors:   DefaultCORSPolicy(),
+		checks: make(map[string]Check),
 	}
 }
 
+// SetShutdownDelay keeps serving for d after a shutdown signal while /readyz already fails,
+// giving load balancers time to stop sending new requests.
+func (a *ApiServer) SetShutdownDelay(d time.Duration) {
+	a.shutdownDelay = d
+}
+
 func (a *ApiServer) Use(mw Middleware) {
 	a.middleware = append(a.middleware, mw)
 }
@@ -50,24 +65,22 @@ func (a *ApiServer) applyMiddleware(h http.Handler) http.Handler {
 	return h
 }
 
-func (a *ApiServer) AddHandler(path string, hFunc HandlerFunc) {
-	finalHandler := a.applyMiddleware(http.HandlerFunc(hFunc))
-	a.mux.Handle(path, finalHandler)
+// AddHandler registers hFunc for path behind the server's middleware. Route middleware, like an
+// authorization check, runs after it, closest to the handler.
+func (a *ApiServer) AddHandler(path string, hFunc HandlerFunc, routeMiddleware ...Middleware) {
+	var h http.Handler = http.HandlerFunc(hFunc)
+	for i := len(routeMiddleware) - 1; i >= 0; i-- {
+		h = routeMiddleware[i](h)
+	}
+	a.mux.Handle(path, a.applyMiddleware(h))
 }
 
 func (a *ApiServer) AddStaticHandler(urlPath string, dirPath string) {
 	// FileServer to serve static files
 	fileServer := http.FileServer(http.Dir(dirPath))
 
-	// Custom handler to serve .wasm files with correct Content-Type
-	a.mux.Handle(urlPath, http.StripPrefix(urlPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers for all static file requests
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		fileServer.ServeHTTP(w, r)
-	})))
+	// Static files get the same CORS policy as the API
+	a.mux.Handle(urlPath, http.StripPrefix(urlPath, MiddlewareCORS(a.cors)(fileServer)))
 }
 
 func (a *ApiServer) AddFileHandler(path string, filePath string, mimeType string) {
@@ -84,18 +97,33 @@ func (a *ApiServer) Start() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	if a.tls != nil {
+		go a.tls.watch(reloadCtx)
+	}
+
 	// Run the server in a goroutine
 	go func() {
-		a.logger.Log(context.Background(), slog.LevelInfo, "Server is running", "addr", a.server.Addr)
-		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			a.logger.Log(context.Background(), slog.LevelError, "Could not listen", "addr", a.server.Addr)
+		a.logger.Log(context.Background(), slog.LevelInfo, "Server is running", "addr", a.server.Addr, "tls", a.tls != nil)
+		var err error
+		if a.tls != nil {
+			// The certificate comes from the TLS config
+			err = a.server.ListenAndServeTLS("", "")
+		} else {
+			err = a.server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Log(context.Background(), slog.LevelError, "Could not listen", "addr", a.server.Addr, "error", err.Error())
 			os.Exit(1)
 		}
 	}()
 
 	// Block until we receive a signal
 	<-stop
-	a.logger.Log(context.Background(), slog.LevelInfo, "shutting down server...")
+	a.shuttingDown.Store(true)
+	a.logger.Log(context.Background(), slog.LevelInfo, "shutting down server...", slog.Duration("delay", a.shutdownDelay))
+	time.Sleep(a.shutdownDelay)
 
 	// Create a deadline to wait for the server to shut down gracefully
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ClientAuthRequire  = "require"  // Every client must present a certificate signed by the client CA
+	ClientAuthOptional = "optional" // Certificates are verified when presented, clients without one get through
+)
+
+// TLSConfig turns on TLS when CertFile and KeyFile are set, and client certificate verification (mTLS)
+// when ClientCAFile is set too.
+type TLSConfig struct {
+	CertFile       string
+	KeyFile        string
+	ClientCAFile   string
+	ClientAuth     string        // require or optional
+	MinVersion     string        // 1.2 or 1.3
+	ReloadInterval time.Duration // How often the files are checked for changes, 0 disables reloading
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// Validate checks that the settings fit together, the files themselves are read by SetTLS.
+func (c TLSConfig) Validate() error {
+	if !c.Enabled() {
+		if c.ClientCAFile != "" {
+			return errors.New("tls: a client CA needs a certificate and key")
+		}
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return errors.New("tls: both certificate and key are required")
+	}
+	if _, err := tlsVersion(c.MinVersion); err != nil {
+		return err
+	}
+	switch c.ClientAuth {
+	case "", ClientAuthRequire, ClientAuthOptional:
+	default:
+		return fmt.Errorf("tls: unknown client auth %q, want %s or %s", c.ClientAuth, ClientAuthRequire, ClientAuthOptional)
+	}
+	return nil
+}
+
+func tlsVersion(s string) (uint16, error) {
+	switch s {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("tls: unsupported minimum version %q, want 1.2 or 1.3", s)
+	}
+}
+
+// certReloader serves the certificate and client CAs most recently read from disk. Like the watch
+// folder it polls modification times, so renewals by cert-manager or certbot are picked up without a
+// restart. A file that fails to load keeps the previous one in use.
+type certReloader struct {
+	cfg    TLSConfig
+	base   *tls.Config
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+func newCertReloader(cfg TLSConfig, logger *slog.Logger) (*certReloader, error) {
+	minVersion, err := tlsVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	r := &certReloader{cfg: cfg, logger: logger, modTimes: make(map[string]time.Time)}
+	r.base = &tls.Config{
+		MinVersion: minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if cfg.ClientCAFile != "" {
+		r.base.ClientAuth = tls.RequireAndVerifyClientCert
+		if cfg.ClientAuth == ClientAuthOptional {
+			r.base.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// config is the server's TLS configuration, each handshake gets the current certificate and client CAs.
+func (r *certReloader) config() *tls.Config {
+	c := r.base.Clone()
+	c.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		hc := r.base.Clone()
+		hc.Certificates = []tls.Certificate{*r.cert}
+		hc.ClientCAs = r.clientCA
+		return hc, nil
+	}
+	return c
+}
+
+// reload reads the files again if any of them changed since the last time and reports whether it did.
+func (r *certReloader) reload() (bool, error) {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	modTimes := make(map[string]time.Time, len(files))
+	changed := false
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return false, fmt.Errorf("tls: %w", err)
+		}
+		modTimes[f] = info.ModTime()
+		changed = changed || !info.ModTime().Equal(r.modTimes[f])
+	}
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("tls: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("tls: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("tls: no certificates in %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCA, r.modTimes = &cert, pool, modTimes
+	r.mu.Unlock()
+
+	r.logger.Log(context.Background(), slog.LevelInfo, "loaded TLS certificate",
+		slog.String("subject", cert.Leaf.Subject.String()),
+		slog.Time("not_after", cert.Leaf.NotAfter),
+		slog.Bool("client_auth", pool != nil))
+	return true, nil
+}
+
+// watch reloads the files every ReloadInterval until ctx is done.
+func (r *certReloader) watch(ctx context.Context) {
+	if r.cfg.ReloadInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.reload(); err != nil {
+				r.logger.Log(ctx, slog.LevelError, "could not reload TLS certificate, keeping the current one", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// SetTLS makes the server listen with TLS, serving HTTP/2 and HTTP/1.1 through ALPN instead of h2c. It
+// fails when the certificate, key or client CA can't be loaded.
+func (a *ApiServer) SetTLS(cfg TLSConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+	reloader, err := newCertReloader(cfg, a.logger)
+	if err != nil {
+		return err
+	}
+	a.tls = reloader
+	a.server.TLSConfig = reloader.config()
+	a.server.Handler = a.mux
+	return nil
+}
@@ -0,0 +1,284 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issuer signs test certificates, a self-signed CA or the leaf it issued.
+type issuer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+var serial int64
+
+func newCertificate(t *testing.T, template *x509.Certificate, parent *issuer) (issuer, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	template.SerialNumber = big.NewInt(serial)
+	template.NotBefore, template.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issuer{cert: cert, key: key},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func newCA(t *testing.T, name string) (issuer, []byte) {
+	t.Helper()
+	ca, certPEM, _ := newCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	return ca, certPEM
+}
+
+func (ca issuer) server(t *testing.T, name string) ([]byte, []byte) {
+	t.Helper()
+	_, certPEM, keyPEM := newCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, &ca)
+	return certPEM, keyPEM
+}
+
+func (ca issuer) client(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	_, certPEM, keyPEM := newCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &ca)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// writeFile writes data to path and moves its modification time to at, file systems may not tell two
+// writes in quick succession apart.
+func writeFile(t *testing.T, path string, data []byte, at time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveTLS serves the reloader's configuration on a local port, answering with the common name of the
+// client certificate, if any.
+func serveTLS(t *testing.T, r *certReloader) string {
+	t.Helper()
+	l, err := tls.Listen("tcp", "127.0.0.1:0", r.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+	}), ErrorLog: log.New(io.Discard, "", 0)}
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "https://" + l.Addr().String()
+}
+
+// get requests addr trusting roots, with the client certificate if given, and returns the server's
+// certificate name and the body.
+func get(t *testing.T, addr string, roots *x509.CertPool, cert *tls.Certificate) (server, body string, err error) {
+	t.Helper()
+	cfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if cert != nil {
+		// Certificates would hold back one the server doesn't name an acceptable CA for
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return cert, nil }
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(addr)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, string(b), err
+}
+
+func discard() *slog.Logger { return slog.New(slog.DiscardHandler) }
+
+func TestTLSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     TLSConfig
+		wantErr bool
+	}{
+		{name: "off"},
+		{name: "certificate and key", cfg: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", MinVersion: "1.3"}},
+		{name: "mTLS", cfg: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", ClientCAFile: "ca.crt", ClientAuth: ClientAuthOptional}},
+		{name: "certificate without key", cfg: TLSConfig{CertFile: "tls.crt"}, wantErr: true},
+		{name: "client CA without TLS", cfg: TLSConfig{ClientCAFile: "ca.crt"}, wantErr: true},
+		{name: "TLS 1.1", cfg: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", MinVersion: "1.1"}, wantErr: true},
+		{name: "unknown client auth", cfg: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", ClientAuth: "always"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCertReload(t *testing.T) {
+	dir := t.TempDir()
+	ca, caPEM := newCA(t, "server CA")
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+
+	cfg := TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	at := time.Now().Add(-time.Hour)
+	write := func(name string) {
+		certPEM, keyPEM := ca.server(t, name)
+		at = at.Add(time.Minute)
+		writeFile(t, cfg.CertFile, certPEM, at)
+		writeFile(t, cfg.KeyFile, keyPEM, at)
+	}
+	var addr string
+	serving := func() string {
+		t.Helper()
+		name, _, err := get(t, addr, roots, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	write("first")
+	r, err := newCertReloader(cfg, discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr = serveTLS(t, r)
+	if got := serving(); got != "first" {
+		t.Fatalf("serving %q, want first", got)
+	}
+
+	if changed, err := r.reload(); changed || err != nil {
+		t.Errorf("reload() of unchanged files = %v, %v", changed, err)
+	}
+
+	write("renewed")
+	if changed, err := r.reload(); !changed || err != nil {
+		t.Fatalf("reload() after a renewal = %v, %v", changed, err)
+	}
+	if got := serving(); got != "renewed" {
+		t.Errorf("serving %q after a renewal, want renewed", got)
+	}
+
+	// A broken file keeps the last certificate that loaded
+	at = at.Add(time.Minute)
+	writeFile(t, cfg.CertFile, []byte("not a certificate"), at)
+	if _, err := r.reload(); err == nil {
+		t.Error("reload() of a broken certificate succeeded")
+	}
+	if got := serving(); got != "renewed" {
+		t.Errorf("serving %q after a failed reload, want renewed", got)
+	}
+
+	if _, err := newCertReloader(cfg, discard()); err == nil {
+		t.Error("newCertReloader() with a broken certificate succeeded")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCA, serverCAPEM := newCA(t, "server CA")
+	clientCA, clientCAPEM := newCA(t, "client CA")
+	otherCA, _ := newCA(t, "other CA")
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(serverCAPEM)
+
+	certPEM, keyPEM := serverCA.server(t, "server")
+	at := time.Now()
+	writeFile(t, filepath.Join(dir, "tls.crt"), certPEM, at)
+	writeFile(t, filepath.Join(dir, "tls.key"), keyPEM, at)
+	writeFile(t, filepath.Join(dir, "ca.crt"), clientCAPEM, at)
+
+	trusted, untrusted := clientCA.client(t, "ci"), otherCA.client(t, "intruder")
+	tests := []struct {
+		name       string
+		clientAuth string
+		cert       *tls.Certificate
+		want       string // the name the handler saw
+		wantErr    bool
+	}{
+		{name: "required and trusted", clientAuth: ClientAuthRequire, cert: &trusted, want: "ci"},
+		{name: "required but missing", clientAuth: ClientAuthRequire, wantErr: true},
+		{name: "required but untrusted", clientAuth: ClientAuthRequire, cert: &untrusted, wantErr: true},
+		{name: "default is required", cert: nil, wantErr: true},
+		{name: "optional and trusted", clientAuth: ClientAuthOptional, cert: &trusted, want: "ci"},
+		{name: "optional and missing", clientAuth: ClientAuthOptional},
+		{name: "optional but untrusted", clientAuth: ClientAuthOptional, cert: &untrusted, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newCertReloader(TLSConfig{
+				CertFile:     filepath.Join(dir, "tls.crt"),
+				KeyFile:      filepath.Join(dir, "tls.key"),
+				ClientCAFile: filepath.Join(dir, "ca.crt"),
+				ClientAuth:   tt.clientAuth,
+			}, discard())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, got, err := get(t, serveTLS(t, r), roots, tt.cert)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("request error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("handler saw client %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	fallback := slog.New(slog.NewTextHandler(&buf, nil)).With(slog.String("logger", "fallback"))
+	request := slog.New(slog.NewTextHandler(&buf, nil)).With(slog.String("request_id", "abc"))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "outside of a request", ctx: context.Background(), want: "logger=fallback"},
+		{name: "request", ctx: NewContext(context.Background(), request), want: "request_id=abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			FromContext(tt.ctx, fallback).Info("converted")
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("logged %q, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
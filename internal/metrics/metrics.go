@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/proto"
+)
+
+// Default holds the metrics of the API server.
+var Default = NewRegistry()
+
+var (
+	durationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+	sizeBuckets     = ExponentialBuckets(1<<10, 4, 10) // 1 KiB to 256 MiB
+
+	HTTPRequests = Default.NewCounter("gofitter_http_requests_total",
+		"HTTP requests by method, route and status code.", "method", "route", "status")
+	HTTPDuration = Default.NewHistogram("gofitter_http_request_duration_seconds",
+		"HTTP request latency by route and status code.", durationBuckets, "route", "status")
+	UploadSize = Default.NewHistogram("gofitter_upload_size_bytes",
+		"Size of uploaded request bodies by route.", sizeBuckets, "route")
+	DecodeDuration = Default.NewHistogram("gofitter_decode_duration_seconds",
+		"Time spent decoding FIT files by output format.", durationBuckets, "format")
+	DecodedMessages = Default.NewCounter("gofitter_decoded_messages_total",
+		"FIT messages decoded by message type.", "type")
+	ConverterErrors = Default.NewCounter("gofitter_converter_errors_total",
+		"Failed decodes and conversions by output format.", "format")
+)
+
+// Handler serves the Default registry.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = Default.WriteTo(w)
+}
+
+// Decode instruments a single decode into format: pass the returned options to the decoder and call done
+// with its error once it is finished.
+//
+//	opts, done := metrics.Decode("json")
+//	msg, err := converters.FitToJson(r, append(decoderOptions, opts...))
+//	done(err)
+func Decode(format string) (opts []decoder.Option, done func(err error)) {
+	c := &mesgCounter{counts: make(map[typedef.MesgNum]int)}
+	start := time.Now()
+	return []decoder.Option{decoder.WithMesgListener(c)}, func(err error) {
+		DecodeDuration.Observe(time.Since(start).Seconds(), format)
+		if err != nil {
+			ConverterErrors.Inc(format)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for num, n := range c.counts {
+			typ := num.String()
+			if strings.HasPrefix(typ, "MesgNumInvalid") {
+				typ = "unknown" // manufacturer specific, don't let them blow up the number of series
+			}
+			DecodedMessages.Add(float64(n), typ)
+		}
+		clear(c.counts)
+	}
+}
+
+// mesgCounter counts messages by type locally, so the decoder doesn't contend on the shared counter.
+type mesgCounter struct {
+	mu     sync.Mutex
+	counts map[typedef.MesgNum]int
+}
+
+func (c *mesgCounter) OnMesg(mesg proto.Message) {
+	c.mu.Lock()
+	c.counts[mesg.Num]++
+	c.mu.Unlock()
+}
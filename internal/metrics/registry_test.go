@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("requests_total", "Requests\nby path.", "path")
+	c.Inc("/b")
+	c.Add(2, `/a"quoted"`)
+	c.Inc(`C:\fit` + "\n")
+	c.Inc("/b")
+
+	var b strings.Builder
+	if _, err := reg.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests by path.
+# TYPE requests_total counter
+requests_total{path="/a\"quoted\""} 2
+requests_total{path="/b"} 2
+requests_total{path="C:\\fit\n"} 1
+`
+	if b.String() != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.NewHistogram("duration_seconds", "Duration.", []float64{0.1, 1, 10}, "route")
+	for _, v := range []float64{0.05, 0.1, 0.5, 20, 30} {
+		h.Observe(v, "/fit")
+	}
+	reg.NewHistogram("size_bytes", "Size.", ExponentialBuckets(1, 4, 2)).Observe(2)
+
+	var b strings.Builder
+	if _, err := reg.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	// Buckets count every observation up to their bound, the +Inf bucket all of them
+	want := `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/fit",le="0.1"} 2
+duration_seconds_bucket{route="/fit",le="1"} 3
+duration_seconds_bucket{route="/fit",le="10"} 3
+duration_seconds_bucket{route="/fit",le="+Inf"} 5
+duration_seconds_sum{route="/fit"} 50.65
+duration_seconds_count{route="/fit"} 5
+# HELP size_bytes Size.
+# TYPE size_bytes histogram
+size_bytes_bucket{le="1"} 0
+size_bytes_bucket{le="4"} 1
+size_bytes_bucket{le="+Inf"} 1
+size_bytes_sum 2
+size_bytes_count 1
+`
+	if b.String() != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	c := NewRegistry().NewCounter("requests_total", "Requests.", "method", "route")
+	tests := []struct {
+		name   string
+		values []string
+	}{
+		{name: "too few", values: []string{"GET"}},
+		{name: "too many", values: []string{"GET", "/fit", "200"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), "wants 2 label values") {
+					t.Errorf("Inc(%q) panic = %v, want a label count panic", tt.values, r)
+				}
+			}()
+			c.Inc(tt.values...)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package negotiate picks the representation of a response from the Accept and Accept-Encoding headers
+// of the request (RFC 9110, section 12), out of the ones the server offers in order of its preference.
+package negotiate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ContentType returns the offer the Accept header prefers, the first offer when the header is empty and
+// "" when the client accepts none of them. The most specific media range matching an offer decides its
+// quality, ties go to the earlier offer. Parameters other than q are ignored.
+func ContentType(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parse(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := matchMediaRange(r.value, strings.ToLower(offer)); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Encoding returns the content coding the Accept-Encoding header prefers, or "" for the identity coding
+// when the header is empty or accepts none of the offers. An offer the header doesn't name takes the
+// quality of "*", ties go to the earlier offer.
+func Encoding(acceptEncoding string, offers ...string) string {
+	codings := parse(acceptEncoding)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, exact := 0.0, false
+		for _, c := range codings {
+			switch {
+			case c.value == strings.ToLower(offer):
+				q, exact = c.q, true
+			case c.value == "*" && !exact:
+				q = c.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+type accepted struct {
+	value string
+	q     float64
+}
+
+// parse splits a header like "text/html;q=0.9, */*;q=0.1" into its values and their quality. Entries with
+// a q that doesn't parse are dropped.
+func parse(header string) []accepted {
+	var list []accepted
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		a := accepted{value: value, q: 1}
+		valid := true
+		for _, param := range strings.Split(params, ";") {
+			name, v, _ := strings.Cut(param, "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			a.q = q
+		}
+		if valid {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// matchMediaRange tells how specifically mediaRange matches mediaType: -1 not at all, 0 for */*, 1 for
+// type/* and 2 for the type itself.
+func matchMediaRange(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,57 @@
+package negotiate
+
+import "testing"
+
+func TestContentType(t *testing.T) {
+	offers := []string{"application/json", "application/gpx+xml", "text/csv"}
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{name: "empty header takes the first offer", accept: "", want: "application/json"},
+		{name: "exact match", accept: "text/csv", want: "text/csv"},
+		{name: "case insensitive", accept: "Text/CSV", want: "text/csv"},
+		{name: "any", accept: "*/*", want: "application/json"},
+		{name: "type wildcard", accept: "text/*", want: "text/csv"},
+		{name: "highest quality wins", accept: "application/json;q=0.5, text/csv;q=0.8", want: "text/csv"},
+		{name: "ties go to the earlier offer", accept: "text/csv, application/gpx+xml", want: "application/gpx+xml"},
+		{name: "specific range beats wildcard", accept: "*/*;q=0.9, application/json;q=0.1", want: "application/gpx+xml"},
+		{name: "q=0 refuses an offer", accept: "application/json;q=0, */*;q=0.5", want: "application/gpx+xml"},
+		{name: "other parameters are ignored", accept: "text/csv;charset=utf-8", want: "text/csv"},
+		{name: "invalid q drops the entry", accept: "text/csv;q=2, application/json;q=0.1", want: "application/json"},
+		{name: "nothing acceptable", accept: "image/png", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentType(tt.accept, offers...); got != tt.want {
+				t.Errorf("ContentType(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	offers := []string{"zstd", "gzip", "deflate"}
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{name: "empty header is identity", acceptEncoding: "", want: ""},
+		{name: "single coding", acceptEncoding: "gzip", want: "gzip"},
+		{name: "ties go to the earlier offer", acceptEncoding: "gzip, deflate, zstd", want: "zstd"},
+		{name: "quality", acceptEncoding: "zstd;q=0.1, gzip;q=0.9", want: "gzip"},
+		{name: "any", acceptEncoding: "*", want: "zstd"},
+		{name: "named coding beats any", acceptEncoding: "*;q=0.2, deflate;q=0.5", want: "deflate"},
+		{name: "q=0 refuses a coding", acceptEncoding: "zstd;q=0, *", want: "gzip"},
+		{name: "unknown coding is identity", acceptEncoding: "br", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Encoding(tt.acceptEncoding, offers...); got != tt.want {
+				t.Errorf("Encoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package problem writes error responses as RFC 9457 problem details (application/problem+json).
+//
+// Every problem has a stable code, clients should branch on it rather than on the status or the detail
+// text. The type is the code as a URN, the request ID ties the response to the server's logs.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/kyzrfranz/go-fitter/internal/reqid"
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	"github.com/muktihari/fit/decoder"
+)
+
+const ContentType = "application/problem+json"
+
+type Code string
+
+const (
+	CodeInvalidMultipart  Code = "invalid_multipart"
+	CodeMissingFile       Code = "missing_file"
+	CodeTooLarge          Code = "too_large"
+	CodeUnsupportedMedia  Code = "unsupported_media_type"
+	CodeDecodeFailed      Code = "decode_failed"
+	CodeUnsupportedProto  Code = "unsupported_protocol"
+	CodeChecksumMismatch  Code = "checksum_mismatch"
+	CodeConvertFailed     Code = "convert_failed"
+	CodeInvalidParameter  Code = "invalid_parameter"
+	CodeUnprocessable     Code = "unprocessable"
+	CodeNotFound          Code = "not_found"
+	CodeMethodNotAllowed  Code = "method_not_allowed"
+	CodeNotAcceptable     Code = "not_acceptable"
+	CodeUnauthorized      Code = "unauthorized"
+	CodeInvalidToken      Code = "invalid_token"
+	CodeInsufficientScope Code = "insufficient_scope"
+	CodeRateLimited       Code = "rate_limited"
+	CodeQuotaExceeded     Code = "quota_exceeded"
+	CodeUnavailable       Code = "unavailable"
+	CodeInternal          Code = "internal"
+)
+
+// Details is the response body.
+type Details struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      Code   `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error attaches a status and code to err, for functions that know better than Error's mapping what
+// went wrong.
+type Error struct {
+	Status int
+	Code   Code
+	Err    error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func Wrap(status int, code Code, err error) error {
+	return &Error{Status: status, Code: code, Err: err}
+}
+
+// Write answers r with a problem.
+func Write(w http.ResponseWriter, r *http.Request, status int, code Code, detail string) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Details{
+		Type:      "urn:go-fitter:problem:" + string(code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: reqid.FromContext(r.Context()),
+	})
+}
+
+// WriteError answers r with the problem err maps to, see From.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := From(err)
+	Write(w, r, status, code, err.Error())
+}
+
+// MethodNotAllowed is the answer of every handler's default case.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not supported")
+}
+
+// From maps err to a status and code. Checksum mismatches come first, whoever decoded the file, then
+// wrapped *Error values, oversized bodies and the typed errors of the converters package. Anything else
+// is an internal error.
+func From(err error) (int, Code) {
+	var pe *Error
+	var maxBytes *http.MaxBytesError
+	var decodeErr *converters.DecodeError
+	var convertErr *converters.ConvertError
+	switch {
+	case errors.Is(err, converters.ErrChecksumMismatch), errors.Is(err, decoder.ErrCRCChecksumMismatch):
+		return http.StatusUnprocessableEntity, CodeChecksumMismatch
+	case errors.As(err, &pe):
+		return pe.Status, pe.Code
+	case errors.As(err, &maxBytes), errors.Is(err, multipart.ErrMessageTooLarge):
+		return http.StatusRequestEntityTooLarge, CodeTooLarge
+	case errors.As(err, &decodeErr):
+		return http.StatusUnprocessableEntity, CodeDecodeFailed
+	case errors.As(err, &convertErr):
+		return http.StatusInternalServerError, CodeConvertFailed
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
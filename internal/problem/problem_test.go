@@ -0,0 +1,95 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kyzrfranz/go-fitter/internal/reqid"
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	"github.com/muktihari/fit/decoder"
+)
+
+func TestWrite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/fit?format=gpx", nil)
+	r = r.WithContext(reqid.NewContext(r.Context(), "abc"))
+	w := httptest.NewRecorder()
+	Write(w, r, http.StatusBadRequest, CodeInvalidParameter, "format: unknown")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ContentType)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	var got Details
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := Details{
+		Type:      "urn:go-fitter:problem:invalid_parameter",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "format: unknown",
+		Instance:  "/fit",
+		Code:      CodeInvalidParameter,
+		RequestID: "abc",
+	}
+	if got != want {
+		t.Errorf("body = %+v, want %+v", got, want)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   Code
+	}{
+		{name: "checksum mismatch", err: converters.ErrChecksumMismatch, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeChecksumMismatch},
+		{name: "decoder checksum mismatch", err: fmt.Errorf("decode: %w", decoder.ErrCRCChecksumMismatch),
+			wantStatus: http.StatusUnprocessableEntity, wantCode: CodeChecksumMismatch},
+		{name: "checksum mismatch before a wrapped problem", err: Wrap(http.StatusBadRequest, CodeInvalidParameter, converters.ErrChecksumMismatch),
+			wantStatus: http.StatusUnprocessableEntity, wantCode: CodeChecksumMismatch},
+		{name: "problem", err: Wrap(http.StatusNotFound, CodeNotFound, errors.New("no such activity")),
+			wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+		{name: "wrapped problem", err: fmt.Errorf("ride.fit: %w", Wrap(http.StatusUnprocessableEntity, CodeUnsupportedProto, errors.New("v3"))),
+			wantStatus: http.StatusUnprocessableEntity, wantCode: CodeUnsupportedProto},
+		{name: "oversized body", err: &http.MaxBytesError{Limit: 10}, wantStatus: http.StatusRequestEntityTooLarge, wantCode: CodeTooLarge},
+		{name: "oversized form", err: multipart.ErrMessageTooLarge, wantStatus: http.StatusRequestEntityTooLarge, wantCode: CodeTooLarge},
+		{name: "decode error", err: &converters.DecodeError{Err: errors.New("bad header")},
+			wantStatus: http.StatusUnprocessableEntity, wantCode: CodeDecodeFailed},
+		{name: "convert error", err: &converters.ConvertError{Format: converters.FormatGPX, Err: errors.New("no records")},
+			wantStatus: http.StatusInternalServerError, wantCode: CodeConvertFailed},
+		{name: "anything else", err: errors.New("disk full"), wantStatus: http.StatusInternalServerError, wantCode: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := From(tt.err)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("From() = %d %s, want %d %s", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodPost, "/fit", nil), Wrap(http.StatusBadRequest, CodeMissingFile, errors.New("empty request body")))
+
+	var got Details
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || got.Code != CodeMissingFile || got.Detail != "empty request body" || got.RequestID != "" {
+		t.Errorf("WriteError() = %d %+v, want 400 missing_file without a request ID", w.Code, got)
+	}
+}
@@ -0,0 +1,406 @@
+// Package ratelimit limits how much of the server a single client can use: a token bucket bounds its
+// request rate and daily quotas bound the bytes it uploads and the conversions it runs.
+//
+// Clients are identified by their API key or JWT subject, or by IP address on unauthenticated servers.
+// State is kept in memory and can be persisted to a file so quotas survive restarts.
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/auth"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+)
+
+// Config sets the limits per client. Zero values disable that limit.
+type Config struct {
+	Rate             float64 // Requests per second
+	Burst            int     // Requests allowed at once, defaults to the rate rounded up
+	DailyBytes       int64   // Upload bytes per UTC day
+	DailyConversions int     // Conversions per UTC day
+	StateFile        string  // File to persist the state to
+}
+
+// client is the state of one client. Fields are exported for persisting.
+type client struct {
+	Tokens      float64   `json:"tokens"`
+	Updated     time.Time `json:"updated"`
+	Day         string    `json:"day"` // UTC date the quota counters belong to
+	Bytes       int64     `json:"bytes"`
+	Conversions int       `json:"conversions"`
+}
+
+type Limiter struct {
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*client
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a limiter and loads the state file, if any. Close must be called to save the state.
+func New(cfg Config, logger *slog.Logger) (*Limiter, error) {
+	if cfg.Burst <= 0 {
+		cfg.Burst = int(math.Ceil(cfg.Rate))
+	}
+	l := &Limiter{
+		cfg:     cfg,
+		logger:  logger,
+		now:     time.Now,
+		clients: make(map[string]*client),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if cfg.StateFile != "" {
+		if err := l.load(); err != nil {
+			return nil, fmt.Errorf("rate limit state: %w", err)
+		}
+	}
+	go l.maintain()
+	return l, nil
+}
+
+// Limit returns a middleware that rejects requests above the client's rate with 429.
+func (l *Limiter) Limit() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.cfg.Rate <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, remaining, retry := l.take(clientKey(r))
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(l.cfg.Burst))
+			h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			h.Set("RateLimit-Reset", seconds(l.untilFull(remaining)))
+			if !ok {
+				h.Set("Retry-After", seconds(retry))
+				problem.Write(w, r, http.StatusTooManyRequests, problem.CodeRateLimited, fmt.Sprintf("more than %g requests per second", l.cfg.Rate))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LimitFailures returns a middleware that limits the failed authentication attempts of every IP address to
+// the request rate, so API keys can't be guessed at full speed. It goes before the auth middleware, requests
+// with valid credentials don't count.
+func (l *Limiter) LimitFailures() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.cfg.Rate <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "auth:" + ipKey(r)
+			if ok, retry := l.peek(key); !ok {
+				w.Header().Set("Retry-After", seconds(retry))
+				problem.Write(w, r, http.StatusTooManyRequests, problem.CodeRateLimited, "too many failed authentication attempts")
+				return
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status == http.StatusUnauthorized {
+				l.take(key)
+			}
+		})
+	}
+}
+
+// Quota returns a middleware for the conversion routes that counts the upload bytes and successful
+// conversions of a request against the client's daily quotas and rejects requests once one is used up with
+// 429. GET and HEAD requests read what is there already and are let through uncounted.
+func (l *Limiter) Quota() func(http.Handler) http.Handler {
+	return l.quota(true)
+}
+
+// UploadQuota is Quota for routes that store uploads without converting them: they count against the byte
+// quota only.
+func (l *Limiter) UploadQuota() func(http.Handler) http.Handler {
+	return l.quota(false)
+}
+
+// quota rejects requests whose Content-Length exceeds the bytes left right away, and the others once they
+// have read that much of their body.
+func (l *Limiter) quota(convert bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.cfg.DailyBytes <= 0 && (!convert || l.cfg.DailyConversions <= 0) {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := clientKey(r)
+			remaining, err := l.checkQuota(key, r.ContentLength, convert)
+			if err != nil {
+				w.Header().Set("Retry-After", seconds(l.untilTomorrow()))
+				problem.Write(w, r, http.StatusTooManyRequests, problem.CodeQuotaExceeded, err.Error())
+				return
+			}
+
+			body := &countingReader{ReadCloser: r.Body, limit: remaining, exceeded: func() error {
+				w.Header().Set("Retry-After", seconds(l.untilTomorrow()))
+				return problem.Wrap(http.StatusTooManyRequests, problem.CodeQuotaExceeded, errBytesQuota)
+			}}
+			r.Body = body
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			conversions := 0
+			if convert && rec.status < http.StatusBadRequest {
+				conversions = 1
+			}
+			l.use(key, body.n, conversions)
+		})
+	}
+}
+
+// take removes a token from the client's bucket.
+func (l *Limiter) take(key string) (ok bool, remaining int, retry time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.refill(key)
+	if c.Tokens < 1 {
+		return false, 0, l.untilToken(c)
+	}
+	c.Tokens--
+	return true, int(c.Tokens), 0
+}
+
+// peek tells whether the client's bucket has a token, without taking it.
+func (l *Limiter) peek(key string) (ok bool, retry time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.refill(key)
+	if c.Tokens < 1 {
+		return false, l.untilToken(c)
+	}
+	return true, 0
+}
+
+// refill adds the tokens earned since the last update to the client's bucket. Callers hold l.mu.
+func (l *Limiter) refill(key string) *client {
+	c := l.client(key)
+	now := l.now()
+	c.Tokens = math.Min(float64(l.cfg.Burst), c.Tokens+now.Sub(c.Updated).Seconds()*l.cfg.Rate)
+	c.Updated = now
+	return c
+}
+
+func (l *Limiter) untilToken(c *client) time.Duration {
+	return time.Duration((1 - c.Tokens) / l.cfg.Rate * float64(time.Second))
+}
+
+var (
+	errBytesQuota      = errors.New("daily upload quota used up")
+	errConversionQuota = errors.New("daily conversion quota used up")
+)
+
+// checkQuota fails when the client has no conversions left, if convert is set, or size, if known, exceeds
+// its remaining bytes. It returns the remaining bytes, or -1 without a byte quota.
+func (l *Limiter) checkQuota(key string, size int64, convert bool) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.client(key)
+	if convert && l.cfg.DailyConversions > 0 && c.Conversions >= l.cfg.DailyConversions {
+		return 0, errConversionQuota
+	}
+	if l.cfg.DailyBytes <= 0 {
+		return -1, nil
+	}
+	remaining := l.cfg.DailyBytes - c.Bytes
+	if remaining <= 0 || max(size, 0) > remaining {
+		return 0, errBytesQuota
+	}
+	return remaining, nil
+}
+
+func (l *Limiter) use(key string, bytes int64, conversions int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.client(key)
+	c.Bytes += bytes
+	c.Conversions += conversions
+}
+
+// client returns the state of key, resetting its quotas on a new day. Callers hold l.mu.
+func (l *Limiter) client(key string) *client {
+	now := l.now()
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{Tokens: float64(l.cfg.Burst), Updated: now}
+		l.clients[key] = c
+	}
+	if today := now.UTC().Format(time.DateOnly); c.Day != today {
+		c.Day, c.Bytes, c.Conversions = today, 0, 0
+	}
+	return c
+}
+
+func (l *Limiter) untilFull(remaining int) time.Duration {
+	return time.Duration(float64(l.cfg.Burst-remaining) / l.cfg.Rate * float64(time.Second))
+}
+
+func (l *Limiter) untilTomorrow() time.Duration {
+	now := l.now().UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+// maintain drops clients that are back to a full bucket and unused quotas and saves the state every minute.
+func (l *Limiter) maintain() {
+	defer close(l.done)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.prune()
+			if err := l.save(); err != nil {
+				l.logger.Error("could not save rate limit state", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (l *Limiter) prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	today := now.UTC().Format(time.DateOnly)
+	for key, c := range l.clients {
+		full := l.cfg.Rate <= 0 || c.Tokens+now.Sub(c.Updated).Seconds()*l.cfg.Rate >= float64(l.cfg.Burst)
+		if full && (c.Day != today || (c.Bytes == 0 && c.Conversions == 0)) {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// Close stops the background work and saves the state.
+func (l *Limiter) Close() error {
+	close(l.stop)
+	<-l.done
+	return l.save()
+}
+
+func (l *Limiter) load() error {
+	data, err := os.ReadFile(l.cfg.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &l.clients)
+}
+
+// save writes the state to a temporary file first, so a crash never leaves a truncated one behind.
+func (l *Limiter) save() error {
+	if l.cfg.StateFile == "" {
+		return nil
+	}
+	l.mu.Lock()
+	data, err := json.Marshal(l.clients)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := l.cfg.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.cfg.StateFile)
+}
+
+// clientKey identifies the caller by principal, set by the auth middleware, or by IP address.
+func clientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return p.Method + ":" + p.Subject
+	}
+	return "ip:" + ipKey(r)
+}
+
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status, s.wroteHeader = code, true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
+// countingReader counts the bytes read through it and fails with the error of exceeded once more than
+// limit bytes have been read, unless limit is negative.
+type countingReader struct {
+	io.ReadCloser
+	n        int64
+	limit    int64
+	exceeded func() error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if c.limit < 0 {
+		n, err := c.ReadCloser.Read(p)
+		c.n += int64(n)
+		return n, err
+	}
+	if c.n > c.limit {
+		return 0, c.exceeded()
+	}
+	// Read one byte more than allowed to tell a body of exactly limit bytes from a longer one
+	if left := c.limit - c.n + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	if c.n > c.limit {
+		return n - int(c.n-c.limit), c.exceeded()
+	}
+	return n, err
+}
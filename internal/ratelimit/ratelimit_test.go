@@ -0,0 +1,250 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+)
+
+// step is one request of a test: its method, body and the status the handler answers with, sent after
+// the clock moved on by wait. A chunked body doesn't declare its length.
+type step struct {
+	wait       time.Duration
+	method     string
+	body       string
+	chunked    bool
+	status     int
+	wantStatus int
+}
+
+func newLimiter(t *testing.T, cfg Config, now *time.Time) *Limiter {
+	t.Helper()
+	l, err := New(cfg, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return *now }
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func run(t *testing.T, handler func(http.Handler) http.Handler, now *time.Time, steps []step) {
+	t.Helper()
+	for i, s := range steps {
+		*now = now.Add(s.wait)
+		h := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := io.Copy(io.Discard, r.Body); err != nil {
+				problem.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(s.status)
+		}))
+		method := s.method
+		if method == "" {
+			method = http.MethodPost
+		}
+		r := httptest.NewRequest(method, "/fit", strings.NewReader(s.body))
+		if s.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != s.wantStatus {
+			t.Fatalf("request %d: status = %d, want %d", i, w.Code, s.wantStatus)
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: no Retry-After", i)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		steps []step
+	}{
+		{
+			name: "off",
+			cfg:  Config{},
+			steps: []step{
+				{status: 200, wantStatus: 200},
+				{status: 200, wantStatus: 200},
+			},
+		},
+		{
+			name: "burst then refill",
+			cfg:  Config{Rate: 1, Burst: 2},
+			steps: []step{
+				{status: 200, wantStatus: 200},
+				{status: 200, wantStatus: 200},
+				{status: 200, wantStatus: 429},
+				{wait: time.Second, status: 200, wantStatus: 200},
+				{status: 200, wantStatus: 429},
+			},
+		},
+		{
+			name: "burst defaults to the rate",
+			cfg:  Config{Rate: 0.5},
+			steps: []step{
+				{status: 200, wantStatus: 200},
+				{wait: time.Second, status: 200, wantStatus: 429},
+				{wait: time.Second, status: 200, wantStatus: 200},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			l := newLimiter(t, tt.cfg, &now)
+			run(t, l.Limit(), &now, tt.steps)
+		})
+	}
+}
+
+func TestLimitFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		steps []step
+	}{
+		{
+			name: "valid credentials don't count",
+			cfg:  Config{Rate: 1, Burst: 1},
+			steps: []step{
+				{status: 200, wantStatus: 200},
+				{status: 200, wantStatus: 200},
+				{status: 403, wantStatus: 403},
+			},
+		},
+		{
+			name: "failures are limited",
+			cfg:  Config{Rate: 1, Burst: 2},
+			steps: []step{
+				{status: 401, wantStatus: 401},
+				{status: 401, wantStatus: 401},
+				{status: 200, wantStatus: 429},
+				{wait: time.Second, status: 200, wantStatus: 200},
+			},
+		},
+		{
+			name: "off",
+			cfg:  Config{},
+			steps: []step{
+				{status: 401, wantStatus: 401},
+				{status: 401, wantStatus: 401},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			l := newLimiter(t, tt.cfg, &now)
+			run(t, l.LimitFailures(), &now, tt.steps)
+		})
+	}
+}
+
+func TestQuota(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		upload bool // UploadQuota instead of Quota
+		steps  []step
+	}{
+		{
+			name: "conversions",
+			cfg:  Config{DailyConversions: 2},
+			steps: []step{
+				{status: 200, wantStatus: 200},
+				{status: 422, wantStatus: 422}, // failures don't count
+				{status: 200, wantStatus: 200},
+				{status: 200, wantStatus: 429},
+				{method: http.MethodGet, status: 200, wantStatus: 200},
+			},
+		},
+		{
+			name: "bytes",
+			cfg:  Config{DailyBytes: 10},
+			steps: []step{
+				{body: "123456", status: 200, wantStatus: 200},
+				{body: "123456", status: 200, wantStatus: 429}, // Content-Length is over what's left
+				{body: "1234", status: 200, wantStatus: 200},
+				{body: "1", status: 200, wantStatus: 429},
+			},
+		},
+		{
+			name: "bytes of bodies without a length",
+			cfg:  Config{DailyBytes: 10},
+			steps: []step{
+				{body: "123456", chunked: true, status: 200, wantStatus: 200},
+				{body: "123456", chunked: true, status: 200, wantStatus: 429}, // cut off after 4 bytes
+				{body: "1", status: 200, wantStatus: 429},
+			},
+		},
+		{
+			name: "a body of exactly the bytes left",
+			cfg:  Config{DailyBytes: 10},
+			steps: []step{
+				{body: "123456", status: 200, wantStatus: 200},
+				{body: "1234", chunked: true, status: 200, wantStatus: 200},
+				{body: "1", chunked: true, status: 200, wantStatus: 429},
+			},
+		},
+		{
+			name:   "uploads count bytes only",
+			cfg:    Config{DailyBytes: 10, DailyConversions: 1},
+			upload: true,
+			steps: []step{
+				{body: "1234", status: 201, wantStatus: 201},
+				{body: "1234", status: 201, wantStatus: 201},
+				{body: "1234", chunked: true, status: 201, wantStatus: 429},
+			},
+		},
+		{
+			name: "reset at midnight UTC",
+			cfg:  Config{DailyConversions: 1},
+			steps: []step{
+				{status: 200, wantStatus: 200},
+				{wait: 11 * time.Hour, status: 200, wantStatus: 429},
+				{wait: time.Hour, status: 200, wantStatus: 200},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			l := newLimiter(t, tt.cfg, &now)
+			quota := l.Quota()
+			if tt.upload {
+				quota = l.UploadQuota()
+			}
+			run(t, quota, &now, tt.steps)
+		})
+	}
+}
+
+func TestStateFile(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cfg := Config{DailyConversions: 1, StateFile: filepath.Join(t.TempDir(), "state.json")}
+
+	l, err := New(cfg, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return now }
+	run(t, l.Quota(), &now, []step{{status: 200, wantStatus: 200}})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l = newLimiter(t, cfg, &now)
+	run(t, l.Quota(), &now, []step{{status: 200, wantStatus: 429}})
+}
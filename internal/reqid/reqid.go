@@ -0,0 +1,45 @@
+// Package reqid carries the ID of a request through its context, so that logs and error responses can
+// refer to it.
+package reqid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header is the request and response header holding the ID.
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// New returns a random ID.
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an ID sent by a client can be used as is: at most 128 printable ASCII characters,
+// so it can't inject anything into logs or headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the ID of the request, or "" outside of one.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
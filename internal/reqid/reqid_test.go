@@ -0,0 +1,52 @@
+package reqid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "generated", id: New(), want: true},
+		{name: "uuid", id: "0f8fad5b-d9cb-469f-a165-70867728950e", want: true},
+		{name: "printable punctuation", id: "!trace~42/span=1", want: true},
+		{name: "128 characters", id: strings.Repeat("a", 128), want: true},
+		{name: "empty"},
+		{name: "129 characters", id: strings.Repeat("a", 129)},
+		{name: "space", id: "a b"},
+		{name: "newline", id: "abc\nlevel=ERROR"},
+		{name: "control character", id: "abc\x1b[31m"},
+		{name: "non-ASCII", id: "réquest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Valid(tt.id); got != tt.want {
+				t.Errorf("Valid(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	a, b := New(), New()
+	if len(a) != 32 || strings.Trim(a, "0123456789abcdef") != "" {
+		t.Errorf("New() = %q, want 32 hex digits", a)
+	}
+	if a == b {
+		t.Errorf("New() returned %q twice", a)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != "" {
+		t.Errorf("FromContext() = %q outside of a request", got)
+	}
+	if got := FromContext(NewContext(context.Background(), "abc")); got != "abc" {
+		t.Errorf("FromContext() = %q, want abc", got)
+	}
+}
@@ -0,0 +1,121 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/auth"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/store"
+)
+
+func (h *Handler) listHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	var ok bool
+	if filter.AthleteID, ok = auth.Athlete(r.Context(), filter.AthleteID); !ok {
+		problem.Write(w, r, http.StatusForbidden, problem.CodeInsufficientScope, "only admins may list activities of other athletes")
+		return
+	}
+
+	activities, err := h.repo.List(r.Context(), filter)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, activities)
+}
+
+func (h *Handler) getHandler(w http.ResponseWriter, r *http.Request) {
+	h.serveFile(w, r, h.repo.OpenJSON, "application/json", "")
+}
+
+func (h *Handler) getFITHandler(w http.ResponseWriter, r *http.Request) {
+	h.serveFile(w, r, h.repo.OpenFIT, "application/vnd.ant.fit", ".fit")
+}
+
+// serveFile answers with a file of the activity, as a download named after its ID when ext is set.
+func (h *Handler) serveFile(w http.ResponseWriter, r *http.Request, open func(ctx context.Context, id string) (io.ReadCloser, error), mimeType string, ext string) {
+	id := r.PathValue("id")
+	rc, err := h.openOwn(r.Context(), id, open)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, err.Error())
+		return
+	case err != nil:
+		problem.WriteError(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", mimeType)
+	if ext != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": id + ext}))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+// openOwn opens a file of the activity id. Activities of other athletes are not found rather than forbidden.
+func (h *Handler) openOwn(ctx context.Context, id string, open func(ctx context.Context, id string) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	a, err := h.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := auth.Athlete(ctx, a.AthleteID); !ok {
+		return nil, store.ErrNotFound
+	}
+	return open(ctx, id)
+}
+
+// parseFilter reads from, to (RFC 3339 or YYYY-MM-DD, to is exclusive), sport, athlete_id, min_distance and max_distance (metres).
+func parseFilter(r *http.Request) (store.Filter, error) {
+	q := r.URL.Query()
+	filter := store.Filter{
+		AthleteID: q.Get("athlete_id"),
+		Sport:     q.Get("sport"),
+	}
+
+	var err error
+	if filter.From, err = parseTime(q.Get("from")); err != nil {
+		return filter, fmt.Errorf("from: %w", err)
+	}
+	if filter.To, err = parseTime(q.Get("to")); err != nil {
+		return filter, fmt.Errorf("to: %w", err)
+	}
+	if filter.MinDistance, err = parseFloat(q.Get("min_distance")); err != nil {
+		return filter, fmt.Errorf("min_distance: %w", err)
+	}
+	if filter.MaxDistance, err = parseFloat(q.Get("max_distance")); err != nil {
+		return filter, fmt.Errorf("max_distance: %w", err)
+	}
+	return filter, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func parseFloat(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
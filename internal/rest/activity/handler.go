@@ -0,0 +1,66 @@
+package activity
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/kyzrfranz/go-fitter/internal/logging"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/store"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+)
+
+type Handler struct {
+	logger  *slog.Logger
+	repo    store.Repository
+	privacy privacy.Policy // applied to every stored activity
+}
+
+func NewHandler(logger *slog.Logger, repo store.Repository, policy privacy.Policy) *Handler {
+	return &Handler{
+		logger:  logger,
+		repo:    repo,
+		privacy: policy,
+	}
+}
+
+// log returns the logger of the request in ctx.
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
+
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		h.listHandler(w, r)
+	case "POST":
+		h.postHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleItem(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		h.getHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleFIT(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		h.getFITHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
@@ -0,0 +1,111 @@
+package activity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/auth"
+	"github.com/kyzrfranz/go-fitter/internal/metrics"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
+	"github.com/kyzrfranz/go-fitter/internal/store"
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+	"github.com/muktihari/fit/decoder"
+)
+
+const defaultAthleteID = "default"
+
+func (h *Handler) postHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := upload.FIT(r)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	athleteID, ok := auth.Athlete(r.Context(), r.FormValue("athlete_id"))
+	if !ok {
+		problem.Write(w, r, http.StatusForbidden, problem.CodeInsufficientScope, "only admins may store activities of other athletes")
+		return
+	}
+	if athleteID == "" {
+		athleteID = defaultAthleteID
+	}
+	data := f.Data
+
+	opts, done := metrics.Decode("activity")
+	act, err := activity.Decode(bytes.NewReader(data), append(opts, decoder.WithIgnoreChecksum())...)
+	done(err)
+	if err != nil {
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeDecodeFailed, err.Error())
+		return
+	}
+
+	// Duplicates are found by the upload, what is stored is hidden like every other output
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	stored, err := h.redact(data)
+	if err != nil {
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeDecodeFailed, err.Error())
+		return
+	}
+
+	opts, done = metrics.Decode("json")
+	msg, err := converters.FitToJsonContext(r.Context(), bytes.NewReader(stored),
+		append(opts, decoder.WithIgnoreChecksum()),
+		cJson.WithPrettyPrint(false), cJson.WithLogger(h.log(r.Context())))
+	done(err)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+
+	saved, err := h.repo.Save(r.Context(), store.Activity{
+		ID:           activityID(athleteID, hash),
+		AthleteID:    athleteID,
+		FileName:     f.Name,
+		ContentHash:  hash,
+		SerialNumber: act.FileId.SerialNumber,
+		TimeCreated:  act.FileId.TimeCreated,
+		UploadedAt:   time.Now().UTC(),
+		Summary:      activity.Summarize(act),
+	}, stored, []byte(msg))
+
+	switch {
+	case errors.Is(err, store.ErrDuplicate):
+		writeJSON(w, http.StatusConflict, saved)
+	case err != nil:
+		h.log(r.Context()).Log(r.Context(), slog.LevelError, "save activity failed", slog.String("error", err.Error()))
+		problem.WriteError(w, r, err)
+	default:
+		w.Header().Set("Location", "/activities/"+saved.ID)
+		writeJSON(w, http.StatusCreated, saved)
+	}
+}
+
+// activityID is unique per athlete and file, two athletes uploading the same file get activities of their own.
+func activityID(athleteID string, contentHash string) string {
+	sum := sha256.Sum256([]byte(athleteID + "\x00" + contentHash))
+	return hex.EncodeToString(sum[:8])
+}
+
+// redact applies the server's privacy policy to the FIT file in data.
+func (h *Handler) redact(data []byte) ([]byte, error) {
+	if h.privacy.Empty() {
+		return data, nil
+	}
+	return privacy.Redact(bytes.NewReader(data), h.privacy, decoder.WithIgnoreChecksum())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
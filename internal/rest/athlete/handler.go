@@ -0,0 +1,45 @@
+package athlete
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/kyzrfranz/go-fitter/internal/logging"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/training"
+)
+
+type Handler struct {
+	logger     *slog.Logger
+	training   *training.Service
+	thresholds training.Thresholds
+	profiles   map[string]training.Thresholds
+}
+
+// NewHandler creates the athlete handler. thresholds are used whenever a request does not override them,
+// profiles are named sets of thresholds a request can pick instead.
+func NewHandler(logger *slog.Logger, service *training.Service, thresholds training.Thresholds, profiles map[string]training.Thresholds) *Handler {
+	return &Handler{
+		logger:     logger,
+		training:   service,
+		thresholds: thresholds,
+		profiles:   profiles,
+	}
+}
+
+// log returns the logger of the request in ctx.
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
+
+func (h *Handler) HandleLoad(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		h.loadHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
@@ -0,0 +1,119 @@
+package athlete
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/auth"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/training"
+)
+
+const (
+	// defaultRange is the number of days reported when the request has no from parameter.
+	defaultRange = 90
+	// maxRange is the most days a report may span.
+	maxRange = 366
+)
+
+func (h *Handler) loadHandler(w http.ResponseWriter, r *http.Request) {
+	athleteID, ok := auth.Athlete(r.Context(), r.PathValue("id"))
+	if !ok {
+		problem.Write(w, r, http.StatusForbidden, problem.CodeInsufficientScope, "only admins may read the load of other athletes")
+		return
+	}
+
+	from, to, opts, err := h.parseLoadQuery(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	report, err := h.training.Load(r.Context(), athleteID, from, to, opts)
+	if err != nil {
+		h.log(r.Context()).Log(r.Context(), slog.LevelError, "training load failed", slog.String("error", err.Error()))
+		problem.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// parseLoadQuery reads from and to (YYYY-MM-DD, at most maxRange days apart), a threshold profile, the thresholds ftp, lthr, max_hr
+// and rest_hr, which override the profile, and the time constants ctl_days and atl_days.
+func (h *Handler) parseLoadQuery(r *http.Request) (time.Time, time.Time, training.Options, error) {
+	q := r.URL.Query()
+	opts := training.Options{Thresholds: h.thresholds}
+
+	to := time.Now().UTC()
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, opts, fmt.Errorf("to: %w", err)
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -defaultRange)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, opts, fmt.Errorf("from: %w", err)
+		}
+		from = t
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, opts, fmt.Errorf("from must not be after to")
+	}
+	if from.AddDate(0, 0, maxRange).Before(to) {
+		return time.Time{}, time.Time{}, opts, fmt.Errorf("from and to must be at most %d days apart", maxRange)
+	}
+
+	if name := q.Get("profile"); name != "" {
+		th, ok := h.profiles[name]
+		if !ok {
+			return time.Time{}, time.Time{}, opts, fmt.Errorf("profile: unknown profile %q", name)
+		}
+		opts.Thresholds = th
+	}
+
+	floats := map[string]*float64{
+		"ftp":     &opts.Thresholds.FTP,
+		"lthr":    &opts.Thresholds.LTHR,
+		"max_hr":  &opts.Thresholds.MaxHR,
+		"rest_hr": &opts.Thresholds.RestHR,
+	}
+	for key, dst := range floats {
+		if v := q.Get(key); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return time.Time{}, time.Time{}, opts, fmt.Errorf("%s: %w", key, err)
+			}
+			*dst = f
+		}
+	}
+
+	ints := map[string]*int{
+		"ctl_days": &opts.ChronicDays,
+		"atl_days": &opts.AcuteDays,
+	}
+	for key, dst := range ints {
+		if v := q.Get(key); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return time.Time{}, time.Time{}, opts, fmt.Errorf("%s: %w", key, err)
+			}
+			if i < 1 || i > training.MaxTimeConstant {
+				return time.Time{}, time.Time{}, opts, fmt.Errorf("%s must be between 1 and %d", key, training.MaxTimeConstant)
+			}
+			*dst = i
+		}
+	}
+
+	return from, to, opts, nil
+}
@@ -0,0 +1,177 @@
+package fit
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kyzrfranz/go-fitter/internal/metrics"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
+	"github.com/kyzrfranz/go-fitter/pkg/archive"
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+	"github.com/muktihari/fit/decoder"
+)
+
+const (
+	batchStatusOK    = "ok"
+	batchStatusError = "error"
+
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeZip    = "application/zip"
+)
+
+// batchResult is one line of the NDJSON response, or one entry of the zip manifest.
+type batchResult struct {
+	Index  int             `json:"index"`
+	Name   string          `json:"name"`
+	Status string          `json:"status"`
+	Code   problem.Code    `json:"code,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+func (h *Handler) batchHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := upload.Files(r)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	if len(entries) == 0 {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeMissingFile, "no FIT files found in request")
+		return
+	}
+
+	policy, err := h.privacyPolicy(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+	jsonOpts, decoderOptions, err := h.jsonOptions(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+	// Every result is a single line of NDJSON
+	jsonOpts = append(jsonOpts, cJson.WithPrettyPrint(false))
+
+	results := h.convertBatch(r, entries, policy, jsonOpts, decoderOptions)
+
+	if wantsZip(r) {
+		h.writeBatchZip(w, r, results)
+		return
+	}
+	h.writeBatchNDJSON(w, r, results)
+}
+
+// convertBatch converts all entries on the shared worker pool and delivers the results in completion order.
+// An entry waits for a free slot before its goroutine starts, so a large archive doesn't spawn one per entry.
+func (h *Handler) convertBatch(r *http.Request, entries []archive.Entry, policy privacy.Policy, jsonOpts []cJson.Option, decoderOptions []decoder.Option) <-chan batchResult {
+	results := make(chan batchResult)
+
+	go func() {
+		var wg sync.WaitGroup
+		for i, entry := range entries {
+			res := batchResult{Index: i, Name: entry.Name}
+			if err := h.pool.Acquire(r.Context()); err != nil {
+				res.Status, res.Code, res.Error = batchStatusError, problem.CodeUnavailable, err.Error()
+				results <- res
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var msg string
+				var ff io.Reader
+				err := upload.CheckFIT(entry.Data)
+				if err == nil {
+					ff, err = redact(bytes.NewReader(entry.Data), policy, decoderOptions)
+				}
+				if err == nil {
+					opts, done := metrics.Decode("json")
+					msg, err = converters.FitToJsonContext(r.Context(), ff, append(slices.Clip(decoderOptions), opts...), slices.Clip(jsonOpts)...)
+					done(err)
+				}
+				h.pool.Release()
+
+				if err != nil {
+					_, code := problem.From(err)
+					res.Status, res.Code, res.Error = batchStatusError, code, err.Error()
+				} else {
+					res.Status, res.Result = batchStatusOK, json.RawMessage(msg)
+				}
+				results <- res
+			}()
+		}
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+func (h *Handler) writeBatchNDJSON(w http.ResponseWriter, r *http.Request, results <-chan batchResult) {
+	w.Header().Set("Content-Type", contentTypeNDJSON)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	for res := range results {
+		if err := enc.Encode(res); err != nil {
+			h.log(r.Context()).Log(r.Context(), slog.LevelWarn, "batch write failed", slog.String("error", err.Error()))
+			continue
+		}
+		_ = rc.Flush()
+	}
+}
+
+func (h *Handler) writeBatchZip(w http.ResponseWriter, r *http.Request, results <-chan batchResult) {
+	w.Header().Set("Content-Type", contentTypeZip)
+	w.Header().Set("Content-Disposition", `attachment; filename="results.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	var manifest []batchResult
+	for res := range results {
+		if res.Status == batchStatusOK {
+			name := fmt.Sprintf("%03d_%s.json", res.Index, strings.TrimSuffix(path.Base(res.Name), path.Ext(res.Name)))
+			f, err := zw.Create(name)
+			if err == nil {
+				_, err = f.Write(res.Result)
+			}
+			if err != nil {
+				h.log(r.Context()).Log(r.Context(), slog.LevelWarn, "batch zip write failed", slog.String("error", err.Error()))
+			}
+			res.Result = nil
+		}
+		manifest = append(manifest, res)
+	}
+
+	f, err := zw.Create("manifest.json")
+	if err == nil {
+		err = json.NewEncoder(f).Encode(manifest)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		h.log(r.Context()).Log(r.Context(), slog.LevelWarn, "batch zip write failed", slog.String("error", err.Error()))
+	}
+}
+
+func wantsZip(r *http.Request) bool {
+	if r.URL.Query().Get("output") == "zip" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), contentTypeZip)
+}
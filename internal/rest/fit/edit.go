@@ -0,0 +1,238 @@
+package fit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/metrics"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
+	"github.com/kyzrfranz/go-fitter/pkg/edit"
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/filedef"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// trimHandler cuts the uploaded activity to ?from= and ?to= (RFC 3339 times or durations from the start)
+// and ?from_distance= and ?to_distance= (metres).
+func (h *Handler) trimHandler(w http.ResponseWriter, r *http.Request) {
+	act, ok := h.decodeUpload(w, r)
+	if !ok {
+		return
+	}
+	defer h.pool.Release()
+
+	var (
+		rng   edit.Range
+		err   error
+		start = activity.Summarize(act).StartTime
+		query = r.URL.Query()
+	)
+	for name, t := range map[string]*time.Time{"from": &rng.From, "to": &rng.To} {
+		if v := query.Get(name); v != "" {
+			if *t, err = edit.ParseTime(v, start); err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, name+": "+err.Error())
+				return
+			}
+		}
+	}
+	for name, d := range map[string]*float64{"from_distance": &rng.FromDistance, "to_distance": &rng.ToDistance} {
+		if v := query.Get(name); v != "" {
+			if *d, err = strconv.ParseFloat(v, 64); err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, name+": "+err.Error())
+				return
+			}
+		}
+	}
+
+	switch {
+	case !rng.To.IsZero() && rng.From.After(rng.To):
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "from must not be after to")
+		return
+	case rng.ToDistance > 0 && rng.FromDistance > rng.ToDistance:
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "from_distance must not be greater than to_distance")
+		return
+	}
+
+	trimmed, err := edit.Trim(act, rng)
+	if err != nil {
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeUnprocessable, err.Error())
+		return
+	}
+	h.writeEdited(w, r, []*filedef.Activity{trimmed}, false)
+}
+
+// splitHandler cuts the uploaded activity at every ?at= (RFC 3339 times or durations from the start,
+// repeated or comma separated).
+func (h *Handler) splitHandler(w http.ResponseWriter, r *http.Request) {
+	act, ok := h.decodeUpload(w, r)
+	if !ok {
+		return
+	}
+	defer h.pool.Release()
+
+	start := activity.Summarize(act).StartTime
+	var at []time.Time
+	for _, v := range r.URL.Query()["at"] {
+		for _, s := range strings.Split(v, ",") {
+			t, err := edit.ParseTime(s, start)
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "at: "+err.Error())
+				return
+			}
+			at = append(at, t)
+		}
+	}
+	if len(at) == 0 {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "at: at least one split time is required")
+		return
+	}
+
+	parts, err := edit.Split(act, at)
+	if err != nil {
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeUnprocessable, err.Error())
+		return
+	}
+	h.writeEdited(w, r, parts, true)
+}
+
+// decodeUpload decodes the uploaded activity on the worker pool. When it succeeds, the caller holds a slot
+// of the pool until it releases it.
+func (h *Handler) decodeUpload(w http.ResponseWriter, r *http.Request) (*filedef.Activity, bool) {
+	settings, err := h.settings(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return nil, false
+	}
+	f, err := upload.FIT(r)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return nil, false
+	}
+
+	if err := h.pool.Acquire(r.Context()); err != nil {
+		problem.Write(w, r, http.StatusServiceUnavailable, problem.CodeUnavailable, err.Error())
+		return nil, false
+	}
+	act, err := decodeActivity(r.Context(), f.Data, decoderOptions(settings))
+	if err != nil {
+		h.pool.Release()
+		problem.WriteError(w, r, err)
+		return nil, false
+	}
+	return act, true
+}
+
+// decodeActivity decodes data, failing with a decode_failed problem.
+func decodeActivity(ctx context.Context, data []byte, decoderOptions []decoder.Option) (*filedef.Activity, error) {
+	_, span := tracer.Start(ctx, "fit.decode", trace.WithAttributes(attribute.Int("fit.size", len(data))))
+	defer span.End()
+
+	opts, done := metrics.Decode("activity")
+	act, err := activity.Decode(bytes.NewReader(data), append(opts, decoderOptions...)...)
+	done(err)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, problem.Wrap(http.StatusUnprocessableEntity, problem.CodeDecodeFailed, err)
+	}
+	return act, nil
+}
+
+// writeEdited answers with the FIT files (?output=fit, a zip for several parts) or with their JSON
+// conversions, with the settings of /fit, both with the privacy policy of the request applied. Several
+// parts are a JSON array. The caller holds a slot of the worker pool.
+func (h *Handler) writeEdited(w http.ResponseWriter, r *http.Request, acts []*filedef.Activity, many bool) {
+	policy, err := h.privacyPolicy(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+	jsonOpts, decoderOptions, err := h.jsonOptions(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	files := make([][]byte, len(acts))
+	for i, act := range acts {
+		data, err := edit.Encode(act)
+		if err == nil {
+			data, err = redactFIT(data, policy)
+		}
+		if err != nil {
+			problem.WriteError(w, r, err)
+			return
+		}
+		files[i] = data
+	}
+
+	h.log(r.Context()).Log(r.Context(), slog.LevelDebug, "edited", slog.Int("parts", len(files)))
+
+	if wantsFIT(r) {
+		if !many {
+			w.Header().Set("Content-Type", contentTypeFIT)
+			w.Header().Set("Content-Disposition", `attachment; filename="activity.fit"`)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(files[0])
+			return
+		}
+		writeFITZip(w, r, files)
+		return
+	}
+
+	jsonOpts = append(jsonOpts, cJson.WithPrettyPrint(false))
+	results := make([]json.RawMessage, len(files))
+	for i, data := range files {
+		msg, err := converters.FitToJsonContext(r.Context(), bytes.NewReader(data), decoderOptions, jsonOpts...)
+		if err != nil {
+			problem.WriteError(w, r, err)
+			return
+		}
+		results[i] = json.RawMessage(msg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if many {
+		_ = json.NewEncoder(w).Encode(results)
+		return
+	}
+	_, _ = w.Write(results[0])
+}
+
+func writeFITZip(w http.ResponseWriter, r *http.Request, files [][]byte) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, data := range files {
+		f, err := zw.Create(fmt.Sprintf("part-%02d.fit", i+1))
+		if err == nil {
+			_, err = f.Write(data)
+		}
+		if err != nil {
+			problem.WriteError(w, r, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeZip)
+	w.Header().Set("Content-Disposition", `attachment; filename="parts.zip"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
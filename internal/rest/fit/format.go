@@ -0,0 +1,37 @@
+package fit
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kyzrfranz/go-fitter/internal/negotiate"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+)
+
+// outputFormat is the format a conversion answers with: ?format= when given, otherwise the one the Accept
+// header prefers, JSON for clients that take anything.
+func outputFormat(r *http.Request) (converters.Format, error) {
+	if v := r.URL.Query().Get("format"); v != "" {
+		format, err := converters.ParseFormat(v)
+		if err != nil {
+			return "", problem.Wrap(http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("format: %w", err))
+		}
+		return format, nil
+	}
+
+	formats := converters.Formats()
+	offers := make([]string, len(formats))
+	for i, f := range formats {
+		offers[i] = f.MediaType()
+	}
+	accept := r.Header.Get("Accept")
+	mediaType := negotiate.ContentType(accept, offers...)
+	for _, f := range formats {
+		if f.MediaType() == mediaType {
+			return f, nil
+		}
+	}
+	return "", problem.Wrap(http.StatusNotAcceptable, problem.CodeNotAcceptable,
+		fmt.Errorf("none of the formats is acceptable for %q, use ?format= or one of %v", accept, offers))
+}
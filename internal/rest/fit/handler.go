@@ -1,27 +1,121 @@
 package fit
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+
+	"github.com/kyzrfranz/go-fitter/internal/logging"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/worker"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/kyzrfranz/go-fitter/internal/rest/fit")
+
+// Defaults are the conversion settings of requests that don't choose them.
+type Defaults struct {
+	Records        bool           // include records in the JSON
+	Degrees        bool           // positions in degrees instead of semicircles
+	VerifyChecksum bool           // reject files with a bad CRC
+	Privacy        privacy.Policy // requests can only add to it
+}
+
 type Handler struct {
-	logger *slog.Logger
+	logger   *slog.Logger
+	pool     *worker.Pool
+	defaults Defaults
+	privacy  privacy.Policy
 }
 
-func NewHandler(logger *slog.Logger) *Handler {
+func NewHandler(logger *slog.Logger, pool *worker.Pool, defaults Defaults) *Handler {
 	return &Handler{
-		logger: logger,
+		logger:   logger,
+		pool:     pool,
+		defaults: defaults,
+		privacy:  defaults.Privacy,
 	}
 }
 
+// log returns the logger of the request in ctx.
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
+
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "POST":
 		h.postHandler(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleBatch(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+		h.batchHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleValidate(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+		h.validateHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleRepair(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+		h.repairHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleTrim(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+		h.trimHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleSplit(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+		h.splitHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
+	}
+
+}
+
+func (h *Handler) HandleMerge(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+		h.mergeHandler(w, r)
+	default:
+		problem.MethodNotAllowed(w, r)
 	}
 
 }
@@ -0,0 +1,82 @@
+package fit
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
+	"github.com/kyzrfranz/go-fitter/pkg/edit"
+	"github.com/muktihari/fit/profile/filedef"
+)
+
+// mergeHandler merges all uploaded files, in the order they were sent, into one activity.
+// ?mode= is concat or interleave, ?prefer=field:i,j (repeatable) sets the precedence of interleaved fields.
+func (h *Handler) mergeHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := edit.MergeOptions{Precedence: make(map[string][]int)}
+
+	var err error
+	if opts.Mode, err = edit.ParseMergeMode(query.Get("mode")); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+	for _, v := range query["prefer"] {
+		field, order, err := edit.ParsePrecedence(v)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+			return
+		}
+		opts.Precedence[field] = order
+	}
+
+	settings, err := h.settings(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	entries, err := upload.Files(r)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+	for _, field := range slices.Sorted(maps.Keys(opts.Precedence)) {
+		for _, i := range opts.Precedence[field] {
+			if i >= len(entries) {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter,
+					fmt.Sprintf("prefer: %s refers to file %d, only %d were uploaded", field, i, len(entries)))
+				return
+			}
+		}
+	}
+
+	if err := h.pool.Acquire(r.Context()); err != nil {
+		problem.Write(w, r, http.StatusServiceUnavailable, problem.CodeUnavailable, err.Error())
+		return
+	}
+	defer h.pool.Release()
+
+	acts := make([]*filedef.Activity, 0, len(entries))
+	for _, entry := range entries {
+		err := upload.CheckFIT(entry.Data)
+		var act *filedef.Activity
+		if err == nil {
+			act, err = decodeActivity(r.Context(), entry.Data, decoderOptions(settings))
+		}
+		if err != nil {
+			problem.WriteError(w, r, fmt.Errorf("%s: %w", entry.Name, err))
+			return
+		}
+		acts = append(acts, act)
+	}
+
+	merged, err := edit.Merge(acts, opts)
+	if err != nil {
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeUnprocessable, err.Error())
+		return
+	}
+	h.writeEdited(w, r, []*filedef.Activity{merged}, false)
+}
@@ -0,0 +1,69 @@
+package fit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
+	"github.com/muktihari/fit/decoder"
+)
+
+// settings are the server defaults with the request's ?records=, ?degrees= and ?verify_checksum= on top.
+func (h *Handler) settings(r *http.Request) (Defaults, error) {
+	settings := h.defaults
+	query := r.URL.Query()
+	for name, dst := range map[string]*bool{
+		"records":         &settings.Records,
+		"degrees":         &settings.Degrees,
+		"verify_checksum": &settings.VerifyChecksum,
+	} {
+		if v := query.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return settings, fmt.Errorf("%s: %q is not a boolean", name, v)
+			}
+			*dst = b
+		}
+	}
+	return settings, nil
+}
+
+// jsonOptions are the JSON converter and decoder options of the request's settings.
+func (h *Handler) jsonOptions(r *http.Request) ([]cJson.Option, []decoder.Option, error) {
+	settings, err := h.settings(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	jsonOpts := []cJson.Option{cJson.WithLogger(h.log(r.Context()))}
+	if !settings.Records {
+		jsonOpts = append(jsonOpts, cJson.WithNoRecords())
+	}
+	if settings.Degrees {
+		jsonOpts = append(jsonOpts, cJson.WithPrintGPSPositionInDegrees())
+	}
+	return jsonOpts, decoderOptions(settings), nil
+}
+
+// convertOptions are the options of converters.Convert for the request's settings, for any format.
+func (h *Handler) convertOptions(r *http.Request) (converters.Options, []decoder.Option, error) {
+	settings, err := h.settings(r)
+	if err != nil {
+		return converters.Options{}, nil, err
+	}
+	opts := converters.Options{
+		Records: settings.Records,
+		Degrees: settings.Degrees,
+		Pretty:  true,
+		Logger:  h.log(r.Context()),
+	}
+	return opts, decoderOptions(settings), nil
+}
+
+func decoderOptions(settings Defaults) []decoder.Option {
+	if settings.VerifyChecksum {
+		return nil
+	}
+	return []decoder.Option{decoder.WithIgnoreChecksum()}
+}
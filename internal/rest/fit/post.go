@@ -1,46 +1,66 @@
 package fit
 
 import (
-	"io"
+	"bytes"
 	"log/slog"
 	"net/http"
+	"path/filepath"
+	"strings"
 
+	"github.com/kyzrfranz/go-fitter/internal/metrics"
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
 	"github.com/kyzrfranz/go-fitter/pkg/converters"
-	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
-	"github.com/muktihari/fit/decoder"
 )
 
+// postHandler converts the uploaded file to the format of outputFormat, see settings for what else a
+// request can choose.
 func (h *Handler) postHandler(w http.ResponseWriter, r *http.Request) {
-	file, err := getFile(r)
+	w.Header().Add("Vary", "Accept")
+	format, err := outputFormat(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.WriteError(w, r, err)
+		return
 	}
-	var decoderOptions []decoder.Option
-	decoderOptions = append(decoderOptions, decoder.WithIgnoreChecksum())
 
-	var jsonOpts []cJson.Option
-	jsonOpts = append(jsonOpts, cJson.WithNoRecords())
-
-	msg, err := converters.FitToJson(file, decoderOptions, jsonOpts...)
+	f, err := upload.FIT(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.WriteError(w, r, err)
+		return
 	}
+	convertOpts, decoderOptions, err := h.convertOptions(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+	convertOpts.Name = strings.TrimSuffix(f.Name, filepath.Ext(f.Name))
 
-	h.logger.Log(r.Context(), slog.LevelDebug, "data", msg)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(msg)) // Write the JSON data
-}
-
-func getFile(r *http.Request) (io.Reader, error) {
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		return nil, err
+	policy, err := h.privacyPolicy(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
 	}
 
-	file, _, err := r.FormFile("file")
+	if err := h.pool.Acquire(r.Context()); err != nil {
+		problem.Write(w, r, http.StatusServiceUnavailable, problem.CodeUnavailable, err.Error())
+		return
+	}
+	var msg string
+	ff, err := redact(bytes.NewReader(f.Data), policy, decoderOptions)
+	if err == nil {
+		opts, done := metrics.Decode(string(format))
+		msg, err = converters.ConvertContext(r.Context(), ff, format, append(decoderOptions, opts...), convertOpts)
+		done(err)
+	}
+	h.pool.Release()
 	if err != nil {
-		return nil, err
+		problem.WriteError(w, r, err)
+		return
 	}
-	return file, nil
+
+	h.log(r.Context()).Log(r.Context(), slog.LevelDebug, "converted", slog.String("format", string(format)), slog.String("data", msg))
+
+	w.Header().Set("Content-Type", format.MediaType())
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(msg))
 }
@@ -0,0 +1,74 @@
+package fit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+	"github.com/muktihari/fit/decoder"
+)
+
+// privacyPolicy is the server's policy with the request's ?zone=, ?hide_start=, ?hide_end= and ?privacy_mode=
+// on top. Requests can add zones and hide more, but never less than the server does: a server that removes
+// positions in its zones can't be asked to fuzz them instead, one that fuzzes can be asked to remove them.
+func (h *Handler) privacyPolicy(r *http.Request) (privacy.Policy, error) {
+	policy := h.privacy
+	policy.Zones = append([]privacy.Zone(nil), h.privacy.Zones...)
+
+	query := r.URL.Query()
+	for _, v := range query["zone"] {
+		zones, err := privacy.ParseZones(v)
+		if err != nil {
+			return policy, err
+		}
+		policy.Zones = append(policy.Zones, zones...)
+	}
+	for name, hide := range map[string]*float64{"hide_start": &policy.HideStart, "hide_end": &policy.HideEnd} {
+		if v := query.Get(name); v != "" {
+			metres, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return policy, err
+			}
+			*hide = max(*hide, metres)
+		}
+	}
+	if v := query.Get("privacy_mode"); v != "" {
+		mode, err := privacy.ParseMode(v)
+		if err != nil {
+			return policy, err
+		}
+		if mode == privacy.ModeFuzz && len(h.privacy.Zones) > 0 && h.privacy.Mode != privacy.ModeFuzz {
+			return policy, fmt.Errorf("privacy_mode: the server removes positions in its zones, %s would show more", mode)
+		}
+		policy.Mode = mode
+	}
+	return policy, nil
+}
+
+// redact applies policy to the FIT file in ff, returning ff itself when there is nothing to hide.
+func redact(ff io.Reader, policy privacy.Policy, decoderOptions []decoder.Option) (io.Reader, error) {
+	if policy.Empty() {
+		return ff, nil
+	}
+	redacted, err := privacy.Redact(ff, policy, decoderOptions...)
+	if err != nil {
+		return nil, problem.Wrap(http.StatusUnprocessableEntity, problem.CodeDecodeFailed, err)
+	}
+	return bytes.NewReader(redacted), nil
+}
+
+// redactFIT is redact for a FIT file the handler encoded itself.
+func redactFIT(data []byte, policy privacy.Policy) ([]byte, error) {
+	if policy.Empty() {
+		return data, nil
+	}
+	redacted, err := privacy.Redact(bytes.NewReader(data), policy, decoder.WithIgnoreChecksum())
+	if err != nil {
+		return nil, problem.Wrap(http.StatusUnprocessableEntity, problem.CodeDecodeFailed, err)
+	}
+	return redacted, nil
+}
@@ -0,0 +1,89 @@
+package fit
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
+	"github.com/kyzrfranz/go-fitter/pkg/repair"
+)
+
+const contentTypeFIT = "application/vnd.ant.fit"
+
+// repairResponse is the JSON response of POST /fit/repair, the repaired file is base64 encoded.
+type repairResponse struct {
+	repair.Result
+	FIT []byte `json:"fit"`
+}
+
+// repairHandler returns the repaired FIT file itself when asked for it with ?output=fit or an Accept
+// header of application/vnd.ant.fit, with the number of log entries in X-Repair-Log-Entries. Otherwise it
+// answers with the repair log and the file as JSON.
+func (h *Handler) repairHandler(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.privacyPolicy(r)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	// Not upload.FIT, broken headers are for repair to fix
+	f, err := upload.Unpack(r)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+
+	if err := h.pool.Acquire(r.Context()); err != nil {
+		problem.Write(w, r, http.StatusServiceUnavailable, problem.CodeUnavailable, err.Error())
+		return
+	}
+	res, err := repair.Repair(f.Data)
+	if err == nil {
+		res.FIT, err = redactFIT(res.FIT, policy)
+		res.Size = len(res.FIT)
+	}
+	h.pool.Release()
+
+	if errors.Is(err, repair.ErrNothingToSalvage) {
+		problem.Write(w, r, http.StatusUnprocessableEntity, problem.CodeDecodeFailed, err.Error())
+		return
+	}
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+
+	h.log(r.Context()).Log(r.Context(), slog.LevelDebug, "repaired",
+		slog.String("file", f.Name),
+		slog.Int("messages", res.Messages),
+		slog.Bool("changed", res.Changed))
+
+	if wantsFIT(r) {
+		name := strings.TrimSuffix(filepath.Base(f.Name), filepath.Ext(f.Name)) + ".repaired.fit"
+		w.Header().Set("Content-Type", contentTypeFIT)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		w.Header().Set("Content-Length", strconv.Itoa(len(res.FIT)))
+		w.Header().Set("X-Repair-Log-Entries", strconv.Itoa(len(res.Log)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(res.FIT)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(repairResponse{Result: res, FIT: res.FIT})
+}
+
+func wantsFIT(r *http.Request) bool {
+	if r.URL.Query().Get("output") == "fit" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), contentTypeFIT)
+}
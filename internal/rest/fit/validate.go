@@ -0,0 +1,41 @@
+package fit
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
+	"github.com/kyzrfranz/go-fitter/pkg/validate"
+)
+
+func (h *Handler) validateHandler(w http.ResponseWriter, r *http.Request) {
+	// Not upload.FIT, broken headers are for validate to report
+	f, err := upload.Unpack(r)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		return
+	}
+
+	var opts []validate.Option
+	if v := r.URL.Query().Get("gap"); v != "" {
+		gap, err := time.ParseDuration(v)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, "gap: "+err.Error())
+			return
+		}
+		opts = append(opts, validate.WithGapThreshold(gap))
+	}
+
+	if err := h.pool.Acquire(r.Context()); err != nil {
+		problem.Write(w, r, http.StatusServiceUnavailable, problem.CodeUnavailable, err.Error())
+		return
+	}
+	report := validate.Validate(f.Data, opts...)
+	h.pool.Release()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -4,20 +4,47 @@ import (
 	"log/slog"
 
 	internalHttp "github.com/kyzrfranz/go-fitter/internal/http"
+	restActivity "github.com/kyzrfranz/go-fitter/internal/rest/activity"
+	restAthlete "github.com/kyzrfranz/go-fitter/internal/rest/athlete"
 	restFit "github.com/kyzrfranz/go-fitter/internal/rest/fit"
+	"github.com/kyzrfranz/go-fitter/internal/store"
+	"github.com/kyzrfranz/go-fitter/internal/training"
+	"github.com/kyzrfranz/go-fitter/internal/worker"
 )
 
 type Handler struct {
-	logger *slog.Logger
-	Fit    internalHttp.HandlerFunc
+	logger      *slog.Logger
+	Fit         internalHttp.HandlerFunc
+	FitBatch    internalHttp.HandlerFunc
+	FitValidate internalHttp.HandlerFunc
+	FitRepair   internalHttp.HandlerFunc
+	FitTrim     internalHttp.HandlerFunc
+	FitSplit    internalHttp.HandlerFunc
+	FitMerge    internalHttp.HandlerFunc
+	Activities  internalHttp.HandlerFunc
+	Activity    internalHttp.HandlerFunc
+	ActivityFIT internalHttp.HandlerFunc
+	AthleteLoad internalHttp.HandlerFunc
 }
 
-func NewHandler(logger *slog.Logger) *Handler {
+func NewHandler(logger *slog.Logger, pool *worker.Pool, repo store.Repository, thresholds training.Thresholds, profiles map[string]training.Thresholds, defaults restFit.Defaults) *Handler {
 
-	fitHandler := restFit.NewHandler(logger)
+	fitHandler := restFit.NewHandler(logger, pool, defaults)
+	activityHandler := restActivity.NewHandler(logger, repo, defaults.Privacy)
+	athleteHandler := restAthlete.NewHandler(logger, training.NewService(repo), thresholds, profiles)
 
 	return &Handler{
-		logger: logger,
-		Fit:    fitHandler.Handle,
+		logger:      logger,
+		Fit:         fitHandler.Handle,
+		FitBatch:    fitHandler.HandleBatch,
+		FitValidate: fitHandler.HandleValidate,
+		FitRepair:   fitHandler.HandleRepair,
+		FitTrim:     fitHandler.HandleTrim,
+		FitSplit:    fitHandler.HandleSplit,
+		FitMerge:    fitHandler.HandleMerge,
+		Activities:  activityHandler.Handle,
+		Activity:    activityHandler.HandleItem,
+		ActivityFIT: activityHandler.HandleFIT,
+		AthleteLoad: athleteHandler.HandleLoad,
 	}
 }
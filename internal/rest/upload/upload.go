@@ -0,0 +1,335 @@
+// Package upload reads the files sent to the API: multipart forms or raw bodies, limited in size,
+// decompressed, converted to FIT and checked to be FIT before any decoder sees them.
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+	"github.com/kyzrfranz/go-fitter/pkg/archive"
+	"github.com/kyzrfranz/go-fitter/pkg/importer"
+	"github.com/muktihari/fit/proto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/kyzrfranz/go-fitter/internal/rest/upload")
+
+const (
+	// maxMemory is the part of a multipart form kept in memory, the rest goes to temporary files.
+	maxMemory = 32 << 20
+
+	defaultName = "upload.fit"
+)
+
+// rawTypes are the content types accepted as a raw request body holding a single file.
+var rawTypes = map[string]bool{
+	"application/vnd.ant.fit":        true,
+	"application/octet-stream":       true,
+	"application/gzip":               true,
+	"application/x-gzip":             true,
+	"application/zip":                true,
+	"application/gpx+xml":            true,
+	"application/vnd.garmin.tcx+xml": true,
+	"application/xml":                true,
+	"text/xml":                       true,
+}
+
+// File is an uploaded file.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Limit returns a middleware that rejects bodies larger than maxBytes with a too_large problem, right
+// away when the Content-Length says so and otherwise once that much has been read.
+func Limit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeTooLarge,
+					fmt.Sprintf("upload of %d bytes exceeds the limit of %d bytes", r.ContentLength, maxBytes))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ParseForm parses the multipart form of r, keeping up to 32 MiB in memory. Errors other than an
+// oversized body or a problem reading it, like a used up quota, are invalid_multipart problems.
+func ParseForm(r *http.Request) error {
+	_, span := tracer.Start(r.Context(), "multipart.parse")
+	defer span.End()
+
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		if bodyError(err) || errors.Is(err, multipart.ErrMessageTooLarge) {
+			return err
+		}
+		return problem.Wrap(http.StatusBadRequest, problem.CodeInvalidMultipart, err)
+	}
+	span.SetAttributes(attribute.Int("multipart.files", len(r.MultipartForm.File)))
+	return nil
+}
+
+// Read returns the file sent in the "file" form field, or the request body if it has one of the raw
+// content types. A raw body is named by ?filename=, or by its Content-Disposition header.
+func Read(r *http.Request) (File, error) {
+	raw, err := isRaw(r)
+	if err != nil {
+		return File{}, err
+	}
+	if raw {
+		return readBody(r)
+	}
+
+	if err := ParseForm(r); err != nil {
+		return File{}, err
+	}
+	defer cleanup(r)
+
+	file, header, err := r.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return File{}, problem.Wrap(http.StatusBadRequest, problem.CodeMissingFile, errors.New(`no file in form field "file"`))
+	}
+	if err != nil {
+		return File{}, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Name: header.Filename, Data: data}, nil
+}
+
+// Unpack reads the upload and decompresses it if it is a .fit.gz, .zip or .tar.gz, going by its magic
+// bytes. Several FIT files in one archive are returned chained, a GPX or TCX file is converted to FIT.
+// Archives and files that can't be read are decode_failed problems.
+//
+// The result isn't checked to be FIT, use FIT unless the handler copes with broken files itself.
+func Unpack(r *http.Request) (File, error) {
+	f, err := Read(r)
+	if err != nil {
+		return File{}, err
+	}
+
+	_, span := tracer.Start(r.Context(), "upload.unpack", trace.WithAttributes(
+		attribute.String("upload.name", f.Name),
+		attribute.Int("upload.size", len(f.Data)),
+		attribute.String("upload.format", importer.Detect(f.Data).String()),
+	))
+	defer span.End()
+	f.Data, err = archive.Unpack(f.Name, f.Data)
+	if err == nil {
+		f.Data, err = importer.ToFIT(f.Data)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return File{}, unpackError(err)
+	}
+	return f, nil
+}
+
+// FIT is Unpack followed by CheckFIT.
+func FIT(r *http.Request) (File, error) {
+	f, err := Unpack(r)
+	if err != nil {
+		return File{}, err
+	}
+	if err := CheckFIT(f.Data); err != nil {
+		return File{}, err
+	}
+	return f, nil
+}
+
+// Files collects every uploaded file, expanding zip and tar.gz archives into their FIT files and
+// converting GPX and TCX files. A raw body is a single file. The entries aren't checked to be FIT,
+// so that a batch can report bad files one by one. The limits of the archive package hold for all
+// files together.
+func Files(r *http.Request) ([]archive.Entry, error) {
+	raw, err := isRaw(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	if raw {
+		f, err := readBody(r)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	} else {
+		if files, err = formFiles(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []archive.Entry
+	var size int
+	for _, f := range files {
+		extracted, err := archive.Extract(f.Name, f.Data)
+		if err != nil {
+			return nil, unpackError(fmt.Errorf("%s: %w", f.Name, err))
+		}
+		for _, e := range extracted {
+			size += len(e.Data)
+		}
+		if size > archive.MaxTotalSize {
+			return nil, unpackError(archive.ErrArchiveTooLarge)
+		}
+		if len(entries)+len(extracted) > archive.MaxEntries {
+			return nil, unpackError(archive.ErrTooManyEntries)
+		}
+		for i := range extracted {
+			if extracted[i].Data, err = importer.ToFIT(extracted[i].Data); err != nil {
+				return nil, unpackError(fmt.Errorf("%s: %w", extracted[i].Name, err))
+			}
+		}
+		entries = append(entries, extracted...)
+	}
+	return entries, nil
+}
+
+// formFiles reads the files of every form field in the order they were sent, which is the order of their
+// fields in the form. Other fields are skipped, they aren't available as form values afterwards.
+func formFiles(r *http.Request) ([]File, error) {
+	_, span := tracer.Start(r.Context(), "multipart.parse")
+	defer span.End()
+
+	files, err := readParts(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		if bodyError(err) {
+			return nil, err
+		}
+		return nil, problem.Wrap(http.StatusBadRequest, problem.CodeInvalidMultipart, err)
+	}
+	span.SetAttributes(attribute.Int("multipart.files", len(files)))
+	return files, nil
+}
+
+func readParts(r *http.Request) ([]File, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: part.FileName(), Data: data})
+	}
+}
+
+// CheckFIT checks the header of the first FIT sequence in data: its size, the ".FIT" signature and a
+// protocol version the decoder supports. Failures are decode_failed or unsupported_protocol problems.
+func CheckFIT(data []byte) error {
+	invalid := func(format string, args ...any) error {
+		return problem.Wrap(http.StatusUnprocessableEntity, problem.CodeDecodeFailed, fmt.Errorf("not a FIT file: "+format, args...))
+	}
+	if len(data) < 12 {
+		return invalid("%d bytes are too short for a header", len(data))
+	}
+	if size := data[0]; size != 12 && size != 14 {
+		return invalid("header size %d, want 12 or 14", size)
+	}
+	if string(data[8:12]) != ".FIT" {
+		return invalid(`missing ".FIT" signature`)
+	}
+	if v := proto.Version(data[1]); v.Major() < proto.V1.Major() || v.Major() > proto.Vmax.Major() {
+		return problem.Wrap(http.StatusUnprocessableEntity, problem.CodeUnsupportedProto,
+			fmt.Errorf("FIT protocol version %d.%d is not supported", v.Major(), v.Minor()))
+	}
+	return nil
+}
+
+// isRaw tells a raw body from a multipart form. Other content types are unsupported_media_type problems.
+func isRaw(r *http.Request) (bool, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case err == nil && mediaType == "multipart/form-data":
+		return false, nil
+	case err == nil && rawTypes[mediaType]:
+		return true, nil
+	default:
+		return false, problem.Wrap(http.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia,
+			fmt.Errorf("content type %q is neither multipart/form-data nor a FIT, GPX, TCX or archive file", r.Header.Get("Content-Type")))
+	}
+}
+
+func readBody(r *http.Request) (File, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return File{}, err
+	}
+	if len(data) == 0 {
+		return File{}, problem.Wrap(http.StatusBadRequest, problem.CodeMissingFile, errors.New("empty request body"))
+	}
+
+	name := r.URL.Query().Get("filename")
+	if name == "" {
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+			name = params["filename"]
+		}
+	}
+	if name == "" {
+		name = defaultName
+	}
+	return File{Name: name, Data: data}, nil
+}
+
+// bodyError reports whether err comes from reading the body rather than from parsing it: an oversized
+// body or a problem of a middleware limiting it.
+func bodyError(err error) bool {
+	var maxBytes *http.MaxBytesError
+	var pe *problem.Error
+	return errors.As(err, &maxBytes) || errors.As(err, &pe)
+}
+
+// cleanup removes the temporary files of a multipart form once its files have been read into memory,
+// instead of at the end of the request. Form values stay available.
+func cleanup(r *http.Request) {
+	if r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
+
+// unpackError maps archive and import failures: oversized archives and entries are too_large, archives
+// with too many entries unprocessable and the rest decode_failed.
+func unpackError(err error) error {
+	switch {
+	case errors.Is(err, archive.ErrEntryTooLarge), errors.Is(err, archive.ErrArchiveTooLarge):
+		return problem.Wrap(http.StatusRequestEntityTooLarge, problem.CodeTooLarge, err)
+	case errors.Is(err, archive.ErrTooManyEntries):
+		return problem.Wrap(http.StatusUnprocessableEntity, problem.CodeUnprocessable, err)
+	default:
+		return problem.Wrap(http.StatusUnprocessableEntity, problem.CodeDecodeFailed, err)
+	}
+}
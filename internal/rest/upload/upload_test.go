@@ -0,0 +1,258 @@
+package upload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kyzrfranz/go-fitter/internal/problem"
+)
+
+func sample(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../../../pkg/converters/testdata/activity_poolswim.fit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// part is a file in a form field.
+type part struct {
+	field, name string
+	data        []byte
+}
+
+// form returns a multipart request with parts, sent without a Content-Length when chunked is set.
+func form(t *testing.T, chunked bool, parts ...part) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		fw, err := mw.CreateFormFile(p.field, p.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(p.data)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/fit", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	if chunked {
+		r.ContentLength = -1
+	}
+	return r
+}
+
+// serve runs FIT behind Limit(maxBytes) and returns the status and problem code of the answer.
+func serve(t *testing.T, maxBytes int64, r *http.Request) (int, problem.Code) {
+	t.Helper()
+	h := Limit(maxBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := FIT(r); err != nil {
+			problem.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code == http.StatusOK {
+		return w.Code, ""
+	}
+	var details problem.Details
+	if err := json.NewDecoder(w.Body).Decode(&details); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, details.Code
+}
+
+func TestLimit(t *testing.T) {
+	data := sample(t)
+	raw := func(chunked bool) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/fit", bytes.NewReader(data))
+		r.Header.Set("Content-Type", "application/vnd.ant.fit")
+		if chunked {
+			r.ContentLength = -1
+		}
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		maxBytes   int64
+		request    *http.Request
+		wantStatus int
+		wantCode   problem.Code
+	}{
+		{name: "within the limit", maxBytes: int64(len(data)), request: raw(false), wantStatus: http.StatusOK},
+		{name: "no limit", request: raw(true), wantStatus: http.StatusOK},
+		{name: "Content-Length over the limit", maxBytes: 100, request: raw(false), wantStatus: http.StatusRequestEntityTooLarge, wantCode: problem.CodeTooLarge},
+		{name: "raw body over the limit", maxBytes: 100, request: raw(true), wantStatus: http.StatusRequestEntityTooLarge, wantCode: problem.CodeTooLarge},
+		{name: "form over the limit", maxBytes: 100, request: form(t, true, part{"file", "ride.fit", data}), wantStatus: http.StatusRequestEntityTooLarge, wantCode: problem.CodeTooLarge},
+		{name: "form within the limit", maxBytes: 1 << 20, request: form(t, true, part{"file", "ride.fit", data}), wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := serve(t, tt.maxBytes, tt.request)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("status = %d %s, want %d %s", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFIT(t *testing.T) {
+	data := sample(t)
+	request := func(contentType string, body []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/fit", bytes.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		wantStatus int
+		wantCode   problem.Code
+	}{
+		{name: "raw FIT", request: request("application/vnd.ant.fit", data), wantStatus: http.StatusOK},
+		{name: "octet stream with parameters", request: request("application/octet-stream; charset=binary", data), wantStatus: http.StatusOK},
+		{name: "gzipped FIT", request: request("application/gzip", gzipped(t, data)), wantStatus: http.StatusOK},
+		{name: "form", request: form(t, false, part{"file", "ride.fit", data}), wantStatus: http.StatusOK},
+		{name: "form without a file field", request: form(t, false, part{"upload", "ride.fit", data}), wantStatus: http.StatusBadRequest, wantCode: problem.CodeMissingFile},
+		{name: "broken form", request: request("multipart/form-data; boundary=x", []byte("not a form")),
+			wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidMultipart},
+		{name: "empty body", request: request("application/vnd.ant.fit", nil), wantStatus: http.StatusBadRequest, wantCode: problem.CodeMissingFile},
+		{name: "JSON", request: request("application/json", data), wantStatus: http.StatusUnsupportedMediaType, wantCode: problem.CodeUnsupportedMedia},
+		{name: "no content type", request: request("", data), wantStatus: http.StatusUnsupportedMediaType, wantCode: problem.CodeUnsupportedMedia},
+		{name: "not FIT", request: request("application/octet-stream", []byte(strings.Repeat("x", 20))),
+			wantStatus: http.StatusUnprocessableEntity, wantCode: problem.CodeDecodeFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := serve(t, 0, tt.request)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("status = %d %s, want %d %s", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestReadName(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		disposition string
+		want        string
+	}{
+		{name: "default", target: "/fit", want: defaultName},
+		{name: "query", target: "/fit?filename=ride.fit", disposition: `attachment; filename="other.fit"`, want: "ride.fit"},
+		{name: "Content-Disposition", target: "/fit", disposition: `attachment; filename="ride.fit.gz"`, want: "ride.fit.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader("data"))
+			r.Header.Set("Content-Type", "application/octet-stream")
+			if tt.disposition != "" {
+				r.Header.Set("Content-Disposition", tt.disposition)
+			}
+			f, err := Read(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Name != tt.want || string(f.Data) != "data" {
+				t.Errorf("Read() = %q %q, want %q", f.Name, f.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFIT(t *testing.T) {
+	data := sample(t)
+	modify := func(i int, b byte) []byte {
+		d := bytes.Clone(data)
+		d[i] = b
+		return d
+	}
+	tests := []struct {
+		name     string
+		data     []byte
+		wantCode problem.Code
+	}{
+		{name: "valid", data: data},
+		{name: "too short", data: data[:11], wantCode: problem.CodeDecodeFailed},
+		{name: "header size", data: modify(0, 13), wantCode: problem.CodeDecodeFailed},
+		{name: "signature", data: modify(9, 'X'), wantCode: problem.CodeDecodeFailed},
+		{name: "protocol version 3", data: modify(1, 0x30), wantCode: problem.CodeUnsupportedProto},
+		{name: "protocol version 0", data: modify(1, 0x01), wantCode: problem.CodeUnsupportedProto},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFIT(tt.data)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("CheckFIT() error = %v", err)
+				}
+				return
+			}
+			if status, code := problem.From(err); status != http.StatusUnprocessableEntity || code != tt.wantCode {
+				t.Errorf("CheckFIT() = %d %s (%v), want 422 %s", status, code, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	data := sample(t)
+	r := form(t, false, part{"b", "b.fit", data}, part{"a", "a.fit.gz", gzipped(t, data)})
+	entries, err := Files(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !bytes.Equal(entries[0].Data, data) || !bytes.Equal(entries[1].Data, data) {
+		t.Fatalf("Files() = %d entries, want b.fit and a.fit.gz unpacked in the order sent", len(entries))
+	}
+	if entries[0].Name != "b.fit" {
+		t.Errorf("first entry = %q, want b.fit", entries[0].Name)
+	}
+}
+
+// A problem of a middleware reading the body, like a used up quota, reaches the handler as it is.
+func TestReadBodyProblem(t *testing.T) {
+	quota := problem.Wrap(http.StatusTooManyRequests, problem.CodeQuotaExceeded, io.ErrUnexpectedEOF)
+	r := form(t, true, part{"file", "ride.fit", sample(t)})
+	r.Body = io.NopCloser(io.MultiReader(io.LimitReader(r.Body, 100), errReader{quota}))
+
+	_, err := FIT(r)
+	if status, code := problem.From(err); status != http.StatusTooManyRequests || code != problem.CodeQuotaExceeded {
+		t.Errorf("FIT() = %d %s (%v), want 429 quota_exceeded", status, code, err)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+const (
+	indexFile    = "index.json"
+	activityDir  = "activities"
+	fitFileName  = "activity.fit"
+	jsonFileName = "activity.json"
+)
+
+var _ Repository = &FileStore{}
+
+// FileStore keeps activities on disk: one directory per activity plus an index with all metadata.
+type FileStore struct {
+	root string
+
+	mu         sync.RWMutex
+	activities []Activity
+}
+
+func NewFileStore(root string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Join(root, activityDir), 0o755); err != nil {
+		return nil, fmt.Errorf("create store: %w", err)
+	}
+
+	s := &FileStore{root: root}
+
+	b, err := os.ReadFile(filepath.Join(root, indexFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("read index: %w", err)
+	default:
+		if err := json.Unmarshal(b, &s.activities); err != nil {
+			return nil, fmt.Errorf("parse index: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Save(_ context.Context, a Activity, fit []byte, jsonData []byte) (Activity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.findDuplicate(a); ok {
+		return existing, ErrDuplicate
+	}
+
+	// Nothing outside the index refers to the directory, it goes again if the activity can't be saved
+	dir := s.dir(a.ID)
+	if err := writeActivity(dir, fit, jsonData); err != nil {
+		_ = os.RemoveAll(dir)
+		return Activity{}, fmt.Errorf("save activity: %w", err)
+	}
+
+	s.activities = append(s.activities, a)
+	if err := s.writeIndex(); err != nil {
+		s.activities = s.activities[:len(s.activities)-1]
+		_ = os.RemoveAll(dir)
+		return Activity{}, err
+	}
+
+	return a, nil
+}
+
+func writeActivity(dir string, fit []byte, jsonData []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, fitFileName), fit); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, jsonFileName), jsonData)
+}
+
+func (s *FileStore) Get(_ context.Context, id string) (Activity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range s.activities {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return Activity{}, ErrNotFound
+}
+
+// List returns matching activities ordered by start time.
+func (s *FileStore) List(_ context.Context, filter Filter) ([]Activity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Activity, 0)
+	for _, a := range s.activities {
+		if filter.Match(a) {
+			result = append(result, a)
+		}
+	}
+	slices.SortFunc(result, func(a, b Activity) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return result, nil
+}
+
+func (s *FileStore) OpenFIT(ctx context.Context, id string) (io.ReadCloser, error) {
+	return s.open(ctx, id, fitFileName)
+}
+
+func (s *FileStore) OpenJSON(ctx context.Context, id string) (io.ReadCloser, error) {
+	return s.open(ctx, id, jsonFileName)
+}
+
+func (s *FileStore) open(ctx context.Context, id string, name string) (io.ReadCloser, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(s.dir(id), name))
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// findDuplicate only looks at the activities of a's athlete, the uploads of others are none of its business.
+func (s *FileStore) findDuplicate(a Activity) (Activity, bool) {
+	for _, existing := range s.activities {
+		if existing.AthleteID != a.AthleteID {
+			continue
+		}
+		if existing.ContentHash == a.ContentHash {
+			return existing, true
+		}
+		if a.SerialNumber != 0 && !a.TimeCreated.IsZero() &&
+			existing.SerialNumber == a.SerialNumber && existing.TimeCreated.Equal(a.TimeCreated) {
+			return existing, true
+		}
+	}
+	return Activity{}, false
+}
+
+func (s *FileStore) dir(id string) string {
+	return filepath.Join(s.root, activityDir, id)
+}
+
+func (s *FileStore) writeIndex() error {
+	b, err := json.MarshalIndent(s.activities, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.root, indexFile), b); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file first so a crash never leaves a half written file behind.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+)
+
+var created = time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+func stored(id, athlete, hash string, serial uint32) Activity {
+	return Activity{
+		ID:           id,
+		AthleteID:    athlete,
+		ContentHash:  hash,
+		SerialNumber: serial,
+		TimeCreated:  created,
+		Summary:      activity.Summary{Sport: "running", StartTime: created},
+	}
+}
+
+func TestFileStoreSave(t *testing.T) {
+	first := stored("a1", "jane", "hash1", 1234)
+
+	tests := []struct {
+		name    string
+		a       Activity
+		wantID  string
+		wantErr error
+	}{
+		{name: "same content", a: stored("a2", "jane", "hash1", 0), wantID: "a1", wantErr: ErrDuplicate},
+		{name: "same serial number and time created", a: stored("a2", "jane", "hash2", 1234), wantID: "a1", wantErr: ErrDuplicate},
+		{name: "same serial number, other time created", a: func() Activity {
+			a := stored("a2", "jane", "hash2", 1234)
+			a.TimeCreated = created.Add(time.Hour)
+			return a
+		}(), wantID: "a2"},
+		{name: "no serial number", a: stored("a2", "jane", "hash2", 0), wantID: "a2"},
+		{name: "same content of another athlete", a: stored("a2", "john", "hash1", 1234), wantID: "a2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			if _, err := s.Save(ctx, first, []byte("fit"), []byte("{}")); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.Save(ctx, tt.a, []byte("fit"), []byte("{}"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Save() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("Save() = %s, want %s", got.ID, tt.wantID)
+			}
+			want := 2
+			if tt.wantErr != nil {
+				want = 1
+			}
+			if list, _ := s.List(ctx, Filter{}); len(list) != want {
+				t.Errorf("List() = %d activities, want %d", len(list), want)
+			}
+		})
+	}
+}
+
+func TestFileStoreReload(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	s, err := NewFileStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []Activity{stored("a1", "jane", "hash1", 1), stored("a2", "john", "hash2", 2)} {
+		if _, err := s.Save(ctx, a, []byte("fit "+a.ID), []byte(`{"id":"`+a.ID+`"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded, err := NewFileStore(root)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		id      string
+		open    func(ctx context.Context, id string) (io.ReadCloser, error)
+		want    string
+		wantErr error
+	}{
+		{name: "fit", id: "a1", open: reloaded.OpenFIT, want: "fit a1"},
+		{name: "json", id: "a2", open: reloaded.OpenJSON, want: `{"id":"a2"}`},
+		{name: "unknown", id: "a3", open: reloaded.OpenFIT, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := tt.open(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("open(%s) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer rc.Close()
+			b, _ := io.ReadAll(rc)
+			if string(b) != tt.want {
+				t.Errorf("open(%s) = %q, want %q", tt.id, b, tt.want)
+			}
+		})
+	}
+
+	if _, err := reloaded.Save(ctx, stored("a3", "jane", "hash1", 0), nil, nil); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Save() of a reloaded duplicate error = %v, want %v", err, ErrDuplicate)
+	}
+	if list, _ := reloaded.List(ctx, Filter{AthleteID: "jane"}); len(list) != 1 || list[0].ID != "a1" {
+		t.Errorf("List() = %+v, want a1", list)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, indexFile), []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(root); err == nil {
+		t.Error("NewFileStore() of a broken index error = nil")
+	}
+}
+
+func TestFileStoreSaveFailure(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewFileStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A directory in place of the index can't be replaced by the new index
+	if err := os.Mkdir(filepath.Join(root, indexFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := s.Save(ctx, stored("a1", "jane", "hash1", 1), []byte("fit"), []byte("{}")); err == nil {
+		t.Fatal("Save() error = nil")
+	}
+	if _, err := os.Stat(s.dir("a1")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("directory of the failed activity is left behind: %v", err)
+	}
+	if _, err := s.Get(ctx, "a1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of the failed activity error = %v, want %v", err, ErrNotFound)
+	}
+
+	// Once the index can be written the same activity is saved, not reported as a duplicate
+	if err := os.Remove(filepath.Join(root, indexFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Save(ctx, stored("a1", "jane", "hash1", 1), []byte("fit"), []byte("{}")); err != nil {
+		t.Errorf("Save() after the failure error = %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+)
+
+var (
+	ErrNotFound  = errors.New("activity not found")
+	ErrDuplicate = errors.New("activity already stored")
+)
+
+// Activity is the metadata kept for every stored upload.
+type Activity struct {
+	ID           string    `json:"id"`
+	AthleteID    string    `json:"athlete_id"`
+	FileName     string    `json:"file_name,omitempty"`
+	ContentHash  string    `json:"content_hash"`
+	SerialNumber uint32    `json:"serial_number,omitempty"`
+	TimeCreated  time.Time `json:"time_created"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+
+	activity.Summary `json:"summary"`
+}
+
+// Filter narrows down List. Zero values match everything.
+type Filter struct {
+	AthleteID   string
+	Sport       string
+	From        time.Time
+	To          time.Time
+	MinDistance float64
+	MaxDistance float64
+}
+
+func (f Filter) Match(a Activity) bool {
+	switch {
+	case f.AthleteID != "" && a.AthleteID != f.AthleteID:
+		return false
+	case f.Sport != "" && a.Sport != f.Sport:
+		return false
+	case !f.From.IsZero() && a.StartTime.Before(f.From):
+		return false
+	case !f.To.IsZero() && !a.StartTime.Before(f.To):
+		return false
+	case f.MinDistance > 0 && a.Distance < f.MinDistance:
+		return false
+	case f.MaxDistance > 0 && a.Distance > f.MaxDistance:
+		return false
+	}
+	return true
+}
+
+// Repository persists activities together with their original FIT file and converted JSON.
+//
+// Save returns ErrDuplicate along with the already stored activity when either the content hash
+// or the file_id serial number and time_created match an existing entry of the same athlete.
+type Repository interface {
+	Save(ctx context.Context, a Activity, fit []byte, json []byte) (Activity, error)
+	Get(ctx context.Context, id string) (Activity, error)
+	List(ctx context.Context, filter Filter) ([]Activity, error)
+	OpenFIT(ctx context.Context, id string) (io.ReadCloser, error)
+	OpenJSON(ctx context.Context, id string) (io.ReadCloser, error)
+}
@@ -0,0 +1,76 @@
+// Package tracing sets up OpenTelemetry tracing for the API server.
+//
+// Spans are created through the global tracer provider, so packages only depend on the OpenTelemetry API.
+// Unless an exporter is configured the provider stays a no-op, but traceparent headers are still
+// propagated so that go-fitter doesn't break the traces of the services around it.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kyzrfranz/go-fitter/internal/args"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// Config selects where spans go. The environment variables are named after the OpenTelemetry ones.
+type Config struct {
+	Exporter    string // none or otlp
+	Endpoint    string // Base URL of the OTLP/HTTP collector, /v1/traces is appended
+	ServiceName string
+	SampleRatio float64 // Fraction of new traces to record, incoming sampled parents are always followed
+}
+
+// ConfigFromEnv reads OTEL_TRACES_EXPORTER, OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_SERVICE_NAME and
+// OTEL_TRACES_SAMPLER_ARG.
+func ConfigFromEnv() Config {
+	return Config{
+		Exporter:    args.EnvOrDefault[string]("OTEL_TRACES_EXPORTER", ExporterNone),
+		Endpoint:    args.EnvOrDefault[string]("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		ServiceName: args.EnvOrDefault[string]("OTEL_SERVICE_NAME", "go-fitter"),
+		SampleRatio: args.EnvOrDefault[float64]("OTEL_TRACES_SAMPLER_ARG", 1),
+	}
+}
+
+// Setup installs the W3C trace context propagator and, for the otlp exporter, a tracer provider
+// exporting to cfg.Endpoint. The returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, want %s or %s", cfg.Exporter, ExporterNone, ExporterOTLP)
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimRight(cfg.Endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,56 @@
+package training
+
+import (
+	"math"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+)
+
+// Method names the formula a training load was computed with.
+type Method string
+
+const (
+	MethodTSS   Method = "tss"
+	MethodHrTSS Method = "hrtss"
+	MethodTRIMP Method = "trimp"
+	MethodNone  Method = "none"
+)
+
+// Thresholds are the athlete specific values the load formulas need. Zero means unknown.
+type Thresholds struct {
+	FTP    float64 `json:"ftp,omitempty"`     // functional threshold power in watts
+	LTHR   float64 `json:"lthr,omitempty"`    // lactate threshold heart rate in bpm
+	MaxHR  float64 `json:"max_hr,omitempty"`  // maximum heart rate in bpm
+	RestHR float64 `json:"rest_hr,omitempty"` // resting heart rate in bpm
+}
+
+// Score picks the best formula the data allows: power based TSS when there is power and an FTP,
+// heart rate based hrTSS when there is a threshold heart rate and Banister's TRIMP otherwise.
+func Score(s activity.Summary, th Thresholds) (float64, Method) {
+	hours := s.Duration / 3600
+	if hours <= 0 {
+		return 0, MethodNone
+	}
+
+	if th.FTP > 0 && s.NormalizedPower > 0 {
+		intensity := s.NormalizedPower / th.FTP
+		return hours * intensity * intensity * 100, MethodTSS
+	}
+
+	if s.AvgHeartRate <= 0 {
+		return 0, MethodNone
+	}
+
+	if th.LTHR > 0 {
+		intensity := s.AvgHeartRate / th.LTHR
+		return hours * intensity * intensity * 100, MethodHrTSS
+	}
+
+	if th.MaxHR > th.RestHR {
+		reserve := (s.AvgHeartRate - th.RestHR) / (th.MaxHR - th.RestHR)
+		reserve = min(max(reserve, 0), 1)
+		return hours * 60 * reserve * 0.64 * math.Exp(1.92*reserve), MethodTRIMP
+	}
+
+	return 0, MethodNone
+}
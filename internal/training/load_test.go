@@ -0,0 +1,44 @@
+package training
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		s          activity.Summary
+		th         Thresholds
+		want       float64
+		wantMethod Method
+	}{
+		{name: "an hour at FTP", s: activity.Summary{Duration: 3600, NormalizedPower: 250}, th: Thresholds{FTP: 250},
+			want: 100, wantMethod: MethodTSS},
+		// 0.5 h × (200 / 250)² × 100
+		{name: "half an hour below FTP", s: activity.Summary{Duration: 1800, NormalizedPower: 200}, th: Thresholds{FTP: 250},
+			want: 32, wantMethod: MethodTSS},
+		// 1.5 h × (135 / 150)² × 100
+		{name: "power without FTP falls back to heart rate", s: activity.Summary{Duration: 5400, NormalizedPower: 250, AvgHeartRate: 135},
+			th: Thresholds{LTHR: 150}, want: 121.5, wantMethod: MethodHrTSS},
+		// 60 min × 0.714 × 0.64 × e^(1.92 × 0.714), heart rate reserve (150 - 50) / (190 - 50)
+		{name: "TRIMP", s: activity.Summary{Duration: 3600, AvgHeartRate: 150}, th: Thresholds{MaxHR: 190, RestHR: 50},
+			want: 108.0954, wantMethod: MethodTRIMP},
+		// reserve capped at 1: 60 min × 0.64 × e^1.92
+		{name: "TRIMP above max heart rate", s: activity.Summary{Duration: 3600, AvgHeartRate: 200}, th: Thresholds{MaxHR: 190, RestHR: 50},
+			want: 261.9248, wantMethod: MethodTRIMP},
+		{name: "no duration", s: activity.Summary{NormalizedPower: 250}, th: Thresholds{FTP: 250}, wantMethod: MethodNone},
+		{name: "no power or heart rate", s: activity.Summary{Duration: 3600}, th: Thresholds{FTP: 250, LTHR: 150}, wantMethod: MethodNone},
+		{name: "no thresholds", s: activity.Summary{Duration: 3600, AvgHeartRate: 150}, wantMethod: MethodNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, method := Score(tt.s, tt.th)
+			if math.Abs(got-tt.want) > 1e-3 || method != tt.wantMethod {
+				t.Errorf("Score() = %g, %s, want %g, %s", got, method, tt.want, tt.wantMethod)
+			}
+		})
+	}
+}
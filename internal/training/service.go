@@ -0,0 +1,172 @@
+package training
+
+import (
+	"context"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/store"
+)
+
+const (
+	DefaultChronicDays = 42
+	DefaultAcuteDays   = 7
+
+	// acwrAcuteDays and acwrChronicDays are the rolling windows of the acute:chronic workload ratio.
+	acwrAcuteDays   = 7
+	acwrChronicDays = 28
+
+	// seedWindows is how many chronic time constants of history seed the averages, older loads would weigh
+	// less than 0.3% on the first reported day.
+	seedWindows = 6
+	// MaxTimeConstant is the longest chronic or acute time constant in days.
+	MaxTimeConstant = 365
+)
+
+// ActivityLoad is the load of a single stored activity.
+type ActivityLoad struct {
+	ID        string    `json:"id"`
+	StartTime time.Time `json:"start_time"`
+	Sport     string    `json:"sport"`
+	Load      float64   `json:"load"`
+	Method    Method    `json:"method"`
+}
+
+// Day is one point of the performance management chart.
+type Day struct {
+	Date string  `json:"date"`
+	Load float64 `json:"load"`
+	CTL  float64 `json:"ctl"`  // chronic training load, "fitness"
+	ATL  float64 `json:"atl"`  // acute training load, "fatigue"
+	TSB  float64 `json:"tsb"`  // training stress balance, "form"
+	ACWR float64 `json:"acwr"` // acute:chronic workload ratio, 0 while there is no chronic load
+}
+
+type Report struct {
+	AthleteID  string         `json:"athlete_id"`
+	From       string         `json:"from"`
+	To         string         `json:"to"`
+	Thresholds Thresholds     `json:"thresholds"`
+	Activities []ActivityLoad `json:"activities"`
+	Days       []Day          `json:"days"`
+}
+
+type Options struct {
+	Thresholds  Thresholds
+	ChronicDays int
+	AcuteDays   int
+}
+
+type Service struct {
+	repo store.Repository
+}
+
+func NewService(repo store.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// Load computes the daily training load chart for athleteID between from and to (both inclusive dates, UTC).
+// The exponentially weighted averages are seeded with the history of seedWindows chronic time constants before
+// from, so the first reported day already carries the fitness built up before it.
+func (s *Service) Load(ctx context.Context, athleteID string, from, to time.Time, opts Options) (Report, error) {
+	if opts.ChronicDays <= 0 {
+		opts.ChronicDays = DefaultChronicDays
+	}
+	if opts.AcuteDays <= 0 {
+		opts.AcuteDays = DefaultAcuteDays
+	}
+	opts.ChronicDays, opts.AcuteDays = min(opts.ChronicDays, MaxTimeConstant), min(opts.AcuteDays, MaxTimeConstant)
+	from, to = day(from), day(to)
+
+	// Activities without a start time or long before from don't take the loop back to year one
+	seed := from.AddDate(0, 0, -seedWindows*opts.ChronicDays)
+	activities, err := s.repo.List(ctx, store.Filter{AthleteID: athleteID, From: seed, To: to.AddDate(0, 0, 1)})
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		AthleteID:  athleteID,
+		From:       from.Format(time.DateOnly),
+		To:         to.Format(time.DateOnly),
+		Thresholds: opts.Thresholds,
+		Activities: make([]ActivityLoad, 0),
+		Days:       make([]Day, 0),
+	}
+
+	daily := make(map[time.Time]float64)
+	start := from
+	for _, a := range activities {
+		load, method := Score(a.Summary, opts.Thresholds)
+		d := day(a.StartTime)
+		daily[d] += load
+		if d.Before(start) {
+			start = d
+		}
+		if !d.Before(from) {
+			report.Activities = append(report.Activities, ActivityLoad{
+				ID:        a.ID,
+				StartTime: a.StartTime,
+				Sport:     a.Sport,
+				Load:      load,
+				Method:    method,
+			})
+		}
+	}
+
+	var ctl, atl float64
+	var history []float64
+	for d := start; !d.After(to); d = d.AddDate(0, 0, 1) {
+		load := daily[d]
+		tsb := ctl - atl // form is yesterday's fitness minus yesterday's fatigue
+		ctl += (load - ctl) / float64(opts.ChronicDays)
+		atl += (load - atl) / float64(opts.AcuteDays)
+		history = append(history, load)
+		if len(history) > acwrChronicDays {
+			history = history[1:] // acwr looks no further back
+		}
+
+		if d.Before(from) {
+			continue
+		}
+		report.Days = append(report.Days, Day{
+			Date: d.Format(time.DateOnly),
+			Load: load,
+			CTL:  ctl,
+			ATL:  atl,
+			TSB:  tsb,
+			ACWR: acwr(history),
+		})
+	}
+
+	return report, nil
+}
+
+// acwr divides the mean daily load of the last acwrAcuteDays by the mean of the last acwrChronicDays.
+func acwr(history []float64) float64 {
+	acute := rollingMean(history, acwrAcuteDays)
+	chronic := rollingMean(history, acwrChronicDays)
+	if chronic == 0 {
+		return 0
+	}
+	return acute / chronic
+}
+
+// rollingMean is the mean of the last days of history, or of all of it while it is shorter.
+func rollingMean(history []float64, days int) float64 {
+	window := history[max(len(history)-days, 0):]
+	if len(window) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range window {
+		sum += v
+	}
+	return sum / float64(len(window))
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
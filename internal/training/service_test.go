@@ -0,0 +1,135 @@
+package training
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/internal/store"
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+)
+
+var day0 = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// hour returns a stored activity on day0 plus days that scores 100 TSS at an FTP of 250.
+func hour(id string, days int) store.Activity {
+	return store.Activity{
+		ID:          id,
+		AthleteID:   "jane",
+		ContentHash: id,
+		Summary: activity.Summary{
+			Sport:           "cycling",
+			StartTime:       day0.AddDate(0, 0, days).Add(8 * time.Hour),
+			Duration:        3600,
+			NormalizedPower: 250,
+		},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	// Time constants of 4 and 2 days: ctl += (load - ctl) / 4, atl += (load - atl) / 2, tsb is yesterday's ctl - atl
+	opts := Options{Thresholds: Thresholds{FTP: 250}, ChronicDays: 4, AcuteDays: 2}
+
+	tests := []struct {
+		name           string
+		activities     []store.Activity
+		wantActivities int
+		wantDays       []Day
+	}{
+		{
+			name:           "a single activity",
+			activities:     []store.Activity{hour("a1", 0)},
+			wantActivities: 1,
+			wantDays: []Day{
+				{Date: "2026-10-01", Load: 100, CTL: 25, ATL: 50, TSB: 0, ACWR: 1},
+				{Date: "2026-10-02", Load: 0, CTL: 18.75, ATL: 25, TSB: -25, ACWR: 1},
+				{Date: "2026-10-03", Load: 0, CTL: 14.0625, ATL: 12.5, TSB: -6.25, ACWR: 1},
+			},
+		},
+		{
+			name:           "history before from seeds the averages",
+			activities:     []store.Activity{hour("a1", -1), hour("a2", 1), hour("a3", 1)},
+			wantActivities: 2,
+			wantDays: []Day{
+				{Date: "2026-10-01", Load: 0, CTL: 18.75, ATL: 25, TSB: -25, ACWR: 1},
+				{Date: "2026-10-02", Load: 200, CTL: 64.0625, ATL: 112.5, TSB: -6.25, ACWR: 1},
+				{Date: "2026-10-03", Load: 0, CTL: 48.046875, ATL: 56.25, TSB: -48.4375, ACWR: 1},
+			},
+		},
+		{
+			// Six chronic time constants are 24 days, anything older or without a start time is left out
+			name: "activities out of the seed window",
+			activities: []store.Activity{hour("a1", -25), func() store.Activity {
+				a := hour("a2", 0)
+				a.StartTime = time.Time{}
+				return a
+			}()},
+			wantDays: []Day{
+				{Date: "2026-10-01"},
+				{Date: "2026-10-02"},
+				{Date: "2026-10-03"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := store.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range tt.activities {
+				if _, err := repo.Save(context.Background(), a, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			report, err := NewService(repo).Load(context.Background(), "jane", day0, day0.AddDate(0, 0, 2), opts)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(report.Activities) != tt.wantActivities {
+				t.Errorf("Load() = %d activities, want %d", len(report.Activities), tt.wantActivities)
+			}
+			if !slices.EqualFunc(report.Days, tt.wantDays, closeDay) {
+				t.Errorf("Load() days = %+v, want %+v", report.Days, tt.wantDays)
+			}
+		})
+	}
+}
+
+func closeDay(a, b Day) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return a.Date == b.Date && near(a.Load, b.Load) && near(a.CTL, b.CTL) && near(a.ATL, b.ATL) &&
+		near(a.TSB, b.TSB) && near(a.ACWR, b.ACWR)
+}
+
+func TestACWR(t *testing.T) {
+	days := func(load float64, n int) []float64 {
+		return slices.Repeat([]float64{load}, n)
+	}
+	tests := []struct {
+		name    string
+		history []float64
+		want    float64
+	}{
+		{name: "no history", history: nil, want: 0},
+		{name: "no load", history: days(0, 28), want: 0},
+		{name: "steady load", history: days(100, 28), want: 1},
+		{name: "shorter than the chronic window", history: days(100, 14), want: 1},
+		// acute 100, chronic 7 × 100 / 28
+		{name: "a week after three weeks off", history: append(days(0, 21), days(100, 7)...), want: 4},
+		// acute 3 × 100 / 7, chronic 21 × 100 / 28
+		{name: "three days back after a week off", history: append(days(100, 18), append(days(0, 7), days(100, 3)...)...),
+			want: 3 * 100 / 7.0 / (21 * 100 / 28.0)},
+		{name: "a week off", history: append(days(100, 21), days(0, 7)...), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acwr(tt.history); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("acwr() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
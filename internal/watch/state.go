@@ -0,0 +1,70 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileState is what we remember about a processed file. A file is processed again only when its size
+// or modification time change.
+type fileState struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	ProcessedAt time.Time `json:"processed_at"`
+	Outputs     []string  `json:"outputs,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+func (s fileState) matches(info fs.FileInfo) bool {
+	return s.Size == info.Size() && s.ModTime.Equal(info.ModTime())
+}
+
+func loadState(path string) (map[string]fileState, error) {
+	state := make(map[string]fileState)
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("parse state: %w", err)
+	}
+	return state, nil
+}
+
+func saveState(path string, state map[string]fileState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	if err := writeFileAtomic(path, b); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file first so readers never see a half written file.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
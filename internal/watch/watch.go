@@ -0,0 +1,222 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/converters"
+	"github.com/muktihari/fit/decoder"
+)
+
+const DefaultStateFile = ".go-fitter-state.json"
+
+type Options struct {
+	Dir       string              // directory to watch, recursively
+	OutDir    string              // where outputs go, next to the input when empty
+	Formats   []converters.Format // one output per format
+	Interval  time.Duration       // how often Dir is scanned
+	StableFor time.Duration       // how long size and modification time must not change before a file is converted
+	StateFile string              // processed files, defaults to DefaultStateFile inside Dir
+	Convert   converters.Options
+}
+
+// pending is a file that has been seen but not been stable for long enough yet.
+type pending struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// Watcher polls a directory for new .fit files and converts each of them once.
+type Watcher struct {
+	logger  *slog.Logger
+	options Options
+
+	outDir  string // OutDir made absolute, it isn't scanned when it lies inside Dir
+	state   map[string]fileState
+	pending map[string]pending
+	now     func() time.Time
+}
+
+func New(logger *slog.Logger, options Options) (*Watcher, error) {
+	if options.Dir == "" {
+		return nil, fmt.Errorf("no directory to watch")
+	}
+	if options.Interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", options.Interval)
+	}
+	if options.StableFor < 0 {
+		return nil, fmt.Errorf("stable time must not be negative, got %s", options.StableFor)
+	}
+	var outDir string
+	if options.OutDir != "" {
+		var err error
+		if outDir, err = filepath.Abs(options.OutDir); err != nil {
+			return nil, fmt.Errorf("output directory: %w", err)
+		}
+	}
+	if len(options.Formats) == 0 {
+		options.Formats = []converters.Format{converters.FormatJSON}
+	}
+	if options.StateFile == "" {
+		options.StateFile = filepath.Join(options.Dir, DefaultStateFile)
+	}
+
+	state, err := loadState(options.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		logger:  logger,
+		options: options,
+		outDir:  outDir,
+		state:   state,
+		pending: make(map[string]pending),
+		now:     time.Now,
+	}, nil
+}
+
+// Run scans the directory every Interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.logger.Log(ctx, slog.LevelInfo, "watching directory", slog.String("dir", w.options.Dir))
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.scan(ctx); err != nil {
+			w.logger.Log(ctx, slog.LevelError, "scan failed", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan converts the files that have been stable for long enough. Files that can't be read or converted are
+// logged and kept in the state like any other, they are converted again once their size or modification time
+// change. Only an unreadable Dir fails the whole scan.
+func (w *Watcher) scan(ctx context.Context) error {
+	now := w.now()
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(w.options.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == w.options.Dir {
+				return err
+			}
+			w.logger.Log(ctx, slog.LevelError, "scan failed", slog.String("path", path), slog.String("error", err.Error()))
+			return nil
+		}
+		if d.IsDir() {
+			if w.outDir != "" {
+				if abs, err := filepath.Abs(path); err == nil && abs == w.outDir {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".fit") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed in the meantime
+		}
+		seen[path] = true
+
+		if st, ok := w.state[path]; ok && st.matches(info) {
+			return nil
+		}
+
+		p, ok := w.pending[path]
+		if !ok || p.size != info.Size() || !p.modTime.Equal(info.ModTime()) {
+			w.pending[path] = pending{size: info.Size(), modTime: info.ModTime(), since: now}
+			return nil
+		}
+		if now.Sub(p.since) < w.options.StableFor {
+			return nil
+		}
+
+		delete(w.pending, path)
+		w.state[path] = w.process(ctx, path, info)
+		// The state is kept in memory and written again with the next file
+		if err := saveState(w.options.StateFile, w.state); err != nil {
+			w.logger.Log(ctx, slog.LevelError, "saving state failed", slog.String("error", err.Error()))
+		}
+		return nil
+	})
+
+	for path := range w.pending {
+		if !seen[path] {
+			delete(w.pending, path)
+		}
+	}
+	return err
+}
+
+func (w *Watcher) process(ctx context.Context, path string, info fs.FileInfo) fileState {
+	st := fileState{
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		ProcessedAt: w.now().UTC(),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		st.Error = err.Error()
+		w.logger.Log(ctx, slog.LevelError, "read failed", slog.String("file", path), slog.String("error", err.Error()))
+		return st
+	}
+
+	opts := w.options.Convert
+	opts.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, format := range w.options.Formats {
+		out, err := w.outputPath(path, format)
+		if err == nil {
+			var result string
+			result, err = converters.Convert(bytes.NewReader(data), format, []decoder.Option{decoder.WithIgnoreChecksum()}, opts)
+			if err == nil {
+				err = writeFileAtomic(out, []byte(result))
+			}
+		}
+		if err != nil {
+			st.Error = err.Error()
+			w.logger.Log(ctx, slog.LevelError, "conversion failed", slog.String("file", path), slog.String("format", string(format)), slog.String("error", err.Error()))
+			continue
+		}
+		st.Outputs = append(st.Outputs, out)
+	}
+
+	if len(st.Outputs) > 0 {
+		w.logger.Log(ctx, slog.LevelInfo, "converted file", slog.String("file", path), slog.Any("outputs", st.Outputs))
+	}
+	return st
+}
+
+// outputPath mirrors the position of path below Dir into OutDir.
+func (w *Watcher) outputPath(path string, format converters.Format) (string, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path)) + format.Extension()
+	if w.options.OutDir == "" {
+		return base, nil
+	}
+	rel, err := filepath.Rel(w.options.Dir, base)
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(w.options.OutDir, rel)
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return "", err
+	}
+	return out, nil
+}
@@ -0,0 +1,229 @@
+package watch
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func sample(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../../pkg/converters/testdata/activity_poolswim.fit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// newWatcher returns a watcher of dir whose clock is *now.
+func newWatcher(t *testing.T, options Options, now *time.Time) *Watcher {
+	t.Helper()
+	options.Interval = time.Second
+	w, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = func() time.Time { return *now }
+	return w
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestScan(t *testing.T) {
+	data := sample(t)
+
+	type step struct {
+		wait       time.Duration
+		write      []byte // new content of the file, nil leaves it alone
+		wantOutput bool   // the JSON output has been written since the last step
+	}
+	tests := []struct {
+		name      string
+		stableFor time.Duration
+		steps     []step
+		wantAt    time.Duration // when the file was last processed
+		wantError bool          // the state records a failed conversion
+	}{
+		{
+			name:      "converted once stable",
+			stableFor: 10 * time.Second,
+			steps: []step{
+				{write: data},
+				{wait: 5 * time.Second},
+				{wait: 5 * time.Second, wantOutput: true},
+				{wait: time.Minute},
+			},
+			wantAt: 10 * time.Second,
+		},
+		{
+			name:      "a growing file waits again",
+			stableFor: 10 * time.Second,
+			steps: []step{
+				{write: data[:100]},
+				{wait: 10 * time.Second, write: data},
+				{wait: 5 * time.Second},
+				{wait: 5 * time.Second, wantOutput: true},
+			},
+			wantAt: 20 * time.Second,
+		},
+		{
+			name: "a failed file is not retried while unchanged",
+			steps: []step{
+				{write: []byte("not a FIT file")},
+				{wait: time.Second},
+				{wait: time.Minute},
+			},
+			wantAt:    time.Second,
+			wantError: true,
+		},
+		{
+			name: "a failed file is retried once it changed",
+			steps: []step{
+				{write: []byte("not a FIT file")},
+				{wait: time.Second},
+				{wait: time.Second, write: data},
+				{wait: time.Second, wantOutput: true},
+			},
+			wantAt: 3 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input, output := filepath.Join(dir, "ride.fit"), filepath.Join(dir, "ride.json")
+			start := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+			now := start
+			w := newWatcher(t, Options{Dir: dir, StableFor: tt.stableFor}, &now)
+
+			for i, s := range tt.steps {
+				now = now.Add(s.wait)
+				if s.write != nil {
+					if err := os.WriteFile(input, s.write, 0o644); err != nil {
+						t.Fatal(err)
+					}
+					// Modification times may be too coarse to tell two writes apart
+					if err := os.Chtimes(input, now, now); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := w.scan(context.Background()); err != nil {
+					t.Fatalf("step %d: scan() error = %v", i, err)
+				}
+				if got := exists(output); got != s.wantOutput {
+					t.Fatalf("step %d: output written = %v, want %v", i, got, s.wantOutput)
+				}
+				os.Remove(output)
+			}
+
+			state, err := loadState(filepath.Join(dir, DefaultStateFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			st, ok := state[input]
+			if !ok || !st.ProcessedAt.Equal(start.Add(tt.wantAt)) || (st.Error != "") != tt.wantError {
+				t.Errorf("state = %+v, want processed after %s, error %v", state, tt.wantAt, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestScanState(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "ride.fit"), filepath.Join(dir, "ride.json")
+	if err := os.WriteFile(input, sample(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	scan := func(w *Watcher) {
+		t.Helper()
+		for range 2 {
+			now = now.Add(time.Second)
+			if err := w.scan(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	scan(newWatcher(t, Options{Dir: dir}, &now))
+	if !exists(output) {
+		t.Fatal("no output")
+	}
+	os.Remove(output)
+
+	// A new watcher knows the file from the state file
+	w := newWatcher(t, Options{Dir: dir}, &now)
+	scan(w)
+	if exists(output) {
+		t.Fatal("file converted again after a restart")
+	}
+
+	modTime := now.Add(time.Hour)
+	if err := os.Chtimes(input, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	scan(w)
+	if !exists(output) {
+		t.Error("touched file not converted again")
+	}
+}
+
+func TestScanSkipsOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "out")
+	for _, path := range []string{filepath.Join(dir, "rides", "a.fit"), filepath.Join(outDir, "b.fit")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, sample(t), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	w := newWatcher(t, Options{Dir: dir, OutDir: outDir}, &now)
+	for range 2 {
+		now = now.Add(time.Second)
+		if err := w.scan(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !exists(filepath.Join(outDir, "rides", "a.json")) {
+		t.Error("rides/a.fit not converted into the output directory")
+	}
+	if exists(filepath.Join(outDir, "b.json")) || len(w.state) != 1 {
+		t.Errorf("output directory scanned, state = %v", w.state)
+	}
+}
+
+func TestScanContinuesWithoutState(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.fit", "b.fit"} {
+		if err := os.WriteFile(filepath.Join(dir, name), sample(t), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The state file can't be written into a directory that doesn't exist
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	w := newWatcher(t, Options{Dir: dir, StateFile: filepath.Join(dir, "missing", "state.json")}, &now)
+	for range 2 {
+		now = now.Add(time.Second)
+		if err := w.scan(context.Background()); err != nil {
+			t.Fatalf("scan() error = %v", err)
+		}
+	}
+
+	for _, name := range []string{"a.json", "b.json"} {
+		if !exists(filepath.Join(dir, name)) {
+			t.Errorf("%s not written after the state could not be saved", name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/kyzrfranz/go-fitter/internal/worker")
+
+// Pool bounds the number of conversions running at the same time across all requests.
+type Pool struct {
+	slots chan struct{}
+}
+
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{
+		slots: make(chan struct{}, size),
+	}
+}
+
+// Acquire blocks until a slot is free or ctx is done.
+func (p *Pool) Acquire(ctx context.Context) error {
+	_, span := tracer.Start(ctx, "pool.acquire")
+	defer span.End()
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (p *Pool) Release() {
+	<-p.slots
+}
+
+// Size returns the maximum number of concurrent jobs.
+func (p *Pool) Size() int {
+	return cap(p.slots)
+}
+
+// InUse returns the number of slots currently taken.
+func (p *Pool) InUse() int {
+	return len(p.slots)
+}
@@ -1,40 +1,185 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/kyzrfranz/go-fitter/internal/args"
+	"github.com/kyzrfranz/go-fitter/internal/auth"
+	"github.com/kyzrfranz/go-fitter/internal/cli"
+	"github.com/kyzrfranz/go-fitter/internal/config"
 	"github.com/kyzrfranz/go-fitter/internal/http"
+	"github.com/kyzrfranz/go-fitter/internal/metrics"
+	"github.com/kyzrfranz/go-fitter/internal/ratelimit"
 	"github.com/kyzrfranz/go-fitter/internal/rest"
+	restFit "github.com/kyzrfranz/go-fitter/internal/rest/fit"
+	"github.com/kyzrfranz/go-fitter/internal/rest/upload"
+	"github.com/kyzrfranz/go-fitter/internal/store"
+	"github.com/kyzrfranz/go-fitter/internal/tracing"
+	"github.com/kyzrfranz/go-fitter/internal/worker"
 )
 
-var (
-	logger     *slog.Logger
-	serverPort = 0
-)
+var logger *slog.Logger
 
 func main() {
+	command, cmdArgs := "serve", os.Args[1:]
+	if len(cmdArgs) > 0 && !strings.HasPrefix(cmdArgs[0], "-") {
+		command, cmdArgs = cmdArgs[0], cmdArgs[1:]
+	}
 
-	flag.IntVar(&serverPort, "port", args.EnvOrDefault[int]("SERVER_PORT", 8080), "Port for the API server")
+	switch command {
+	case "serve":
+		os.Exit(serve(cmdArgs))
+	case "convert":
+		os.Exit(cli.Convert(cmdArgs, os.Stdin, os.Stdout, os.Stderr))
+	case "inspect":
+		os.Exit(cli.Inspect(cmdArgs, os.Stdin, os.Stdout, os.Stderr))
+	case "validate":
+		os.Exit(cli.Validate(cmdArgs, os.Stdin, os.Stdout, os.Stderr))
+	case "watch":
+		os.Exit(cli.Watch(cmdArgs, os.Stdout, os.Stderr))
+	case "apikey":
+		os.Exit(cli.APIKey(cmdArgs, os.Stdout, os.Stderr))
+	case "dump-config":
+		os.Exit(cli.DumpConfig(cmdArgs, os.Stdout, os.Stderr))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\nUsage: go-fitter [serve|convert|inspect|validate|watch|apikey|dump-config] [flags]\n", command)
+		os.Exit(cli.ExitUsage)
+	}
+}
 
-	flag.Parse()
+func serve(cmdArgs []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	cfg, err := cli.ServeConfig(fs, cmdArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitUsage
+	}
 
 	logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	apiServer := http.NewApiServer(serverPort, logger)
 
-	apiServer.Use(http.MiddlewareRecovery)
-	apiServer.Use(http.MiddlewareCORS)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.ConfigFromEnv())
+	if err != nil {
+		logger.Error("could not set up tracing", slog.String("error", err.Error()))
+		return cli.ExitFailure
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("could not flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	apiServer := http.NewApiServer(cfg.Server.Port, logger)
+	apiServer.SetShutdownDelay(time.Duration(cfg.Server.ShutdownDelay))
+	apiServer.SetCORSPolicy(cfg.CORSPolicy())
+	if err := apiServer.SetTLS(cfg.TLSConfig()); err != nil {
+		logger.Error("could not set up TLS", slog.String("error", err.Error()))
+		return cli.ExitFailure
+	}
+
+	apiServer.Use(http.MiddlewareRequestID(logger))
+	apiServer.Use(http.MiddlewareRecovery(logger))
+	apiServer.Use(http.MiddlewareTracing)
+	apiServer.Use(http.MiddlewareCORS(cfg.CORSPolicy()))
 	apiServer.Use(http.MiddlewareLogging(logger))
+	apiServer.Use(http.MiddlewareMetrics)
+	apiServer.Use(http.MiddlewareCompress(cfg.CompressionPolicy()))
+
+	limiter, err := ratelimit.New(cfg.RateLimit(), logger)
+	if err != nil {
+		logger.Error("could not set up rate limiting", slog.String("error", err.Error()))
+		return cli.ExitFailure
+	}
+	defer func() {
+		if err := limiter.Close(); err != nil {
+			logger.Error("could not save rate limit state", slog.String("error", err.Error()))
+		}
+	}()
 
-	setupHandlers(apiServer)
+	if err := setupHandlers(apiServer, limiter, cfg); err != nil {
+		logger.Error("could not set up handlers", slog.String("error", err.Error()))
+		return cli.ExitFailure
+	}
 
 	apiServer.Start()
+	return cli.ExitOK
 }
 
-func setupHandlers(apiServer *http.ApiServer) {
-	handler := rest.NewHandler(logger)
+func setupHandlers(apiServer *http.ApiServer, limiter *ratelimit.Limiter, cfg config.Config) error {
+	repo, err := store.NewFileStore(cfg.Storage.DataDir)
+	if err != nil {
+		return err
+	}
+	policy, err := cfg.PrivacyPolicy()
+	if err != nil {
+		return err
+	}
+
+	authenticator, err := auth.New(cfg.AuthConfig())
+	if err != nil {
+		return err
+	}
+	if !authenticator.Enabled() {
+		logger.Warn("no API keys or JWT verification configured, all endpoints are open")
+	}
+	convert := authenticator.Require(auth.ScopeConvert)
+	storage := authenticator.Require(auth.ScopeStore)
+	admin := authenticator.Require(auth.ScopeAdmin)
+	guard, limit := limiter.LimitFailures(), limiter.Limit()
+	quota, uploadQuota := limiter.Quota(), limiter.UploadQuota()
+	size := upload.Limit(int64(cfg.Server.MaxUploadMB) << 20)
+
+	pool := worker.NewPool(cfg.Server.Workers)
+	handler := rest.NewHandler(logger, pool, repo, cfg.Athlete.Thresholds(), cfg.ProfileThresholds(), restFit.Defaults{
+		Records:        cfg.Convert.Records,
+		Degrees:        cfg.Convert.Degrees,
+		VerifyChecksum: cfg.Convert.VerifyChecksum,
+		Privacy:        policy,
+	})
+
+	apiServer.AddHealthHandlers()
+	apiServer.AddCheck("workers", http.PoolCheck(pool))
+
+	apiServer.AddHandler("/fit", handler.Fit, guard, convert, limit, size, quota)
+	apiServer.AddHandler("/fit/batch", handler.FitBatch, guard, convert, limit, size, quota)
+	apiServer.AddHandler("/fit/validate", handler.FitValidate, guard, convert, limit, size, quota)
+	apiServer.AddHandler("/fit/repair", handler.FitRepair, guard, convert, limit, size, quota)
+	apiServer.AddHandler("/fit/trim", handler.FitTrim, guard, convert, limit, size, quota)
+	apiServer.AddHandler("/fit/split", handler.FitSplit, guard, convert, limit, size, quota)
+	apiServer.AddHandler("/fit/merge", handler.FitMerge, guard, convert, limit, size, quota)
+	apiServer.AddHandler("/activities", handler.Activities, guard, storage, limit, size, uploadQuota)
+	apiServer.AddHandler("/activities/{id}", handler.Activity, guard, storage, limit)
+	apiServer.AddHandler("/activities/{id}/fit", handler.ActivityFIT, guard, storage, limit)
+	apiServer.AddHandler("/athletes/{id}/load", handler.AthleteLoad, guard, storage, limit)
+	apiServer.AddHandler("/metrics", metrics.Handler, guard, admin)
+	setupDocs(apiServer, cfg.Server.DocsDir)
+	return nil
+}
 
-	apiServer.AddHandler("/fit", handler.Fit)
+// setupDocs serves the OpenAPI document, the JSON Schema of converted activities it refers to and a
+// page rendering them from dir. Missing files only log a warning, the API works without them.
+func setupDocs(apiServer *http.ApiServer, dir string) {
+	if dir == "" {
+		return
+	}
+	files := []struct{ path, file, mimeType string }{
+		{"/openapi.yaml", "openapi.yaml", "application/yaml"},
+		{"/schemas/activity.schema.json", "schemas/activity.schema.json", "application/schema+json"},
+		{"/docs", "index.html", "text/html; charset=utf-8"},
+	}
+	for _, f := range files {
+		name := filepath.Join(dir, f.file)
+		if _, err := os.Stat(name); err != nil {
+			logger.Warn("API docs file missing, not serving it", slog.String("path", f.path), slog.String("error", err.Error()))
+			continue
+		}
+		apiServer.AddFileHandler(f.path, name, f.mimeType)
+	}
 }
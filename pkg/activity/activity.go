@@ -0,0 +1,34 @@
+package activity
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/filedef"
+)
+
+var ErrNoActivityData = errors.New("file contains no session or record messages")
+
+// Decode reads all FIT sequences from r into a single filedef.Activity.
+func Decode(r io.Reader, decoderOptions ...decoder.Option) (*filedef.Activity, error) {
+	dec := decoder.New(r, decoderOptions...)
+
+	act := filedef.NewActivity()
+	for dec.Next() {
+		fit, err := dec.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("decode failed: %w", err)
+		}
+		for i := range fit.Messages {
+			act.Add(fit.Messages[i])
+		}
+	}
+
+	if len(act.Sessions) == 0 && len(act.Records) == 0 {
+		return nil, ErrNoActivityData
+	}
+
+	return act, nil
+}
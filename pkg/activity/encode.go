@@ -0,0 +1,26 @@
+package activity
+
+import (
+	"slices"
+
+	"github.com/muktihari/fit/encoder"
+	"github.com/muktihari/fit/proto"
+)
+
+// Encode encodes fit with enc after dropping the messages the encoder would refuse, those without a single
+// valid field. Decoders happily produce them, e.g. for a message whose fields are all unset.
+func Encode(enc *encoder.Encoder, fit *proto.FIT) error {
+	fit.Messages = slices.DeleteFunc(fit.Messages, func(m proto.Message) bool {
+		if len(m.DeveloperFields) > 0 {
+			return false
+		}
+		for i := range m.Fields {
+			f := &m.Fields[i]
+			if f.FieldBase != nil && !f.IsExpandedField && f.Value.Valid(f.BaseType) {
+				return false
+			}
+		}
+		return true
+	})
+	return enc.Encode(fit)
+}
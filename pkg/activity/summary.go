@@ -0,0 +1,139 @@
+package activity
+
+import (
+	"math"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// Summary holds the headline numbers of an activity, taken from its sessions and records.
+type Summary struct {
+	Sport        string    `json:"sport"`
+	SubSport     string    `json:"sub_sport,omitempty"`
+	StartTime    time.Time `json:"start_time"`
+	Duration     float64   `json:"duration"` // timer time in seconds
+	Elapsed      float64   `json:"elapsed"`  // elapsed time in seconds
+	Distance     float64   `json:"distance"` // metres
+	Calories     int       `json:"calories,omitempty"`
+	AvgHeartRate float64   `json:"avg_heart_rate,omitempty"`
+	MaxHeartRate int       `json:"max_heart_rate,omitempty"`
+	AvgPower     float64   `json:"avg_power,omitempty"`
+	MaxPower     int       `json:"max_power,omitempty"`
+
+	NormalizedPower float64 `json:"normalized_power,omitempty"`
+}
+
+// Summarize builds a Summary for act. Session totals win over values derived from records.
+func Summarize(act *filedef.Activity) Summary {
+	var s Summary
+
+	for i, ses := range act.Sessions {
+		if i == 0 {
+			s.Sport = ses.Sport.String()
+			if ses.SubSport != typedef.SubSportInvalid && ses.SubSport != typedef.SubSportGeneric {
+				s.SubSport = ses.SubSport.String()
+			}
+			s.StartTime = ses.StartTime
+		} else if s.Sport != ses.Sport.String() {
+			s.Sport = typedef.SportMultisport.String()
+			s.SubSport = ""
+		}
+		s.Duration += validFloat(ses.TotalTimerTimeScaled())
+		s.Elapsed += validFloat(ses.TotalElapsedTimeScaled())
+		s.Distance += validFloat(ses.TotalDistanceScaled())
+		if ses.TotalCalories != basetype.Uint16Invalid {
+			s.Calories += int(ses.TotalCalories)
+		}
+	}
+
+	var hrSum, powerSum float64
+	var hrCount, powerCount int
+	for _, rec := range act.Records {
+		if rec.HeartRate != basetype.Uint8Invalid {
+			hrSum += float64(rec.HeartRate)
+			hrCount++
+			s.MaxHeartRate = max(s.MaxHeartRate, int(rec.HeartRate))
+		}
+		if rec.Power != basetype.Uint16Invalid {
+			powerSum += float64(rec.Power)
+			powerCount++
+			s.MaxPower = max(s.MaxPower, int(rec.Power))
+		}
+	}
+	if hrCount > 0 {
+		s.AvgHeartRate = hrSum / float64(hrCount)
+	}
+	if powerCount > 0 {
+		s.AvgPower = powerSum / float64(powerCount)
+		s.NormalizedPower = NormalizedPower(act.Records)
+	}
+
+	if len(act.Sessions) == 0 && len(act.Records) > 0 {
+		first, last := act.Records[0], act.Records[len(act.Records)-1]
+		s.Sport = typedef.SportGeneric.String()
+		s.StartTime = first.Timestamp
+		s.Elapsed = last.Timestamp.Sub(first.Timestamp).Seconds()
+		s.Duration = s.Elapsed
+		s.Distance = validFloat(last.DistanceScaled())
+	}
+
+	if s.Duration == 0 {
+		s.Duration = s.Elapsed
+	}
+	if s.StartTime.IsZero() && !act.FileId.TimeCreated.IsZero() {
+		s.StartTime = act.FileId.TimeCreated
+	}
+
+	return s
+}
+
+// NormalizedPower is the fourth root of the mean of the fourth powers of the 30 second rolling average power.
+func NormalizedPower(records []*mesgdef.Record) float64 {
+	const window = 30 * time.Second
+
+	var (
+		start      int
+		windowSum  float64
+		windowSize int
+		sum4       float64
+		count      int
+	)
+	for i, rec := range records {
+		if rec.Power == basetype.Uint16Invalid {
+			continue
+		}
+		windowSum += float64(rec.Power)
+		windowSize++
+
+		for start < i && rec.Timestamp.Sub(records[start].Timestamp) >= window {
+			if records[start].Power != basetype.Uint16Invalid {
+				windowSum -= float64(records[start].Power)
+				windowSize--
+			}
+			start++
+		}
+
+		if rec.Timestamp.Sub(records[0].Timestamp) < window-time.Second {
+			continue // rolling window not filled yet
+		}
+		avg := windowSum / float64(windowSize)
+		sum4 += avg * avg * avg * avg
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return math.Pow(sum4/float64(count), 0.25)
+}
+
+// validFloat maps the NaN the mesgdef *Scaled helpers return for invalid values to zero.
+func validFloat(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,232 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	// MaxEntrySize is the largest uncompressed entry we are willing to extract.
+	MaxEntrySize = 64 << 20
+	// MaxTotalSize is the most an upload may decompress to, all entries together.
+	MaxTotalSize = 256 << 20
+	// MaxEntries is the most entries an archive may have, FIT files or not.
+	MaxEntries = 1000
+)
+
+var (
+	ErrEntryTooLarge   = errors.New("archive entry too large")
+	ErrArchiveTooLarge = errors.New("archive too large when decompressed")
+	ErrTooManyEntries  = errors.New("archive has too many entries")
+	ErrNoFITEntries    = errors.New("archive contains no .fit files")
+)
+
+// Kind is the container format of a payload, detected by its magic bytes.
+type Kind int
+
+const (
+	KindRaw Kind = iota
+	KindGzip
+	KindTarGzip
+	KindZip
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindGzip:
+		return "gzip"
+	case KindTarGzip:
+		return "tar.gz"
+	case KindZip:
+		return "zip"
+	default:
+		return "raw"
+	}
+}
+
+// Entry is a single file taken out of an upload.
+type Entry struct {
+	Name string
+	Data []byte
+}
+
+// Detect returns the container format of data. A gzip stream is reported as
+// KindTarGzip when the decompressed content starts with a tar header.
+func Detect(data []byte) Kind {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return KindZip
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return KindGzip
+		}
+		defer zr.Close()
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(zr, head)
+		if n >= 262 && string(head[257:262]) == "ustar" {
+			return KindTarGzip
+		}
+		return KindGzip
+	default:
+		return KindRaw
+	}
+}
+
+// Extract unpacks data according to its detected Kind. Archives only yield their .fit entries,
+// a plain gzip stream yields one entry named after name without the .gz suffix and anything
+// else is returned as is. Archives with more than MaxEntries entries, or decompressing to more
+// than MaxTotalSize bytes, are rejected.
+func Extract(name string, data []byte) ([]Entry, error) {
+	left := newBudget()
+	switch Detect(data) {
+	case KindZip:
+		return extractZip(data, left)
+	case KindTarGzip:
+		return extractTarGzip(data, left)
+	case KindGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer zr.Close()
+		b, err := left.read(zr)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return []Entry{{Name: strings.TrimSuffix(name, ".gz"), Data: b}}, nil
+	default:
+		return []Entry{{Name: name, Data: data}}, nil
+	}
+}
+
+// Unpack returns the FIT data inside data. The FIT files of an archive are chained into one
+// stream, in archive order, so they decode as consecutive sequences.
+func Unpack(name string, data []byte) ([]byte, error) {
+	entries, err := Extract(name, data)
+	if err != nil {
+		return nil, err
+	}
+	switch len(entries) {
+	case 0:
+		return nil, ErrNoFITEntries
+	case 1:
+		return entries[0].Data, nil
+	}
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		buf.Write(e.Data)
+	}
+	return buf.Bytes(), nil
+}
+
+func extractZip(data []byte, left *budget) ([]Entry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("zip: %w", err)
+	}
+	if len(zr.File) > MaxEntries {
+		return nil, ErrTooManyEntries
+	}
+	var entries []Entry
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !isFitName(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("zip %s: %w", f.Name, err)
+		}
+		b, err := left.read(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("zip %s: %w", f.Name, err)
+		}
+		entries = append(entries, Entry{Name: f.Name, Data: b})
+	}
+	return entries, nil
+}
+
+func extractTarGzip(data []byte, left *budget) ([]Entry, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+	defer zr.Close()
+
+	var entries []Entry
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tar: %w", err)
+		}
+		if err := left.entry(); err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !isFitName(hdr.Name) {
+			continue
+		}
+		b, err := left.read(tr)
+		if err != nil {
+			return nil, fmt.Errorf("tar %s: %w", hdr.Name, err)
+		}
+		entries = append(entries, Entry{Name: hdr.Name, Data: b})
+	}
+	return entries, nil
+}
+
+// isFitName skips everything that is not a FIT file, including the resource forks macOS adds to zips.
+func isFitName(name string) bool {
+	base := path.Base(name)
+	if strings.HasPrefix(base, "._") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	return strings.EqualFold(path.Ext(base), ".fit")
+}
+
+// budget is what is left of the limits of one archive while it is extracted.
+type budget struct {
+	bytes   int64
+	entries int
+}
+
+func newBudget() *budget {
+	return &budget{bytes: MaxTotalSize, entries: MaxEntries}
+}
+
+// entry counts an entry of the archive.
+func (b *budget) entry() error {
+	if b.entries--; b.entries < 0 {
+		return ErrTooManyEntries
+	}
+	return nil
+}
+
+// read reads an entry, failing once it exceeds MaxEntrySize or the bytes left.
+func (b *budget) read(r io.Reader) ([]byte, error) {
+	limit := min(MaxEntrySize, b.bytes)
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		if limit < MaxEntrySize {
+			return nil, ErrArchiveTooLarge
+		}
+		return nil, ErrEntryTooLarge
+	}
+	b.bytes -= int64(len(data))
+	return data, nil
+}
@@ -0,0 +1,207 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type file struct {
+	name string
+	data string
+}
+
+func zipOf(t *testing.T, files ...file) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzipOf(t *testing.T, files ...file) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipOf(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func manyFiles(n int) []file {
+	files := make([]file, n)
+	for i := range files {
+		files[i] = file{name: fmt.Sprintf("%04d.fit", i)}
+	}
+	return files
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    []byte
+		kind    Kind
+		want    []Entry
+		wantErr error
+	}{
+		{
+			name: "raw",
+			file: "a.fit",
+			data: []byte("fit"),
+			kind: KindRaw,
+			want: []Entry{{Name: "a.fit", Data: []byte("fit")}},
+		},
+		{
+			name: "gzip",
+			file: "a.fit.gz",
+			data: gzipOf(t, "fit"),
+			kind: KindGzip,
+			want: []Entry{{Name: "a.fit", Data: []byte("fit")}},
+		},
+		{
+			name: "zip keeps only fit files in order",
+			file: "a.zip",
+			data: zipOf(t, file{"b.FIT", "b"}, file{"notes.txt", "x"}, file{"__MACOSX/._a.fit", "x"}, file{"dir/a.fit", "a"}),
+			kind: KindZip,
+			want: []Entry{{Name: "b.FIT", Data: []byte("b")}, {Name: "dir/a.fit", Data: []byte("a")}},
+		},
+		{
+			name: "tar.gz",
+			file: "a.tar.gz",
+			data: tarGzipOf(t, file{"a.fit", "a"}, file{"._a.fit", "x"}),
+			kind: KindTarGzip,
+			want: []Entry{{Name: "a.fit", Data: []byte("a")}},
+		},
+		{
+			name:    "zip with too many entries",
+			file:    "a.zip",
+			data:    zipOf(t, manyFiles(MaxEntries+1)...),
+			kind:    KindZip,
+			wantErr: ErrTooManyEntries,
+		},
+		{
+			name:    "tar.gz with too many entries",
+			file:    "a.tar.gz",
+			data:    tarGzipOf(t, manyFiles(MaxEntries+1)...),
+			kind:    KindTarGzip,
+			wantErr: ErrTooManyEntries,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := Detect(tt.data); kind != tt.kind {
+				t.Errorf("Detect() = %v, want %v", kind, tt.kind)
+			}
+			got, err := Extract(tt.file, tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Extract() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Extract() = %d entries, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].Name != tt.want[i].Name || !bytes.Equal(got[i].Data, tt.want[i].Data) {
+					t.Errorf("entry %d = %s %q, want %s %q", i, got[i].Name, got[i].Data, tt.want[i].Name, tt.want[i].Data)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{name: "single file", data: []byte("fit"), want: "fit"},
+		{name: "archive entries are chained", data: zipOf(t, file{"a.fit", "a"}, file{"b.fit", "b"}), want: "ab"},
+		{name: "archive without fit files", data: zipOf(t, file{"a.txt", "a"}), wantErr: ErrNoFITEntries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unpack("upload", tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unpack() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Unpack() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  budget
+		reads   []string
+		wantErr error
+	}{
+		{name: "within budget", budget: budget{bytes: 10, entries: 2}, reads: []string{"12345", "12345"}},
+		{name: "total exceeded", budget: budget{bytes: 10, entries: 2}, reads: []string{"12345", "123456"}, wantErr: ErrArchiveTooLarge},
+		{name: "entries exceeded", budget: budget{bytes: 10, entries: 1}, reads: []string{"1", "2"}, wantErr: ErrTooManyEntries},
+		{name: "entry exceeded", budget: budget{bytes: MaxTotalSize, entries: 1}, reads: []string{strings.Repeat("x", MaxEntrySize+1)}, wantErr: ErrEntryTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.budget
+			var err error
+			for _, r := range tt.reads {
+				if err = b.entry(); err != nil {
+					break
+				}
+				if _, err = b.read(strings.NewReader(r)); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,17 +1,62 @@
 package converters
 
 import (
-	"fmt"
+	"context"
 	"io"
 
+	cCsv "github.com/kyzrfranz/go-fitter/pkg/converters/csv"
+	cGeojson "github.com/kyzrfranz/go-fitter/pkg/converters/geojson"
+	cGpx "github.com/kyzrfranz/go-fitter/pkg/converters/gpx"
 	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
+	cTcx "github.com/kyzrfranz/go-fitter/pkg/converters/tcx"
 	"github.com/muktihari/fit/decoder"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+var tracer = otel.Tracer("github.com/kyzrfranz/go-fitter/pkg/converters")
+
+// converter is what every output format implements: a decoder listener that renders its result once Wait returns.
+// NextSection tells it that the messages that follow belong to the next FIT sequence of a chained file.
+type converter interface {
+	decoder.MesgDefListener
+	decoder.MesgListener
+	NextSection()
+	Wait()
+	Err() error
+	Result() string
+}
+
 func FitToJson(ff io.Reader, decoderOptions []decoder.Option, opts ...cJson.Option) (string, error) {
+	return FitToJsonContext(context.Background(), ff, decoderOptions, opts...)
+}
+
+// FitToJsonContext is FitToJson with the decode, enrichment and marshal spans as children of the span in ctx.
+func FitToJsonContext(ctx context.Context, ff io.Reader, decoderOptions []decoder.Option, opts ...cJson.Option) (string, error) {
+	opts = append(opts, cJson.WithContext(ctx))
 	// We don't need a bufio.Writer, json.Marshal writes it all at once at the end
-	conv := cJson.NewFITToJSONConv(opts...) // Use the new converter
+	return convert(ctx, ff, FormatJSON, decoderOptions, cJson.NewFITToJSONConv(opts...))
+}
+
+func FitToGpx(ff io.Reader, decoderOptions []decoder.Option, opts ...cGpx.Option) (string, error) {
+	return convert(context.Background(), ff, FormatGPX, decoderOptions, cGpx.NewFITToGPXConv(opts...))
+}
 
+func FitToTcx(ff io.Reader, decoderOptions []decoder.Option, opts ...cTcx.Option) (string, error) {
+	return convert(context.Background(), ff, FormatTCX, decoderOptions, cTcx.NewFITToTCXConv(opts...))
+}
+
+func FitToGeoJson(ff io.Reader, decoderOptions []decoder.Option, opts ...cGeojson.Option) (string, error) {
+	return convert(context.Background(), ff, FormatGeoJSON, decoderOptions, cGeojson.NewFITToGeoJSONConv(opts...))
+}
+
+func FitToCsv(ff io.Reader, decoderOptions []decoder.Option, opts ...cCsv.Option) (string, error) {
+	return convert(context.Background(), ff, FormatCSV, decoderOptions, cCsv.NewFITToCSVConv(opts...))
+}
+
+// convert runs conv over ff. Failures are a *DecodeError or a *ConvertError.
+func convert(ctx context.Context, ff io.Reader, format Format, decoderOptions []decoder.Option, conv converter) (string, error) {
 	options := []decoder.Option{
 		decoder.WithMesgDefListener(conv),
 		decoder.WithMesgListener(conv),
@@ -21,22 +66,32 @@ func FitToJson(ff io.Reader, decoderOptions []decoder.Option, opts ...cJson.Opti
 	options = append(options, decoderOptions...)
 	dec := decoder.New(ff, options...)
 
+	_, span := tracer.Start(ctx, "fit.decode")
 	var err error
-	for dec.Next() {
+	seq := 0
+	for ; dec.Next(); seq++ {
+		if seq > 0 {
+			conv.NextSection()
+		}
 		_, err = dec.Decode()
 		if err != nil {
 			break
 		}
 	}
+	span.SetAttributes(attribute.Int("fit.sequences", seq))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 
-	conv.Wait() // This is where the JSON is marshaled and written
+	conv.Wait() // This is where the result is marshaled
 
 	if err != nil {
-		return "", fmt.Errorf("decode failed: %w", err)
+		return "", &DecodeError{Sequence: seq, Err: err}
 	}
 
 	if err := conv.Err(); err != nil {
-		return "", fmt.Errorf("convert done with error: %v", err)
+		return "", &ConvertError{Format: format, Err: err}
 	}
 
 	result := conv.Result()
@@ -0,0 +1,96 @@
+package converters
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+const activitySchema = "../../docs/schemas/activity.schema.json"
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func compileSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	schema, err := jsonschema.NewCompiler().Compile(activitySchema)
+	if err != nil {
+		t.Fatalf("compile %s: %v", activitySchema, err)
+	}
+	return schema
+}
+
+func TestFitToJsonMatchesSchema(t *testing.T) {
+	schema := compileSchema(t)
+
+	chained := append(readFile(t, "activity_multisport.fit"), readFile(t, "activity_poolswim.fit")...)
+	tests := []struct {
+		name string
+		data []byte
+		opts []cJson.Option
+	}{
+		{name: "multisport activity", data: readFile(t, "activity_multisport.fit")},
+		{name: "pool swim", data: readFile(t, "activity_poolswim.fit")},
+		{name: "developer fields", data: readFile(t, "DeveloperData.fit")},
+		{name: "workout file", data: readFile(t, "WorkoutIndividualSteps.fit")},
+		{name: "chained file", data: chained},
+		{name: "degrees and valid values only", data: readFile(t, "activity_multisport.fit"),
+			opts: []cJson.Option{cJson.WithPrintGPSPositionInDegrees(), cJson.WithPrintOnlyValidValue()}},
+		{name: "without records", data: readFile(t, "activity_poolswim.fit"), opts: []cJson.Option{cJson.WithNoRecords()}},
+		{name: "compact", data: readFile(t, "activity_poolswim.fit"), opts: []cJson.Option{cJson.WithPrettyPrint(false)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := FitToJson(bytes.NewReader(tt.data), nil, tt.opts...)
+			if err != nil {
+				t.Fatalf("FitToJson() error = %v", err)
+			}
+			doc, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(out)))
+			if err != nil {
+				t.Fatalf("FitToJson() is not JSON: %v", err)
+			}
+			if err := schema.Validate(doc); err != nil {
+				t.Errorf("FitToJson() does not match the schema: %v", err)
+			}
+		})
+	}
+}
+
+func TestFitToJsonSections(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		wantSections int
+	}{
+		{name: "single sequence", data: readFile(t, "activity_poolswim.fit"), wantSections: 0},
+		{name: "chained file", data: append(readFile(t, "activity_multisport.fit"), readFile(t, "activity_poolswim.fit")...), wantSections: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := FitToJson(bytes.NewReader(tt.data), nil)
+			if err != nil {
+				t.Fatalf("FitToJson() error = %v", err)
+			}
+			var doc struct {
+				Sections []json.RawMessage `json:"sections"`
+			}
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if len(doc.Sections) != tt.wantSections {
+				t.Errorf("FitToJson() = %d sections, want %d", len(doc.Sections), tt.wantSections)
+			}
+		})
+	}
+}
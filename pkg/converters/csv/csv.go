@@ -0,0 +1,266 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/kit/datetime"
+	"github.com/muktihari/fit/kit/scaleoffset"
+	"github.com/muktihari/fit/kit/semicircles"
+	"github.com/muktihari/fit/profile"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+	"github.com/muktihari/fit/proto"
+)
+
+var (
+	_ decoder.MesgDefListener = &Converter{}
+	_ decoder.MesgListener    = &Converter{}
+)
+
+// Converter is an implementation for listeners that receive message events and convert the records into a CSV table,
+// one row per record and one column per field seen in any record.
+type Converter struct {
+	err error // Error occurred while receiving messages.
+
+	options *options
+
+	fieldDescriptions []*mesgdef.FieldDescription
+
+	columns []string
+	rows    []map[string]string
+	section int // Index of the FIT sequence being decoded, a chained file has more than one
+
+	mesgc chan any      // This buffered event channel can accept either proto.Message or proto.MessageDefinition maintaining the order of arrival.
+	done  chan struct{} // Tells that all messages have been completely processed.
+
+	result string
+}
+
+type options struct {
+	channelBufferSize         int
+	delimiter                 rune // Field delimiter
+	printGPSPositionInDegrees bool // Print latitude and longitude in degrees instead of semicircles.
+}
+
+// NewFITToCSVConv creates a new FIT to CSV converter.
+func NewFITToCSVConv(opts ...Option) *Converter {
+	options := defaultOptions()
+	for i := range opts {
+		opts[i](options)
+	}
+
+	c := &Converter{
+		options: options,
+		rows:    make([]map[string]string, 0),
+		mesgc:   make(chan any, options.channelBufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go c.handleEvent() // spawn only once.
+
+	return c
+}
+
+// Err returns any error that occur during processing events.
+func (c *Converter) Err() error { return c.err }
+
+// OnMesgDef receive message definition from broadcaster
+func (c *Converter) OnMesgDef(mesgDef proto.MessageDefinition) { c.mesgc <- mesgDef }
+
+// OnMesg receive message from broadcaster
+func (c *Converter) OnMesg(mesg proto.Message) { c.mesgc <- mesg }
+
+// sectionBreak separates the messages of two sequences of a chained file in the event channel.
+type sectionBreak struct{}
+
+// NextSection tells the converter that the following messages belong to the next FIT sequence.
+// The rows of a chained file get a leading sequence column.
+func (c *Converter) NextSection() { c.mesgc <- sectionBreak{} }
+
+// handleEvent processes events from a buffered channel.
+func (c *Converter) handleEvent() {
+	for event := range c.mesgc {
+		switch event := event.(type) {
+		case proto.Message:
+			c.processMessage(event)
+		case sectionBreak:
+			c.section++
+			c.fieldDescriptions = nil
+		}
+	}
+	close(c.done)
+}
+
+func (c *Converter) processMessage(mesg proto.Message) {
+	switch mesg.Num {
+	case mesgnum.FieldDescription:
+		c.fieldDescriptions = append(c.fieldDescriptions, mesgdef.NewFieldDescription(&mesg))
+	case mesgnum.Record:
+		if row := c.buildRow(mesg); len(row) > 0 {
+			row[sequenceColumn] = strconv.Itoa(c.section)
+			c.rows = append(c.rows, row)
+		}
+	}
+}
+
+// sequenceColumn holds the index of the FIT sequence a row comes from, only written for chained files.
+const sequenceColumn = "sequence"
+
+func (c *Converter) buildRow(mesg proto.Message) map[string]string {
+	row := make(map[string]string)
+
+	for i := range mesg.Fields {
+		field := &mesg.Fields[i]
+		if field.IsExpandedField || !field.Value.Valid(field.BaseType) {
+			continue
+		}
+
+		var s string
+		switch {
+		case field.Type == profile.DateTime || field.Type == profile.LocalDateTime:
+			s = datetime.ToTime(field.Value.Uint32()).Format(time.RFC3339)
+		case c.options.printGPSPositionInDegrees && field.Units == "semicircles":
+			s = formatFloat(semicircles.ToDegrees(field.Value.Int32()))
+		default:
+			s = formatValue(scaleoffset.ApplyValue(field.Value, field.Scale, field.Offset).Any())
+		}
+		if s == "" {
+			continue
+		}
+		c.addColumn(field.Name)
+		row[field.Name] = s
+	}
+
+	for i := range mesg.DeveloperFields {
+		devField := &mesg.DeveloperFields[i]
+		fieldDesc := c.getFieldDescription(devField.DeveloperDataIndex, devField.Num)
+		if fieldDesc == nil {
+			continue
+		}
+		s := formatValue(devField.Value.Any())
+		if s == "" {
+			continue
+		}
+		name := strings.Join(fieldDesc.FieldName, "|")
+		c.addColumn(name)
+		row[name] = s
+	}
+
+	return row
+}
+
+func (c *Converter) addColumn(name string) {
+	if !slices.Contains(c.columns, name) {
+		c.columns = append(c.columns, name)
+	}
+}
+
+// Wait closes the buffered channel and waits until all event handling is completed
+// and then writes the final CSV.
+func (c *Converter) Wait() {
+	close(c.mesgc)
+	<-c.done
+	c.result = c.marshal()
+}
+
+func (c *Converter) Result() string {
+	return c.result
+}
+
+func (c *Converter) marshal() string {
+	if c.err != nil {
+		return ""
+	}
+
+	// timestamp always comes first, the rest in the order they were first seen
+	if i := slices.Index(c.columns, "timestamp"); i > 0 {
+		c.columns = slices.Insert(slices.Delete(c.columns, i, i+1), 0, "timestamp")
+	}
+	if c.section > 0 {
+		c.columns = slices.Insert(c.columns, 0, sequenceColumn)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = c.options.delimiter
+
+	_ = w.Write(c.columns)
+	record := make([]string, len(c.columns))
+	for _, row := range c.rows {
+		for i, col := range c.columns {
+			record[i] = row[col]
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		c.err = fmt.Errorf("write csv: %w", err)
+		return ""
+	}
+
+	return buf.String()
+}
+
+// getFieldDescription finds the matching FieldDescription for a developer field.
+func (c *Converter) getFieldDescription(developerDataIndex, fieldDefinitionNumber uint8) *mesgdef.FieldDescription {
+	for _, fieldDesc := range c.fieldDescriptions {
+		if fieldDesc.DeveloperDataIndex == developerDataIndex &&
+			fieldDesc.FieldDefinitionNumber == fieldDefinitionNumber {
+			return fieldDesc
+		}
+	}
+	return nil
+}
+
+// formatValue renders single values; arrays are joined with '|' so they stay in one cell.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return formatFloat(v)
+	case float32:
+		return formatFloat(float64(v))
+	case string:
+		return v
+	case []uint8:
+		return joinSlice(v)
+	case []int8:
+		return joinSlice(v)
+	case []uint16:
+		return joinSlice(v)
+	case []int16:
+		return joinSlice(v)
+	case []uint32:
+		return joinSlice(v)
+	case []int32:
+		return joinSlice(v)
+	case []float64:
+		return joinSlice(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatFloat(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func joinSlice[T any](s []T) string {
+	parts := make([]string, len(s))
+	for i, v := range s {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,28 @@
+package csv
+
+// Option is Converter's option.
+type Option func(o *options)
+
+func defaultOptions() *options {
+	return &options{
+		channelBufferSize:         1000,
+		delimiter:                 ',',
+		printGPSPositionInDegrees: false,
+	}
+}
+
+func WithChannelBufferSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.channelBufferSize = size
+		}
+	}
+}
+
+func WithDelimiter(delimiter rune) Option {
+	return func(o *options) { o.delimiter = delimiter }
+}
+
+func WithPrintGPSPositionInDegrees() Option {
+	return func(o *options) { o.printGPSPositionInDegrees = true }
+}
@@ -0,0 +1,40 @@
+package converters
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/muktihari/fit/decoder"
+)
+
+// ErrChecksumMismatch matches, with errors.Is, a DecodeError caused by a file whose CRC doesn't match its
+// contents. Decoding with decoder.WithIgnoreChecksum never fails with it.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// DecodeError is returned when the input isn't a readable FIT file.
+type DecodeError struct {
+	Sequence int // FIT sequence of a chained file the error occurred in, 0 for the first
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode failed: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrChecksumMismatch && errors.Is(e.Err, decoder.ErrCRCChecksumMismatch)
+}
+
+// ConvertError is returned when a decoded file can't be rendered in the output format.
+type ConvertError struct {
+	Format Format
+	Err    error
+}
+
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("convert to %s failed: %v", e.Format, e.Err)
+}
+
+func (e *ConvertError) Unwrap() error { return e.Err }
@@ -0,0 +1,123 @@
+package converters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	cCsv "github.com/kyzrfranz/go-fitter/pkg/converters/csv"
+	cGeojson "github.com/kyzrfranz/go-fitter/pkg/converters/geojson"
+	cGpx "github.com/kyzrfranz/go-fitter/pkg/converters/gpx"
+	cJson "github.com/kyzrfranz/go-fitter/pkg/converters/json"
+	cTcx "github.com/kyzrfranz/go-fitter/pkg/converters/tcx"
+	"github.com/kyzrfranz/go-fitter/pkg/privacy"
+	"github.com/muktihari/fit/decoder"
+)
+
+// Format is an output format Convert can produce.
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatNDJSON  Format = "ndjson"
+	FormatGPX     Format = "gpx"
+	FormatTCX     Format = "tcx"
+	FormatCSV     Format = "csv"
+	FormatGeoJSON Format = "geojson"
+)
+
+var formats = []Format{FormatJSON, FormatNDJSON, FormatGPX, FormatTCX, FormatCSV, FormatGeoJSON}
+
+var mediaTypes = map[Format]string{
+	FormatJSON:    "application/json",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatGPX:     "application/gpx+xml",
+	FormatTCX:     "application/vnd.garmin.tcx+xml",
+	FormatCSV:     "text/csv",
+	FormatGeoJSON: "application/geo+json",
+}
+
+// Formats returns every format Convert can produce.
+func Formats() []Format {
+	return slices.Clone(formats)
+}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+// MediaType returns the media type of f, e.g. application/gpx+xml.
+func (f Format) MediaType() string {
+	return mediaTypes[f]
+}
+
+// Extension returns the file extension for f, including the dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Options are the format independent settings of Convert. Each one is applied where the format supports it.
+type Options struct {
+	Name    string       // track name (gpx, geojson)
+	Records bool         // include records (json)
+	Degrees bool         // positions in degrees instead of semicircles (json, ndjson, csv)
+	Pretty  bool         // indent the output (json, gpx, tcx, geojson)
+	Logger  *slog.Logger // debug output of the conversion (json, ndjson)
+
+	Privacy privacy.Policy // positions to hide (all formats)
+}
+
+// Convert converts ff into format.
+func Convert(ff io.Reader, format Format, decoderOptions []decoder.Option, opts Options) (string, error) {
+	return ConvertContext(context.Background(), ff, format, decoderOptions, opts)
+}
+
+// ConvertContext is Convert with the spans of the conversion as children of the span in ctx.
+func ConvertContext(ctx context.Context, ff io.Reader, format Format, decoderOptions []decoder.Option, opts Options) (string, error) {
+	if !opts.Privacy.Empty() {
+		redacted, err := privacy.Redact(ff, opts.Privacy, decoderOptions...)
+		if err != nil {
+			return "", err
+		}
+		ff = bytes.NewReader(redacted)
+	}
+
+	var conv converter
+	switch format {
+	case FormatGPX:
+		conv = cGpx.NewFITToGPXConv(cGpx.WithName(opts.Name), cGpx.WithPrettyPrint(opts.Pretty))
+	case FormatTCX:
+		conv = cTcx.NewFITToTCXConv(cTcx.WithPrettyPrint(opts.Pretty))
+	case FormatGeoJSON:
+		conv = cGeojson.NewFITToGeoJSONConv(cGeojson.WithName(opts.Name), cGeojson.WithPrettyPrint(opts.Pretty))
+	case FormatCSV:
+		var csvOpts []cCsv.Option
+		if opts.Degrees {
+			csvOpts = append(csvOpts, cCsv.WithPrintGPSPositionInDegrees())
+		}
+		conv = cCsv.NewFITToCSVConv(csvOpts...)
+	case FormatJSON, FormatNDJSON:
+		jsonOpts := []cJson.Option{cJson.WithPrettyPrint(opts.Pretty), cJson.WithContext(ctx), cJson.WithLogger(opts.Logger)}
+		if !opts.Records {
+			jsonOpts = append(jsonOpts, cJson.WithNoRecords())
+		}
+		if opts.Degrees {
+			jsonOpts = append(jsonOpts, cJson.WithPrintGPSPositionInDegrees())
+		}
+		if format == FormatNDJSON {
+			jsonOpts = append(jsonOpts, cJson.WithNDJSON())
+		}
+		conv = cJson.NewFITToJSONConv(jsonOpts...)
+	default:
+		return "", fmt.Errorf("unknown format %q", format)
+	}
+	return convert(ctx, ff, format, decoderOptions, conv)
+}
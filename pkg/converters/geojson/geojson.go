@@ -0,0 +1,202 @@
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+	"github.com/muktihari/fit/proto"
+)
+
+var (
+	_ decoder.MesgDefListener = &Converter{}
+	_ decoder.MesgListener    = &Converter{}
+)
+
+// Converter is an implementation for listeners that receive message events and convert the records into a GeoJSON
+// FeatureCollection (RFC 7946) with a LineString feature per FIT sequence.
+type Converter struct {
+	err error // Error occurred while receiving messages.
+
+	options *options
+
+	sport    typedef.Sport
+	start    time.Time
+	points   []point
+	features []feature // Features of the previous sequences of a chained file
+
+	mesgc chan any      // This buffered event channel can accept either proto.Message or proto.MessageDefinition maintaining the order of arrival.
+	done  chan struct{} // Tells that all messages have been completely processed.
+
+	result string
+}
+
+type options struct {
+	channelBufferSize int
+	name              string // Feature name, defaults to the sport
+	prettyPrint       bool   // Indent the JSON output
+}
+
+// NewFITToGeoJSONConv creates a new FIT to GeoJSON converter.
+func NewFITToGeoJSONConv(opts ...Option) *Converter {
+	options := defaultOptions()
+	for i := range opts {
+		opts[i](options)
+	}
+
+	c := &Converter{
+		options: options,
+		sport:   typedef.SportInvalid,
+		points:  make([]point, 0),
+		mesgc:   make(chan any, options.channelBufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go c.handleEvent() // spawn only once.
+
+	return c
+}
+
+// Err returns any error that occur during processing events.
+func (c *Converter) Err() error { return c.err }
+
+// OnMesgDef receive message definition from broadcaster
+func (c *Converter) OnMesgDef(mesgDef proto.MessageDefinition) { c.mesgc <- mesgDef }
+
+// OnMesg receive message from broadcaster
+func (c *Converter) OnMesg(mesg proto.Message) { c.mesgc <- mesg }
+
+// sectionBreak separates the messages of two sequences of a chained file in the event channel.
+type sectionBreak struct{}
+
+// NextSection starts a new feature, the following messages belong to the next FIT sequence.
+func (c *Converter) NextSection() { c.mesgc <- sectionBreak{} }
+
+// handleEvent processes events from a buffered channel.
+func (c *Converter) handleEvent() {
+	for event := range c.mesgc {
+		switch event := event.(type) {
+		case proto.Message:
+			c.processMessage(event)
+		case sectionBreak:
+			c.features = append(c.features, c.feature())
+			c.sport, c.start = typedef.SportInvalid, time.Time{}
+			c.points = make([]point, 0)
+		}
+	}
+	close(c.done)
+}
+
+func (c *Converter) processMessage(mesg proto.Message) {
+	switch mesg.Num {
+	case mesgnum.Session:
+		session := mesgdef.NewSession(&mesg)
+		if c.sport == typedef.SportInvalid {
+			c.sport = session.Sport
+		}
+		if c.start.IsZero() {
+			c.start = session.StartTime
+		}
+	case mesgnum.Record:
+		rec := mesgdef.NewRecord(&mesg)
+		if rec.PositionLat == basetype.Sint32Invalid || rec.PositionLong == basetype.Sint32Invalid {
+			return
+		}
+		c.points = append(c.points, newPoint(rec))
+	}
+}
+
+// Wait closes the buffered channel and waits until all event handling is completed
+// and then marshals the final GeoJSON.
+func (c *Converter) Wait() {
+	close(c.mesgc)
+	<-c.done
+	c.result = c.marshal()
+}
+
+func (c *Converter) Result() string {
+	return c.result
+}
+
+// feature builds the feature of the current sequence.
+func (c *Converter) feature() feature {
+	f := feature{Type: "Feature", Properties: properties{Name: c.options.name}}
+	if c.sport != typedef.SportInvalid {
+		f.Properties.Sport = c.sport.String()
+		if f.Properties.Name == "" {
+			f.Properties.Name = c.sport.String()
+		}
+	}
+	if !c.start.IsZero() {
+		f.Properties.StartTime = c.start.UTC().Format(time.RFC3339)
+	}
+	if len(c.points) == 0 {
+		return f
+	}
+
+	line := &lineString{Type: "LineString", Coordinates: make([][]float64, len(c.points))}
+	props := &coordinateProperties{Times: make([]string, len(c.points))}
+	var hasHR, hasCadence, hasPower bool
+	for i, pt := range c.points {
+		line.Coordinates[i] = pt.coordinates
+		if !pt.time.IsZero() {
+			props.Times[i] = pt.time.UTC().Format(time.RFC3339)
+		}
+		hasHR = hasHR || pt.heartRate != nil
+		hasCadence = hasCadence || pt.cadence != nil
+		hasPower = hasPower || pt.power != nil
+	}
+	if hasHR {
+		props.HeartRate = make([]*uint8, len(c.points))
+	}
+	if hasCadence {
+		props.Cadence = make([]*uint8, len(c.points))
+	}
+	if hasPower {
+		props.Power = make([]*uint16, len(c.points))
+	}
+	for i, pt := range c.points {
+		if hasHR {
+			props.HeartRate[i] = pt.heartRate
+		}
+		if hasCadence {
+			props.Cadence[i] = pt.cadence
+		}
+		if hasPower {
+			props.Power[i] = pt.power
+		}
+	}
+	f.Geometry = line
+	f.Properties.CoordinateProperties = props
+	return f
+}
+
+func (c *Converter) marshal() string {
+	if c.err != nil {
+		return ""
+	}
+
+	doc := featureCollection{
+		Type:     "FeatureCollection",
+		Features: append(c.features, c.feature()),
+	}
+
+	var b []byte
+	var err error
+	if c.options.prettyPrint {
+		b, err = json.MarshalIndent(doc, "", "  ")
+	} else {
+		b, err = json.Marshal(doc)
+	}
+	if err != nil {
+		c.err = fmt.Errorf("marshal geojson: %w", err)
+		return ""
+	}
+
+	return string(b)
+}
@@ -0,0 +1,84 @@
+package geojson
+
+import (
+	"math"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string      `json:"type"`
+	Geometry   *lineString `json:"geometry"` // null for a sequence without positions
+	Properties properties  `json:"properties"`
+}
+
+type lineString struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+type properties struct {
+	Name      string `json:"name,omitempty"`
+	Sport     string `json:"sport,omitempty"`
+	StartTime string `json:"start_time,omitempty"`
+
+	// CoordinateProperties holds values per coordinate, the convention of togeojson that map libraries
+	// understand.
+	CoordinateProperties *coordinateProperties `json:"coordinateProperties,omitempty"`
+}
+
+// coordinateProperties are parallel to the coordinates. A series only appears when it has a value, and
+// then has null where a point lacks one.
+type coordinateProperties struct {
+	Times     []string  `json:"times"`
+	HeartRate []*uint8  `json:"heart_rate,omitempty"`
+	Cadence   []*uint8  `json:"cadence,omitempty"`
+	Power     []*uint16 `json:"power,omitempty"`
+}
+
+// point is a record with a position.
+type point struct {
+	coordinates []float64 // longitude, latitude and the altitude if known
+	time        time.Time
+	heartRate   *uint8
+	cadence     *uint8
+	power       *uint16
+}
+
+func newPoint(rec *mesgdef.Record) point {
+	pt := point{
+		coordinates: []float64{round(rec.PositionLongDegrees()), round(rec.PositionLatDegrees())},
+		time:        rec.Timestamp,
+	}
+
+	ele := rec.EnhancedAltitudeScaled()
+	if math.IsNaN(ele) {
+		ele = rec.AltitudeScaled()
+	}
+	if !math.IsNaN(ele) {
+		pt.coordinates = append(pt.coordinates, ele)
+	}
+
+	if rec.HeartRate != basetype.Uint8Invalid {
+		pt.heartRate = &rec.HeartRate
+	}
+	if rec.Cadence != basetype.Uint8Invalid {
+		pt.cadence = &rec.Cadence
+	}
+	if rec.Power != basetype.Uint16Invalid {
+		pt.power = &rec.Power
+	}
+	return pt
+}
+
+// round cuts degrees to 7 decimals, about a centimetre, which is more than any GPS delivers.
+func round(deg float64) float64 {
+	return math.Round(deg*1e7) / 1e7
+}
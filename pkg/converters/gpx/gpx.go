@@ -0,0 +1,170 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+	"github.com/muktihari/fit/proto"
+)
+
+var (
+	_ decoder.MesgDefListener = &Converter{}
+	_ decoder.MesgListener    = &Converter{}
+)
+
+// Converter is an implementation for listeners that receive message events and convert the records into a GPX 1.1 track.
+type Converter struct {
+	err error // Error occurred while receiving messages.
+
+	options *options
+
+	sport   typedef.Sport
+	created time.Time
+	points  []trackPoint
+	tracks  []track // Tracks of the previous sequences of a chained file
+
+	mesgc chan any      // This buffered event channel can accept either proto.Message or proto.MessageDefinition maintaining the order of arrival.
+	done  chan struct{} // Tells that all messages have been completely processed.
+
+	result string
+}
+
+type options struct {
+	channelBufferSize int
+	creator           string // Value of the gpx creator attribute
+	name              string // Track name, defaults to the sport
+	prettyPrint       bool   // Indent the XML output
+}
+
+// NewFITToGPXConv creates a new FIT to GPX converter.
+func NewFITToGPXConv(opts ...Option) *Converter {
+	options := defaultOptions()
+	for i := range opts {
+		opts[i](options)
+	}
+
+	c := &Converter{
+		options: options,
+		sport:   typedef.SportInvalid,
+		points:  make([]trackPoint, 0),
+		mesgc:   make(chan any, options.channelBufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go c.handleEvent() // spawn only once.
+
+	return c
+}
+
+// Err returns any error that occur during processing events.
+func (c *Converter) Err() error { return c.err }
+
+// OnMesgDef receive message definition from broadcaster
+func (c *Converter) OnMesgDef(mesgDef proto.MessageDefinition) { c.mesgc <- mesgDef }
+
+// OnMesg receive message from broadcaster
+func (c *Converter) OnMesg(mesg proto.Message) { c.mesgc <- mesg }
+
+// sectionBreak separates the messages of two sequences of a chained file in the event channel.
+type sectionBreak struct{}
+
+// NextSection starts a new track, the following messages belong to the next FIT sequence.
+func (c *Converter) NextSection() { c.mesgc <- sectionBreak{} }
+
+// handleEvent processes events from a buffered channel.
+func (c *Converter) handleEvent() {
+	for event := range c.mesgc {
+		switch event := event.(type) {
+		case proto.Message:
+			c.processMessage(event)
+		case sectionBreak:
+			c.tracks = append(c.tracks, c.track())
+			c.sport = typedef.SportInvalid
+			c.points = make([]trackPoint, 0)
+		}
+	}
+	close(c.done)
+}
+
+func (c *Converter) processMessage(mesg proto.Message) {
+	switch mesg.Num {
+	case mesgnum.FileId:
+		c.created = mesgdef.NewFileId(&mesg).TimeCreated
+	case mesgnum.Session:
+		if c.sport == typedef.SportInvalid {
+			c.sport = mesgdef.NewSession(&mesg).Sport
+		}
+	case mesgnum.Record:
+		rec := mesgdef.NewRecord(&mesg)
+		if rec.PositionLat == basetype.Sint32Invalid || rec.PositionLong == basetype.Sint32Invalid {
+			return
+		}
+		c.points = append(c.points, newTrackPoint(rec))
+	}
+}
+
+// Wait closes the buffered channel and waits until all event handling is completed
+// and then marshals the final GPX.
+func (c *Converter) Wait() {
+	close(c.mesgc)
+	<-c.done
+	c.result = c.marshal()
+}
+
+func (c *Converter) Result() string {
+	return c.result
+}
+
+// track builds the track of the current sequence, a chained file gets one track per sequence.
+func (c *Converter) track() track {
+	t := track{
+		Name:     c.options.name,
+		Segments: []segment{{Points: c.points}},
+	}
+	if c.sport != typedef.SportInvalid {
+		t.Type = c.sport.String()
+		if t.Name == "" {
+			t.Name = c.sport.String()
+		}
+	}
+	return t
+}
+
+func (c *Converter) marshal() string {
+	if c.err != nil {
+		return ""
+	}
+
+	doc := document{
+		Version:   "1.1",
+		Creator:   c.options.creator,
+		Xmlns:     "http://www.topografix.com/GPX/1/1",
+		XmlnsTPX:  "http://www.garmin.com/xmlschemas/TrackPointExtension/v1",
+		XmlnsXSI:  "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLoc: "http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd",
+		Tracks:    append(c.tracks, c.track()),
+	}
+	if !c.created.IsZero() {
+		doc.Metadata = &metadata{Time: c.created.UTC().Format(time.RFC3339)}
+	}
+
+	var b []byte
+	var err error
+	if c.options.prettyPrint {
+		b, err = xml.MarshalIndent(doc, "", "  ")
+	} else {
+		b, err = xml.Marshal(doc)
+	}
+	if err != nil {
+		c.err = fmt.Errorf("marshal gpx: %w", err)
+		return ""
+	}
+
+	return xml.Header + string(b) + "\n"
+}
@@ -0,0 +1,32 @@
+package gpx
+
+// Option is Converter's option.
+type Option func(o *options)
+
+func defaultOptions() *options {
+	return &options{
+		channelBufferSize: 1000,
+		creator:           "go-fitter",
+		prettyPrint:       true,
+	}
+}
+
+func WithChannelBufferSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.channelBufferSize = size
+		}
+	}
+}
+
+func WithCreator(creator string) Option {
+	return func(o *options) { o.creator = creator }
+}
+
+func WithName(name string) Option {
+	return func(o *options) { o.name = name }
+}
+
+func WithPrettyPrint(pretty bool) Option {
+	return func(o *options) { o.prettyPrint = pretty }
+}
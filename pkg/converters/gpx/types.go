@@ -0,0 +1,89 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"math"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+)
+
+type document struct {
+	XMLName   xml.Name  `xml:"gpx"`
+	Version   string    `xml:"version,attr"`
+	Creator   string    `xml:"creator,attr"`
+	Xmlns     string    `xml:"xmlns,attr"`
+	XmlnsTPX  string    `xml:"xmlns:gpxtpx,attr"`
+	XmlnsXSI  string    `xml:"xmlns:xsi,attr"`
+	SchemaLoc string    `xml:"xsi:schemaLocation,attr"`
+	Metadata  *metadata `xml:"metadata,omitempty"`
+	Tracks    []track   `xml:"trk"`
+}
+
+type metadata struct {
+	Time string `xml:"time,omitempty"`
+}
+
+type track struct {
+	Name     string    `xml:"name,omitempty"`
+	Type     string    `xml:"type,omitempty"`
+	Segments []segment `xml:"trkseg"`
+}
+
+type segment struct {
+	Points []trackPoint `xml:"trkpt"`
+}
+
+type trackPoint struct {
+	Lat        float64     `xml:"lat,attr"`
+	Lon        float64     `xml:"lon,attr"`
+	Ele        *float64    `xml:"ele,omitempty"`
+	Time       string      `xml:"time,omitempty"`
+	Extensions *extensions `xml:"extensions,omitempty"`
+}
+
+type extensions struct {
+	TPX trackPointExtension `xml:"gpxtpx:TrackPointExtension"`
+}
+
+// trackPointExtension is Garmin's TrackPointExtension v1, understood by most GPX consumers.
+type trackPointExtension struct {
+	Temp *int8  `xml:"gpxtpx:atemp,omitempty"`
+	HR   *uint8 `xml:"gpxtpx:hr,omitempty"`
+	Cad  *uint8 `xml:"gpxtpx:cad,omitempty"`
+}
+
+func newTrackPoint(rec *mesgdef.Record) trackPoint {
+	pt := trackPoint{
+		Lat: rec.PositionLatDegrees(),
+		Lon: rec.PositionLongDegrees(),
+	}
+	if !rec.Timestamp.IsZero() {
+		pt.Time = rec.Timestamp.UTC().Format(time.RFC3339)
+	}
+
+	ele := rec.EnhancedAltitudeScaled()
+	if math.IsNaN(ele) {
+		ele = rec.AltitudeScaled()
+	}
+	if !math.IsNaN(ele) {
+		pt.Ele = &ele
+	}
+
+	var ext trackPointExtension
+	if rec.HeartRate != basetype.Uint8Invalid {
+		ext.HR = &rec.HeartRate
+	}
+	if rec.Cadence != basetype.Uint8Invalid {
+		ext.Cad = &rec.Cadence
+	}
+	if rec.Temperature != basetype.Sint8Invalid {
+		ext.Temp = &rec.Temperature
+	}
+	if ext != (trackPointExtension{}) {
+		pt.Extensions = &extensions{TPX: ext}
+	}
+
+	return pt
+}
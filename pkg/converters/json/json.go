@@ -1,8 +1,11 @@
 package json
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"math"
 	"strings"
 	"time"
@@ -16,8 +19,14 @@ import (
 	"github.com/muktihari/fit/profile/mesgdef"
 	"github.com/muktihari/fit/profile/untyped/mesgnum"
 	"github.com/muktihari/fit/proto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/kyzrfranz/go-fitter/pkg/converters/json")
+
 var (
 	_ decoder.MesgDefListener = &Converter{}
 	_ decoder.MesgListener    = &Converter{}
@@ -37,6 +46,10 @@ type Converter struct {
 	recordMessages  []map[string]any
 	sportMessages   []map[string]any
 
+	// Output of the previous sequences of a chained file, their records alone for NDJSON
+	sections       []map[string]any
+	recordSections [][]map[string]any
+
 	mesgc chan any      // This buffered event channel can accept either proto.Message or proto.MessageDefinition maintaining the order of arrival.
 	done  chan struct{} // Tells that all messages have been completely processed.
 
@@ -45,11 +58,14 @@ type Converter struct {
 
 type options struct {
 	channelBufferSize         int
-	useRawValue               bool // Use raw value instead of scaled value
-	printOnlyValidValue       bool // Print only valid value
-	printGPSPositionInDegrees bool // Print latitude and longitude in degrees instead of semicircles.
-	prettyPrint               bool // Pretty-print the final JSON output
-	noRecords                 bool // Add --no-records flag
+	useRawValue               bool            // Use raw value instead of scaled value
+	printOnlyValidValue       bool            // Print only valid value
+	printGPSPositionInDegrees bool            // Print latitude and longitude in degrees instead of semicircles.
+	prettyPrint               bool            // Pretty-print the final JSON output
+	noRecords                 bool            // Add --no-records flag
+	ndjson                    bool            // One line per record instead of one object
+	ctx                       context.Context // Parent of the enrichment and marshal spans
+	logger                    *slog.Logger
 }
 
 // NewFITToJSONConv creates a new FIT to JSON converter.
@@ -83,6 +99,12 @@ func (c *Converter) OnMesgDef(mesgDef proto.MessageDefinition) { c.mesgc <- mesg
 // OnMesg receive message from broadcaster
 func (c *Converter) OnMesg(mesg proto.Message) { c.mesgc <- mesg }
 
+// sectionBreak separates the messages of two sequences of a chained file in the event channel.
+type sectionBreak struct{}
+
+// NextSection starts a new output section, the following messages belong to the next FIT sequence.
+func (c *Converter) NextSection() { c.mesgc <- sectionBreak{} }
+
 // handleEvent processes events from a buffered channel.
 func (c *Converter) handleEvent() {
 	for event := range c.mesgc {
@@ -91,6 +113,8 @@ func (c *Converter) handleEvent() {
 			c.processMessage(mesg)
 		case proto.MessageDefinition:
 			// We don't need to do anything with Defs for JSON
+		case sectionBreak:
+			c.closeSection()
 		}
 	}
 	close(c.done)
@@ -285,13 +309,94 @@ func (c *Converter) Result() string {
 	return c.result
 }
 
+// closeSection collates the messages of the sequence that just ended and starts over for the next one.
+func (c *Converter) closeSection() {
+	if c.err != nil {
+		return
+	}
+	if c.options.ndjson {
+		c.recordSections = append(c.recordSections, c.recordMessages)
+	} else {
+		c.sections = append(c.sections, c.collate())
+	}
+	c.fieldDescriptions = nil
+	c.sessionMessages = make([]map[string]any, 0)
+	c.lapMessages = make([]map[string]any, 0)
+	c.recordMessages = make([]map[string]any, 0)
+	c.sportMessages = make([]map[string]any, 0)
+}
+
 // marshalAndWrite collates all processed data and writes it as a single JSON object.
+// A chained file becomes {"sections": [...]} with one object per FIT sequence.
 func (c *Converter) marshal() string {
 	if c.err != nil { // Check for earlier processing errors
 		return ""
 	}
+	if c.options.ndjson {
+		return c.marshalLines()
+	}
+
+	finalData := c.collate()
+	if len(c.sections) > 0 {
+		finalData = map[string]any{"sections": append(c.sections, finalData)}
+	}
+
+	// Marshal to JSON
+	_, span := tracer.Start(c.options.ctx, "json.marshal")
+	defer span.End()
+	var jsonData []byte
+	var err error
+	if c.options.prettyPrint {
+		jsonData, err = json.MarshalIndent(finalData, "", "  ")
+	} else {
+		jsonData, err = json.Marshal(finalData)
+	}
+	if err != nil {
+		c.err = fmt.Errorf("marshal json: %w", err)
+		span.SetStatus(codes.Error, c.err.Error())
+		return ""
+	}
+	span.SetAttributes(attribute.Int("json.size", len(jsonData)))
+	c.options.logger.Log(c.options.ctx, slog.LevelDebug, "marshaled json",
+		slog.Int("size", len(jsonData)),
+		slog.Int("sections", len(c.sections)+1))
+
+	return string(jsonData)
+}
+
+// marshalLines writes every record as a line of its own.
+func (c *Converter) marshalLines() string {
+	_, span := tracer.Start(c.options.ctx, "json.marshal")
+	defer span.End()
+
+	sections := append(c.recordSections, c.recordMessages)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i, records := range sections {
+		for _, record := range records {
+			if len(sections) > 1 {
+				record["sequence"] = i
+			}
+			if err := enc.Encode(record); err != nil {
+				c.err = fmt.Errorf("marshal json: %w", err)
+				span.SetStatus(codes.Error, c.err.Error())
+				return ""
+			}
+		}
+	}
+	span.SetAttributes(attribute.Int("json.size", buf.Len()))
+	c.options.logger.Log(c.options.ctx, slog.LevelDebug, "marshaled ndjson",
+		slog.Int("size", buf.Len()),
+		slog.Int("sections", len(sections)))
 
+	return buf.String()
+}
+
+// collate builds the output object of the current sequence.
+func (c *Converter) collate() map[string]any {
+	_, span := tracer.Start(c.options.ctx, "json.enrich", trace.WithAttributes(attribute.Int("fit.laps", len(c.lapMessages))))
 	c.enrichLaps()
+	span.End()
 
 	// Collate all data into the final coach-friendly structure
 	finalData := make(map[string]any)
@@ -313,20 +418,7 @@ func (c *Converter) marshal() string {
 		finalData["records"] = c.recordMessages
 	}
 
-	// Marshal to JSON
-	var jsonData []byte
-	var err error
-	if c.options.prettyPrint {
-		jsonData, err = json.MarshalIndent(finalData, "", "  ")
-	} else {
-		jsonData, err = json.Marshal(finalData)
-	}
-	if err != nil {
-		c.err = fmt.Errorf("marshal json: %w", err)
-		return ""
-	}
-
-	return string(jsonData)
+	return finalData
 }
 
 // getFieldDescription finds the matching FieldDescription for a developer field.
@@ -436,15 +528,10 @@ func (c *Converter) enrichLaps() {
 		lap := c.lapMessages[i]
 
 		lapStartTimeStr, ok := lap["start_time"].(string)
-		if !ok {
-			continue
-		}
-		lapDuration, ok := getFloat(lap, "total_timer_time")
-		if !ok {
-			continue
-		}
+		lapDuration, hasDuration := getFloat(lap, "total_timer_time")
 		lapStartTime, err := time.Parse(time.RFC3339, lapStartTimeStr)
-		if err != nil {
+		if !ok || !hasDuration || err != nil {
+			c.options.logger.Log(c.options.ctx, slog.LevelDebug, "lap not enriched, start time or timer time missing", slog.Int("lap", i))
 			continue
 		}
 		nanoseconds := int64(lapDuration * float64(time.Second))
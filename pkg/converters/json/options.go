@@ -1,5 +1,10 @@
 package json
 
+import (
+	"context"
+	"log/slog"
+)
+
 // Option is Converter's option.
 type Option func(o *options)
 
@@ -11,6 +16,8 @@ func defaultOptions() *options {
 		printGPSPositionInDegrees: false,
 		prettyPrint:               true,
 		noRecords:                 false,
+		ctx:                       context.Background(),
+		logger:                    slog.New(slog.DiscardHandler),
 	}
 }
 
@@ -37,3 +44,27 @@ func WithPrettyPrint(pretty bool) Option {
 func WithNoRecords() Option {
 	return func(o *options) { o.noRecords = true }
 }
+
+// WithNDJSON makes the result newline delimited JSON with one line per record instead of a single object.
+// The records of a chained file carry the index of their FIT sequence as "sequence".
+func WithNDJSON() Option {
+	return func(o *options) { o.ndjson = true }
+}
+
+// WithContext makes the spans of lap enrichment and marshaling children of the span in ctx.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		if ctx != nil {
+			o.ctx = ctx
+		}
+	}
+}
+
+// WithLogger logs laps that can't be enriched and the size of the output to logger at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package tcx
+
+// Option is Converter's option.
+type Option func(o *options)
+
+func defaultOptions() *options {
+	return &options{
+		channelBufferSize: 1000,
+		prettyPrint:       true,
+	}
+}
+
+func WithChannelBufferSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.channelBufferSize = size
+		}
+	}
+}
+
+func WithPrettyPrint(pretty bool) Option {
+	return func(o *options) { o.prettyPrint = pretty }
+}
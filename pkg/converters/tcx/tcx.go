@@ -0,0 +1,205 @@
+package tcx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+	"github.com/muktihari/fit/proto"
+)
+
+var (
+	_ decoder.MesgDefListener = &Converter{}
+	_ decoder.MesgListener    = &Converter{}
+)
+
+// Converter is an implementation for listeners that receive message events and convert the laps and records into
+// a Garmin Training Center Database v2 activity.
+type Converter struct {
+	err error // Error occurred while receiving messages.
+
+	options *options
+
+	sport      typedef.Sport
+	start      time.Time // Start time of the session, the Id of the activity
+	laps       []*mesgdef.Lap
+	points     []trackPoint
+	activities []activity // Activities of the previous sequences of a chained file
+
+	mesgc chan any      // This buffered event channel can accept either proto.Message or proto.MessageDefinition maintaining the order of arrival.
+	done  chan struct{} // Tells that all messages have been completely processed.
+
+	result string
+}
+
+type options struct {
+	channelBufferSize int
+	prettyPrint       bool // Indent the XML output
+}
+
+// NewFITToTCXConv creates a new FIT to TCX converter.
+func NewFITToTCXConv(opts ...Option) *Converter {
+	options := defaultOptions()
+	for i := range opts {
+		opts[i](options)
+	}
+
+	c := &Converter{
+		options: options,
+		sport:   typedef.SportInvalid,
+		points:  make([]trackPoint, 0),
+		mesgc:   make(chan any, options.channelBufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go c.handleEvent() // spawn only once.
+
+	return c
+}
+
+// Err returns any error that occur during processing events.
+func (c *Converter) Err() error { return c.err }
+
+// OnMesgDef receive message definition from broadcaster
+func (c *Converter) OnMesgDef(mesgDef proto.MessageDefinition) { c.mesgc <- mesgDef }
+
+// OnMesg receive message from broadcaster
+func (c *Converter) OnMesg(mesg proto.Message) { c.mesgc <- mesg }
+
+// sectionBreak separates the messages of two sequences of a chained file in the event channel.
+type sectionBreak struct{}
+
+// NextSection starts a new activity, the following messages belong to the next FIT sequence.
+func (c *Converter) NextSection() { c.mesgc <- sectionBreak{} }
+
+// handleEvent processes events from a buffered channel.
+func (c *Converter) handleEvent() {
+	for event := range c.mesgc {
+		switch event := event.(type) {
+		case proto.Message:
+			c.processMessage(event)
+		case sectionBreak:
+			c.activities = append(c.activities, c.activity())
+			c.sport, c.start = typedef.SportInvalid, time.Time{}
+			c.laps = nil
+			c.points = make([]trackPoint, 0)
+		}
+	}
+	close(c.done)
+}
+
+func (c *Converter) processMessage(mesg proto.Message) {
+	switch mesg.Num {
+	case mesgnum.Session:
+		session := mesgdef.NewSession(&mesg)
+		if c.sport == typedef.SportInvalid {
+			c.sport = session.Sport
+		}
+		if c.start.IsZero() {
+			c.start = session.StartTime
+		}
+	case mesgnum.Lap:
+		c.laps = append(c.laps, mesgdef.NewLap(&mesg))
+	case mesgnum.Record:
+		rec := mesgdef.NewRecord(&mesg)
+		if rec.Timestamp.IsZero() {
+			return // a trackpoint needs a time
+		}
+		c.points = append(c.points, newTrackPoint(rec))
+	}
+}
+
+// Wait closes the buffered channel and waits until all event handling is completed
+// and then marshals the final TCX.
+func (c *Converter) Wait() {
+	close(c.mesgc)
+	<-c.done
+	c.result = c.marshal()
+}
+
+func (c *Converter) Result() string {
+	return c.result
+}
+
+// activity builds the activity of the current sequence. Every point goes to the last lap started at or
+// before it, points before the first lap to the first one. A file without laps gets one covering all points.
+func (c *Converter) activity() activity {
+	running := c.sport == typedef.SportRunning
+	for i := range c.points {
+		c.points[i].setCadence(running)
+	}
+
+	laps := make([]lap, 0, len(c.laps))
+	starts := make([]time.Time, 0, len(c.laps))
+	for _, l := range c.laps {
+		laps = append(laps, newLap(l))
+		start := l.StartTime
+		if start.IsZero() {
+			start = l.Timestamp
+		}
+		starts = append(starts, start)
+	}
+	if len(laps) == 0 {
+		laps = append(laps, lapFromPoints(c.points))
+		starts = append(starts, time.Time{})
+	}
+
+	i := 0
+	for _, pt := range c.points {
+		for i+1 < len(starts) && !pt.timestamp.Before(starts[i+1]) {
+			i++
+		}
+		if laps[i].Track == nil {
+			laps[i].Track = &track{}
+		}
+		laps[i].Track.Points = append(laps[i].Track.Points, pt)
+	}
+
+	id := c.start
+	if id.IsZero() && len(c.points) > 0 {
+		id = c.points[0].timestamp
+	}
+	a := activity{
+		Sport: sportName(c.sport),
+		ID:    id.UTC().Format(time.RFC3339),
+		Laps:  laps,
+	}
+	for i := range a.Laps {
+		if a.Laps[i].StartTime == "" {
+			a.Laps[i].StartTime = a.ID
+		}
+	}
+	return a
+}
+
+func (c *Converter) marshal() string {
+	if c.err != nil {
+		return ""
+	}
+
+	doc := document{
+		Xmlns:      "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2",
+		XmlnsNS3:   "http://www.garmin.com/xmlschemas/ActivityExtension/v2",
+		XmlnsXSI:   "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLoc:  "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2 http://www.garmin.com/xmlschemas/TrainingCenterDatabasev2.xsd",
+		Activities: append(c.activities, c.activity()),
+	}
+
+	var b []byte
+	var err error
+	if c.options.prettyPrint {
+		b, err = xml.MarshalIndent(doc, "", "  ")
+	} else {
+		b, err = xml.Marshal(doc)
+	}
+	if err != nil {
+		c.err = fmt.Errorf("marshal tcx: %w", err)
+		return ""
+	}
+
+	return xml.Header + string(b) + "\n"
+}
@@ -0,0 +1,212 @@
+package tcx
+
+import (
+	"encoding/xml"
+	"math"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+type document struct {
+	XMLName    xml.Name   `xml:"TrainingCenterDatabase"`
+	Xmlns      string     `xml:"xmlns,attr"`
+	XmlnsNS3   string     `xml:"xmlns:ns3,attr"`
+	XmlnsXSI   string     `xml:"xmlns:xsi,attr"`
+	SchemaLoc  string     `xml:"xsi:schemaLocation,attr"`
+	Activities []activity `xml:"Activities>Activity"`
+}
+
+type activity struct {
+	Sport string `xml:"Sport,attr"`
+	ID    string `xml:"Id"` // start time of the activity
+	Laps  []lap  `xml:"Lap"`
+}
+
+type lap struct {
+	StartTime        string        `xml:"StartTime,attr"`
+	TotalTimeSeconds float64       `xml:"TotalTimeSeconds"`
+	DistanceMeters   float64       `xml:"DistanceMeters"`
+	MaximumSpeed     *float64      `xml:"MaximumSpeed,omitempty"`
+	Calories         uint16        `xml:"Calories"`
+	AvgHeartRate     *heartRate    `xml:"AverageHeartRateBpm,omitempty"`
+	MaxHeartRate     *heartRate    `xml:"MaximumHeartRateBpm,omitempty"`
+	Intensity        string        `xml:"Intensity"`
+	Cadence          *uint8        `xml:"Cadence,omitempty"`
+	TriggerMethod    string        `xml:"TriggerMethod"`
+	Track            *track        `xml:"Track,omitempty"`
+	Extensions       *lapExtension `xml:"Extensions,omitempty"`
+}
+
+type heartRate struct {
+	Value uint8 `xml:"Value"`
+}
+
+type track struct {
+	Points []trackPoint `xml:"Trackpoint"`
+}
+
+type trackPoint struct {
+	Time     string       `xml:"Time"`
+	Position *position    `xml:"Position,omitempty"`
+	Altitude *float64     `xml:"AltitudeMeters,omitempty"`
+	Distance *float64     `xml:"DistanceMeters,omitempty"`
+	HR       *heartRate   `xml:"HeartRateBpm,omitempty"`
+	Cadence  *uint8       `xml:"Cadence,omitempty"`
+	Ext      *tpExtension `xml:"Extensions,omitempty"`
+
+	timestamp time.Time // for assigning the point to its lap
+	cadence   *uint8    // placed by setCadence once the sport is known
+}
+
+type position struct {
+	Lat float64 `xml:"LatitudeDegrees"`
+	Lon float64 `xml:"LongitudeDegrees"`
+}
+
+type tpExtension struct {
+	TPX tpx `xml:"ns3:TPX"`
+}
+
+// tpx is the trackpoint part of Garmin's ActivityExtension v2. Running cadence goes here, the Cadence
+// element of a trackpoint is for cycling.
+type tpx struct {
+	Speed      *float64 `xml:"ns3:Speed,omitempty"`
+	RunCadence *uint8   `xml:"ns3:RunCadence,omitempty"`
+	Watts      *uint16  `xml:"ns3:Watts,omitempty"`
+}
+
+type lapExtension struct {
+	LX lx `xml:"ns3:LX"`
+}
+
+// lx is the lap part of Garmin's ActivityExtension v2.
+type lx struct {
+	AvgWatts *uint16 `xml:"ns3:AvgWatts,omitempty"`
+}
+
+// sportName maps a FIT sport to one of the three sports TCX knows.
+func sportName(sport typedef.Sport) string {
+	switch sport {
+	case typedef.SportRunning:
+		return "Running"
+	case typedef.SportCycling:
+		return "Biking"
+	default:
+		return "Other"
+	}
+}
+
+func newTrackPoint(rec *mesgdef.Record) trackPoint {
+	pt := trackPoint{
+		Time:      rec.Timestamp.UTC().Format(time.RFC3339),
+		timestamp: rec.Timestamp,
+	}
+	if rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid {
+		pt.Position = &position{Lat: rec.PositionLatDegrees(), Lon: rec.PositionLongDegrees()}
+	}
+
+	pt.Altitude = validFloat(rec.EnhancedAltitudeScaled())
+	if pt.Altitude == nil {
+		pt.Altitude = validFloat(rec.AltitudeScaled())
+	}
+	pt.Distance = validFloat(rec.DistanceScaled())
+	if rec.HeartRate != basetype.Uint8Invalid {
+		pt.HR = &heartRate{Value: rec.HeartRate}
+	}
+
+	var ext tpx
+	ext.Speed = validFloat(rec.EnhancedSpeedScaled())
+	if ext.Speed == nil {
+		ext.Speed = validFloat(rec.SpeedScaled())
+	}
+	if rec.Cadence != basetype.Uint8Invalid {
+		pt.cadence = &rec.Cadence
+	}
+	if rec.Power != basetype.Uint16Invalid {
+		ext.Watts = &rec.Power
+	}
+	if ext != (tpx{}) {
+		pt.Ext = &tpExtension{TPX: ext}
+	}
+
+	return pt
+}
+
+func newLap(l *mesgdef.Lap) lap {
+	start := l.StartTime
+	if start.IsZero() {
+		start = l.Timestamp
+	}
+	out := lap{
+		StartTime:     start.UTC().Format(time.RFC3339),
+		Intensity:     "Active",
+		TriggerMethod: "Manual",
+	}
+	if v := validFloat(l.TotalTimerTimeScaled()); v != nil {
+		out.TotalTimeSeconds = *v
+	}
+	if v := validFloat(l.TotalDistanceScaled()); v != nil {
+		out.DistanceMeters = *v
+	}
+	out.MaximumSpeed = validFloat(l.EnhancedMaxSpeedScaled())
+	if out.MaximumSpeed == nil {
+		out.MaximumSpeed = validFloat(l.MaxSpeedScaled())
+	}
+	if l.TotalCalories != basetype.Uint16Invalid {
+		out.Calories = l.TotalCalories
+	}
+	if l.AvgHeartRate != basetype.Uint8Invalid {
+		out.AvgHeartRate = &heartRate{Value: l.AvgHeartRate}
+	}
+	if l.MaxHeartRate != basetype.Uint8Invalid {
+		out.MaxHeartRate = &heartRate{Value: l.MaxHeartRate}
+	}
+	if l.AvgCadence != basetype.Uint8Invalid {
+		out.Cadence = &l.AvgCadence
+	}
+	if l.AvgPower != basetype.Uint16Invalid {
+		out.Extensions = &lapExtension{LX: lx{AvgWatts: &l.AvgPower}}
+	}
+	return out
+}
+
+// setCadence puts the cadence where TCX expects it for the sport, the Cadence element for cycling and
+// the RunCadence extension for running.
+func (pt *trackPoint) setCadence(running bool) {
+	if pt.cadence == nil {
+		return
+	}
+	if !running {
+		pt.Cadence = pt.cadence
+		return
+	}
+	if pt.Ext == nil {
+		pt.Ext = &tpExtension{}
+	}
+	pt.Ext.TPX.RunCadence = pt.cadence
+}
+
+// lapFromPoints stands in for the laps of a file that has none, covering all of its points.
+func lapFromPoints(points []trackPoint) lap {
+	out := lap{Intensity: "Active", TriggerMethod: "Manual"}
+	if len(points) == 0 {
+		return out
+	}
+	first, last := points[0], points[len(points)-1]
+	out.StartTime = first.Time
+	out.TotalTimeSeconds = last.timestamp.Sub(first.timestamp).Seconds()
+	if last.Distance != nil {
+		out.DistanceMeters = *last.Distance
+	}
+	return out
+}
+
+func validFloat(f float64) *float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
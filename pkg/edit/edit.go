@@ -0,0 +1,209 @@
+// Package edit cuts activities: it trims them to a time or distance range and splits them into several.
+// Laps, sessions and the activity message of every result are recomputed from the remaining records.
+package edit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/kyzrfranz/go-fitter/pkg/summary"
+	"github.com/muktihari/fit/encoder"
+	"github.com/muktihari/fit/kit/datetime"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/proto"
+)
+
+var ErrEmptyRange = errors.New("range contains no records")
+
+// Range selects records by time and by distance. From bounds are inclusive, To bounds exclusive,
+// zero values leave that side open.
+type Range struct {
+	From         time.Time `json:"from,omitzero"`
+	To           time.Time `json:"to,omitzero"`
+	FromDistance float64   `json:"from_distance,omitempty"` // metres
+	ToDistance   float64   `json:"to_distance,omitempty"`   // metres
+}
+
+func (r Range) contains(rec *mesgdef.Record) bool {
+	if !r.From.IsZero() && rec.Timestamp.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !rec.Timestamp.Before(r.To) {
+		return false
+	}
+	if r.FromDistance <= 0 && r.ToDistance <= 0 {
+		return true
+	}
+	d := rec.DistanceScaled()
+	if math.IsNaN(d) {
+		return false
+	}
+	return d >= r.FromDistance && (r.ToDistance <= 0 || d < r.ToDistance)
+}
+
+// Trim returns a copy of act holding only the records within r, with distances starting from zero again.
+// Events, lengths and GPS metadata outside the kept records are dropped, as are the messages that
+// summarize the whole original activity, like splits and time in zone.
+func Trim(act *filedef.Activity, r Range) (*filedef.Activity, error) {
+	records := make([]*mesgdef.Record, 0, len(act.Records))
+	for _, rec := range act.Records {
+		if r.contains(rec) {
+			records = append(records, rec)
+		}
+	}
+	if len(records) == 0 {
+		return nil, ErrEmptyRange
+	}
+	slices.SortStableFunc(records, byTimestamp)
+	return cut(act, records), nil
+}
+
+// Split cuts act at each of at into consecutive activities. Parts without records are left out.
+// Every part gets its own file_id time_created, the time of its first record, so stores don't
+// take the parts for duplicates of each other.
+func Split(act *filedef.Activity, at []time.Time) ([]*filedef.Activity, error) {
+	records := slices.Clone(act.Records)
+	slices.SortStableFunc(records, byTimestamp)
+
+	parts := make([]*filedef.Activity, 0, len(at)+1)
+	for _, part := range summary.Split(records, at) {
+		if len(part) == 0 {
+			continue
+		}
+		res := cut(act, part)
+		res.FileId.SetTimeCreated(part[0].Timestamp)
+		parts = append(parts, res)
+	}
+	if len(parts) == 0 {
+		return nil, ErrEmptyRange
+	}
+	return parts, nil
+}
+
+// cut builds a new activity from act around records, which must be a chronologically ordered subset of act's.
+func cut(act *filedef.Activity, records []*mesgdef.Record) *filedef.Activity {
+	first, last := records[0].Timestamp, records[len(records)-1].Timestamp
+	within := func(t time.Time) bool { return !t.Before(first) && !t.After(last) }
+
+	res := filedef.NewActivity()
+	res.FileId = act.FileId
+	res.DeveloperDataIds = act.DeveloperDataIds
+	res.FieldDescriptions = act.FieldDescriptions
+	res.UserProfile = act.UserProfile
+	res.DeviceInfos = act.DeviceInfos
+	res.Sports = act.Sports
+	res.ZonesTargets = act.ZonesTargets
+	res.Workouts = act.Workouts
+	res.WorkoutSteps = act.WorkoutSteps
+
+	res.Records = rebase(records)
+	for _, ev := range act.Events {
+		if within(ev.Timestamp) {
+			res.Events = append(res.Events, ev)
+		}
+	}
+	for _, l := range act.Lengths {
+		if within(l.StartTime) {
+			res.Lengths = append(res.Lengths, l)
+		}
+	}
+	for _, g := range act.GpsMetadatas {
+		if within(g.Timestamp) {
+			res.GpsMetadatas = append(res.GpsMetadatas, g)
+		}
+	}
+	for _, m := range act.UnrelatedMessages {
+		t := m.FieldValueByNum(proto.FieldNumTimestamp).Uint32()
+		if t == basetype.Uint32Invalid || within(datetime.ToTime(t)) {
+			res.UnrelatedMessages = append(res.UnrelatedMessages, m)
+		}
+	}
+	if !slices.ContainsFunc(res.Events, isTimerStart) {
+		res.Events = slices.Insert(res.Events, 0, timerEvent(first, typedef.EventTypeStart))
+	}
+	if !slices.ContainsFunc(res.Events, isTimerStop) {
+		res.Events = append(res.Events, timerEvent(last, typedef.EventTypeStopAll))
+	}
+
+	// Laps and sessions are only used for their boundaries and sports, Rebuild replaces them.
+	res.Laps = act.Laps
+	res.Sessions = act.Sessions
+	summary.Rebuild(res)
+	return res
+}
+
+// rebase shifts the distance of records back to start at zero, working on copies.
+func rebase(records []*mesgdef.Record) []*mesgdef.Record {
+	offset := math.NaN()
+	for _, rec := range records {
+		if d := rec.DistanceScaled(); !math.IsNaN(d) {
+			offset = d
+			break
+		}
+	}
+	if math.IsNaN(offset) || offset == 0 {
+		return records
+	}
+
+	rebased := make([]*mesgdef.Record, len(records))
+	for i, rec := range records {
+		c := *rec
+		if d := c.DistanceScaled(); !math.IsNaN(d) {
+			c.SetDistanceScaled(d - offset)
+		}
+		rebased[i] = &c
+	}
+	return rebased
+}
+
+func byTimestamp(a, b *mesgdef.Record) int { return a.Timestamp.Compare(b.Timestamp) }
+
+func isTimerStart(ev *mesgdef.Event) bool {
+	return ev.Event == typedef.EventTimer && ev.EventType == typedef.EventTypeStart
+}
+
+func isTimerStop(ev *mesgdef.Event) bool {
+	return ev.Event == typedef.EventTimer && (ev.EventType == typedef.EventTypeStop || ev.EventType == typedef.EventTypeStopAll)
+}
+
+func timerEvent(t time.Time, eventType typedef.EventType) *mesgdef.Event {
+	return mesgdef.NewEvent(nil).
+		SetTimestamp(t).
+		SetEvent(typedef.EventTimer).
+		SetEventType(eventType)
+}
+
+// Encode encodes act as a FIT file, of protocol version 2 when it has developer fields.
+func Encode(act *filedef.Activity) ([]byte, error) {
+	fit := act.ToFIT(nil)
+	if slices.ContainsFunc(fit.Messages, func(m proto.Message) bool { return len(m.DeveloperFields) > 0 }) {
+		fit.FileHeader.ProtocolVersion = proto.V2
+	}
+	var buf bytes.Buffer
+	if err := activity.Encode(encoder.New(&buf), &fit); err != nil {
+		return nil, fmt.Errorf("encode failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseTime parses s as an RFC 3339 timestamp, or as a duration like "1h05m" counted from start.
+func ParseTime(s string, start time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", s)
+	}
+	return start.Add(d), nil
+}
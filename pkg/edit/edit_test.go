@@ -0,0 +1,229 @@
+package edit
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/validate"
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+var t0 = time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+// ride returns an activity with a record every second for n seconds from begin, at 5 m/s, with the given
+// heart rate and power.
+func ride(begin time.Time, n int, heartRate uint8, power uint16) *filedef.Activity {
+	act := filedef.NewActivity()
+	act.FileId = *mesgdef.NewFileId(nil).
+		SetType(typedef.FileActivity).
+		SetManufacturer(typedef.ManufacturerDevelopment).
+		SetTimeCreated(begin)
+	for i := range n {
+		rec := mesgdef.NewRecord(nil).
+			SetTimestamp(begin.Add(time.Duration(i) * time.Second)).
+			SetDistanceScaled(float64(i) * 5)
+		if heartRate > 0 {
+			rec.SetHeartRate(heartRate)
+		}
+		if power > 0 {
+			rec.SetPower(power)
+		}
+		act.Records = append(act.Records, rec)
+	}
+	return act
+}
+
+func TestTrim(t *testing.T) {
+	tests := []struct {
+		name        string
+		r           Range
+		wantRecords int
+		wantFirst   time.Time
+		wantErr     error
+	}{
+		{name: "open range keeps everything", r: Range{}, wantRecords: 60, wantFirst: t0},
+		{name: "time range, to is exclusive", r: Range{From: t0.Add(10 * time.Second), To: t0.Add(20 * time.Second)},
+			wantRecords: 10, wantFirst: t0.Add(10 * time.Second)},
+		{name: "distance range", r: Range{FromDistance: 100, ToDistance: 200}, wantRecords: 20, wantFirst: t0.Add(20 * time.Second)},
+		{name: "from only", r: Range{From: t0.Add(50 * time.Second)}, wantRecords: 10, wantFirst: t0.Add(50 * time.Second)},
+		{name: "outside the activity", r: Range{From: t0.Add(time.Hour)}, wantErr: ErrEmptyRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Trim(ride(t0, 60, 140, 200), tt.r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Trim() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got.Records) != tt.wantRecords {
+				t.Fatalf("Trim() = %d records, want %d", len(got.Records), tt.wantRecords)
+			}
+			if first := got.Records[0]; !first.Timestamp.Equal(tt.wantFirst) || first.DistanceScaled() != 0 {
+				t.Errorf("first record at %v, %g m, want %v, 0 m", first.Timestamp, first.DistanceScaled(), tt.wantFirst)
+			}
+			if len(got.Laps) == 0 || len(got.Sessions) != 1 || got.Activity == nil {
+				t.Errorf("Trim() = %d laps, %d sessions, activity %v", len(got.Laps), len(got.Sessions), got.Activity != nil)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        []time.Time
+		wantParts []int
+	}{
+		{name: "in two", at: []time.Time{t0.Add(20 * time.Second)}, wantParts: []int{20, 40}},
+		{name: "unordered cuts", at: []time.Time{t0.Add(40 * time.Second), t0.Add(20 * time.Second)}, wantParts: []int{20, 20, 20}},
+		{name: "empty parts are left out", at: []time.Time{t0.Add(-time.Minute), t0.Add(30 * time.Second), t0.Add(time.Hour)}, wantParts: []int{30, 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := Split(ride(t0, 60, 140, 200), tt.at)
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			got := make([]int, len(parts))
+			for i, p := range parts {
+				got[i] = len(p.Records)
+			}
+			if !slices.Equal(got, tt.wantParts) {
+				t.Fatalf("Split() = %v records, want %v", got, tt.wantParts)
+			}
+			for i := 1; i < len(parts); i++ {
+				if parts[i].FileId.TimeCreated.Equal(parts[i-1].FileId.TimeCreated) {
+					t.Errorf("parts %d and %d share the file_id time_created", i-1, i)
+				}
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		acts        []*filedef.Activity
+		opts        MergeOptions
+		wantRecords int
+		wantPower   uint16
+		wantHR      uint8
+		wantErr     bool
+	}{
+		{
+			name:        "concat",
+			acts:        []*filedef.Activity{ride(t0, 30, 140, 200), ride(t0.Add(time.Minute), 30, 150, 210)},
+			wantRecords: 60, wantPower: 200, wantHR: 140,
+		},
+		{
+			name:        "interleave takes fields from the first activity that has them",
+			acts:        []*filedef.Activity{ride(t0, 30, 140, 0), ride(t0, 30, 0, 250)},
+			opts:        MergeOptions{Mode: MergeInterleave},
+			wantRecords: 30, wantPower: 250, wantHR: 140,
+		},
+		{
+			name:        "interleave with precedence",
+			acts:        []*filedef.Activity{ride(t0, 30, 140, 200), ride(t0, 30, 150, 250)},
+			opts:        MergeOptions{Mode: MergeInterleave, Precedence: map[string][]int{"power": {1}}},
+			wantRecords: 30, wantPower: 250, wantHR: 140,
+		},
+		{
+			name:    "a single activity with records",
+			acts:    []*filedef.Activity{ride(t0, 30, 140, 200), ride(t0, 0, 0, 0)},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			acts:    []*filedef.Activity{ride(t0, 30, 140, 200), ride(t0, 30, 140, 200)},
+			opts:    MergeOptions{Mode: "zip"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge(tt.acts, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Merge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got.Records) != tt.wantRecords {
+				t.Fatalf("Merge() = %d records, want %d", len(got.Records), tt.wantRecords)
+			}
+			first := got.Records[0]
+			if first.Power != tt.wantPower || first.HeartRate != tt.wantHR {
+				t.Errorf("first record power %d, heart rate %d, want %d, %d", first.Power, first.HeartRate, tt.wantPower, tt.wantHR)
+			}
+			if len(got.Sessions) == 0 || got.Activity == nil {
+				t.Errorf("Merge() = %d sessions, activity %v", len(got.Sessions), got.Activity != nil)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	act, err := Trim(ride(t0, 60, 140, 200), Range{To: t0.Add(30 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Encode(act)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if report := validate.Validate(data); !report.Valid {
+		t.Errorf("encoded file is not valid: %+v", report.Issues)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2026-10-18T09:00:00Z", want: t0.Add(time.Hour)},
+		{in: "2026-10-18T10:00:00+01:00", want: t0.Add(time.Hour)},
+		{in: "1h05m", want: t0.Add(65 * time.Minute)},
+		{in: " 90s ", want: t0.Add(90 * time.Second)},
+		{in: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTime(tt.in, t0)
+			if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantField string
+		wantOrder []int
+		wantErr   bool
+	}{
+		{in: "power:1,0", wantField: "power", wantOrder: []int{1, 0}},
+		{in: "heart_rate: 2", wantField: "heart_rate", wantOrder: []int{2}},
+		{in: "power", wantErr: true},
+		{in: ":1", wantErr: true},
+		{in: "power:-1", wantErr: true},
+		{in: "power:first", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			field, order, err := ParsePrecedence(tt.in)
+			if (err != nil) != tt.wantErr || field != tt.wantField || !slices.Equal(order, tt.wantOrder) {
+				t.Errorf("ParsePrecedence(%q) = %q, %v, %v, want %q, %v", tt.in, field, order, err, tt.wantField, tt.wantOrder)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/summary"
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/proto"
+)
+
+// MergeMode decides how the records of several activities are combined.
+type MergeMode string
+
+const (
+	// MergeConcat appends the activities one after another, e.g. the files of a watch that was restarted.
+	// Distances continue from where the previous activity ended.
+	MergeConcat MergeMode = "concat"
+	// MergeInterleave combines activities recorded at the same time by different devices, e.g. watch GPS
+	// and bike computer power, into one record per second.
+	MergeInterleave MergeMode = "interleave"
+)
+
+var ErrNothingToMerge = errors.New("at least two activities with records are needed")
+
+func ParseMergeMode(s string) (MergeMode, error) {
+	switch MergeMode(s) {
+	case "", MergeConcat:
+		return MergeConcat, nil
+	case MergeInterleave:
+		return MergeInterleave, nil
+	}
+	return "", fmt.Errorf("unknown merge mode %q", s)
+}
+
+// MergeOptions configure Merge.
+type MergeOptions struct {
+	Mode MergeMode
+
+	// Precedence lists, by record field name, the indexes of the activities to take the field from first
+	// when records are interleaved. Activities not listed follow in their given order.
+	Precedence map[string][]int
+}
+
+// ParsePrecedence parses a field precedence written as "field:i,j", e.g. "power:1,0".
+func ParsePrecedence(s string) (string, []int, error) {
+	field, list, ok := strings.Cut(s, ":")
+	if !ok || field == "" || list == "" {
+		return "", nil, fmt.Errorf("precedence %q: want field:index,...", s)
+	}
+	var order []int
+	for _, v := range strings.Split(list, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || i < 0 {
+			return "", nil, fmt.Errorf("precedence %q: %q is not an activity index", s, v)
+		}
+		order = append(order, i)
+	}
+	return strings.TrimSpace(field), order, nil
+}
+
+// Merge combines acts into a single activity and recomputes its laps, sessions and activity message.
+//
+// The file_id, developer data and sessions of the first activity are kept, device infos and events
+// of all of them. When concatenating, consecutive sessions of the same sport are joined and every
+// activity starts a new lap. When interleaving, only the first activity's laps are kept, and developer
+// fields of the other activities' records are dropped since their data indexes may collide.
+func Merge(acts []*filedef.Activity, opts MergeOptions) (*filedef.Activity, error) {
+	// Precedence refers to activities by index, so empty ones are only skipped, not removed.
+	withRecords := 0
+	for _, act := range acts {
+		if len(act.Records) > 0 {
+			withRecords++
+		}
+	}
+	if withRecords < 2 {
+		return nil, ErrNothingToMerge
+	}
+
+	var res *filedef.Activity
+	switch opts.Mode {
+	case "", MergeConcat:
+		res = concat(acts)
+	case MergeInterleave:
+		res = interleave(acts, opts.Precedence)
+	default:
+		return nil, fmt.Errorf("unknown merge mode %q", opts.Mode)
+	}
+
+	for _, act := range acts {
+		res.DeviceInfos = append(res.DeviceInfos, act.DeviceInfos...)
+		res.Events = append(res.Events, act.Events...)
+	}
+	slices.SortStableFunc(res.Events, func(a, b *mesgdef.Event) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	summary.Rebuild(res)
+	return res, nil
+}
+
+// base starts the merged activity from the first one.
+func base(first *filedef.Activity) *filedef.Activity {
+	res := filedef.NewActivity()
+	res.FileId = first.FileId
+	res.DeveloperDataIds = first.DeveloperDataIds
+	res.FieldDescriptions = first.FieldDescriptions
+	res.UserProfile = first.UserProfile
+	res.Sports = first.Sports
+	return res
+}
+
+func concat(acts []*filedef.Activity) *filedef.Activity {
+	acts = slices.DeleteFunc(slices.Clone(acts), func(a *filedef.Activity) bool { return len(a.Records) == 0 })
+	slices.SortStableFunc(acts, func(a, b *filedef.Activity) int { return start(a).Compare(start(b)) })
+	res := base(acts[0])
+
+	var offset float64
+	for _, act := range acts {
+		records := slices.Clone(act.Records)
+		slices.SortStableFunc(records, byTimestamp)
+		records = rebase(records)
+
+		last := math.NaN()
+		for _, rec := range records {
+			c := *rec
+			if d := c.DistanceScaled(); !math.IsNaN(d) {
+				c.SetDistanceScaled(d + offset)
+				last = d
+			}
+			res.Records = append(res.Records, &c)
+		}
+		if !math.IsNaN(last) {
+			offset += last
+		}
+
+		// Each activity starts a lap, its own laps are kept as boundaries.
+		res.Laps = append(res.Laps, mesgdef.NewLap(nil).SetStartTime(records[0].Timestamp))
+		res.Laps = append(res.Laps, act.Laps...)
+
+		sessions := act.Sessions
+		if len(sessions) == 0 {
+			sport, subSport := summary.Sport(act)
+			sessions = []*mesgdef.Session{mesgdef.NewSession(nil).
+				SetStartTime(records[0].Timestamp).SetSport(sport).SetSubSport(subSport)}
+		}
+		for _, ses := range sessions {
+			if n := len(res.Sessions); n > 0 && res.Sessions[n-1].Sport == ses.Sport && res.Sessions[n-1].SubSport == ses.SubSport {
+				continue
+			}
+			res.Sessions = append(res.Sessions, ses)
+		}
+	}
+
+	// Overlapping activities record the same seconds twice, the earlier activity wins.
+	slices.SortStableFunc(res.Records, byTimestamp)
+	res.Records = slices.CompactFunc(res.Records, func(a, b *mesgdef.Record) bool { return a.Timestamp.Equal(b.Timestamp) })
+	return res
+}
+
+func interleave(acts []*filedef.Activity, precedence map[string][]int) *filedef.Activity {
+	res := base(acts[0])
+	res.Laps = acts[0].Laps
+	res.Sessions = acts[0].Sessions
+
+	// One slot per second, holding the record of every activity that has one.
+	slots := make(map[int64][]*mesgdef.Record)
+	for i, act := range acts {
+		for _, rec := range act.Records {
+			sec := rec.Timestamp.Unix()
+			if slots[sec] == nil {
+				slots[sec] = make([]*mesgdef.Record, len(acts))
+			}
+			if slots[sec][i] == nil {
+				slots[sec][i] = rec
+			}
+		}
+	}
+
+	for _, sec := range slices.Sorted(maps.Keys(slots)) {
+		res.Records = append(res.Records, mergeRecords(slots[sec], precedence))
+	}
+	return res
+}
+
+// mergeRecords combines records of the same second, taking every field from the first record in
+// precedence order that has a valid value for it.
+func mergeRecords(records []*mesgdef.Record, precedence map[string][]int) *mesgdef.Record {
+	mesgs := make([]*proto.Message, len(records))
+	for i, rec := range records {
+		if rec != nil {
+			m := rec.ToMesg(nil)
+			if i > 0 {
+				m.DeveloperFields = nil
+			}
+			mesgs[i] = &m
+		}
+	}
+
+	merged := proto.Message{Num: typedef.MesgNumRecord}
+	seen := make(map[byte]bool)
+	for _, m := range mesgs {
+		if m == nil {
+			continue
+		}
+		if merged.DeveloperFields == nil {
+			merged.DeveloperFields = m.DeveloperFields
+		}
+		for _, f := range m.Fields {
+			if seen[f.Num] {
+				continue
+			}
+			seen[f.Num] = true
+			merged.Fields = append(merged.Fields, pick(mesgs, f, order(precedence[f.Name], len(mesgs))))
+		}
+	}
+
+	return mesgdef.NewRecord(&merged)
+}
+
+// pick returns the field with field's number from the first message in order that has it.
+func pick(mesgs []*proto.Message, field proto.Field, order []int) proto.Field {
+	for _, i := range order {
+		if mesgs[i] == nil {
+			continue
+		}
+		if f := mesgs[i].FieldByNum(field.Num); f != nil {
+			return *f
+		}
+	}
+	return field
+}
+
+// order puts the listed indexes first, followed by the remaining ones up to n.
+func order(preferred []int, n int) []int {
+	res := make([]int, 0, n)
+	for _, i := range preferred {
+		if i < n && !slices.Contains(res, i) {
+			res = append(res, i)
+		}
+	}
+	for i := range n {
+		if !slices.Contains(res, i) {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+func start(act *filedef.Activity) time.Time {
+	first := act.Records[0].Timestamp
+	for _, rec := range act.Records[1:] {
+		if rec.Timestamp.Before(first) {
+			first = rec.Timestamp
+		}
+	}
+	return first
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+)
+
+// gpxDocument holds what we read from a GPX 1.0 or 1.1 file. Elements are matched by local name,
+// so the namespaces of the different TrackPointExtension versions don't matter.
+type gpxDocument struct {
+	Creator string     `xml:"creator,attr"`
+	Time    string     `xml:"metadata>time"`
+	Tracks  []gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Type     string `xml:"type"`
+	Segments []struct {
+		Points []gpxPoint `xml:"trkpt"`
+	} `xml:"trkseg"`
+}
+
+type gpxPoint struct {
+	Lat        float64  `xml:"lat,attr"`
+	Lon        float64  `xml:"lon,attr"`
+	Ele        *float64 `xml:"ele"`
+	Time       string   `xml:"time"`
+	Extensions struct {
+		TPX struct {
+			HR    *uint8   `xml:"hr"`
+			Cad   *uint8   `xml:"cad"`
+			Temp  *float64 `xml:"atemp"`
+			Speed *float64 `xml:"speed"`
+		} `xml:"TrackPointExtension"`
+		Power *uint16 `xml:"power"` // Written by Strava and others outside of any extension schema
+	} `xml:"extensions"`
+}
+
+// ParseGPX reads the tracks of a GPX file into an activity, one session per track.
+// Track points without a time are skipped, routes and waypoints are ignored.
+func ParseGPX(data []byte) (*filedef.Activity, error) {
+	var doc gpxDocument
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("gpx: %w", err)
+	}
+
+	tracks := make([]track, 0, len(doc.Tracks))
+	for _, trk := range doc.Tracks {
+		t := track{}
+		t.sport, t.subSport = sportFromName(trk.Type)
+		for _, seg := range trk.Segments {
+			for _, pt := range seg.Points {
+				if rec, ok := pt.record(); ok {
+					t.records = append(t.records, rec)
+				}
+			}
+		}
+		tracks = append(tracks, t)
+	}
+
+	created, _ := parseTime(doc.Time)
+	act, err := build(tracks, doc.Creator, created)
+	if err != nil {
+		return nil, fmt.Errorf("gpx: %w", err)
+	}
+	return act, nil
+}
+
+func (pt gpxPoint) record() (*mesgdef.Record, bool) {
+	t, ok := parseTime(pt.Time)
+	if !ok {
+		return nil, false
+	}
+
+	rec := mesgdef.NewRecord(nil).SetTimestamp(t)
+	if pt.Lat != 0 || pt.Lon != 0 {
+		rec.SetPositionLatDegrees(pt.Lat).SetPositionLongDegrees(pt.Lon)
+	}
+	if pt.Ele != nil {
+		rec.SetEnhancedAltitudeScaled(*pt.Ele)
+	}
+
+	tpx := pt.Extensions.TPX
+	if tpx.HR != nil {
+		rec.SetHeartRate(*tpx.HR)
+	}
+	if tpx.Cad != nil {
+		rec.SetCadence(*tpx.Cad)
+	}
+	if tpx.Temp != nil {
+		rec.SetTemperature(int8(math.Round(*tpx.Temp)))
+	}
+	if tpx.Speed != nil {
+		rec.SetEnhancedSpeedScaled(*tpx.Speed)
+	}
+	if pt.Extensions.Power != nil {
+		rec.SetPower(*pt.Extensions.Power)
+	}
+	return rec, true
+}
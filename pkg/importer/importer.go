@@ -0,0 +1,220 @@
+// Package importer turns GPX and TCX files into FIT activities, so everything that reads FIT
+// (the converters, lap enrichment, analytics, editing) works on them as well.
+//
+// Track points become records, tracks and TCX activities become sessions and TCX laps become laps.
+// Laps, sessions and the activity message are computed from the records like for an edited activity.
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/kyzrfranz/go-fitter/pkg/summary"
+	"github.com/muktihari/fit/encoder"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/proto"
+)
+
+var ErrNoTrackPoints = errors.New("file contains no track points with a time")
+
+// Format is the file format of an upload, detected by its content.
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatFIT
+	FormatGPX
+	FormatTCX
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatFIT:
+		return "fit"
+	case FormatGPX:
+		return "gpx"
+	case FormatTCX:
+		return "tcx"
+	default:
+		return "unknown"
+	}
+}
+
+// Detect returns the format of data: FIT by its file header, GPX and TCX by their XML root element.
+func Detect(data []byte) Format {
+	if len(data) >= 12 && (data[0] == 12 || data[0] == 14) && string(data[8:12]) == proto.DataTypeFIT {
+		return FormatFIT
+	}
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		return FormatUnknown
+	}
+	dec := xml.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return FormatUnknown
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			switch el.Name.Local {
+			case "gpx":
+				return FormatGPX
+			case "TrainingCenterDatabase":
+				return FormatTCX
+			}
+			return FormatUnknown
+		}
+	}
+}
+
+// ToFIT converts GPX and TCX data to a FIT activity file. FIT data, and anything not recognized,
+// is returned as is and left to the FIT decoder.
+func ToFIT(data []byte) ([]byte, error) {
+	var (
+		act *filedef.Activity
+		err error
+	)
+	switch Detect(data) {
+	case FormatGPX:
+		act, err = ParseGPX(data)
+	case FormatTCX:
+		act, err = ParseTCX(data)
+	default:
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fit := act.ToFIT(nil)
+	var buf bytes.Buffer
+	if err := activity.Encode(encoder.New(&buf), &fit); err != nil {
+		return nil, fmt.Errorf("encode failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// track is what both formats are parsed into: the records of one session and where its laps start.
+type track struct {
+	sport     typedef.Sport
+	subSport  typedef.SubSport
+	lapStarts []time.Time
+	records   []*mesgdef.Record
+}
+
+// build assembles an activity from tracks, recorded by creator at created (both may be empty).
+func build(tracks []track, creator string, created time.Time) (*filedef.Activity, error) {
+	act := filedef.NewActivity()
+	for _, t := range tracks {
+		if len(t.records) == 0 {
+			continue
+		}
+		slices.SortStableFunc(t.records, func(a, b *mesgdef.Record) int { return a.Timestamp.Compare(b.Timestamp) })
+		act.Sessions = append(act.Sessions, mesgdef.NewSession(nil).
+			SetStartTime(t.records[0].Timestamp).
+			SetSport(t.sport).
+			SetSubSport(t.subSport))
+		for _, start := range t.lapStarts {
+			act.Laps = append(act.Laps, mesgdef.NewLap(nil).SetStartTime(start))
+		}
+		act.Records = append(act.Records, t.records...)
+	}
+	if len(act.Records) == 0 {
+		return nil, ErrNoTrackPoints
+	}
+	slices.SortStableFunc(act.Records, func(a, b *mesgdef.Record) int { return a.Timestamp.Compare(b.Timestamp) })
+	addDistances(act.Records)
+
+	first, last := act.Records[0].Timestamp, act.Records[len(act.Records)-1].Timestamp
+	if created.IsZero() {
+		created = first
+	}
+	act.FileId.
+		SetType(typedef.FileActivity).
+		SetManufacturer(typedef.ManufacturerDevelopment).
+		SetTimeCreated(created)
+	if creator != "" {
+		act.FileId.SetProductName(creator)
+	}
+	act.Events = []*mesgdef.Event{
+		timerEvent(first, typedef.EventTypeStart),
+		timerEvent(last, typedef.EventTypeStopAll),
+	}
+
+	summary.Rebuild(act)
+	return act, nil
+}
+
+// addDistances fills in the distance of records that have none, GPX never has any and TCX only
+// sometimes. Records with a distance keep it, the ones after continue from there along the positions.
+func addDistances(records []*mesgdef.Record) {
+	var (
+		dist             float64
+		prevLat, prevLon int32
+		hasPrev          bool
+	)
+	for _, rec := range records {
+		hasPos := rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid
+		if hasPos && hasPrev {
+			dist += summary.Distance(prevLat, prevLon, rec.PositionLat, rec.PositionLong)
+		}
+		if d := rec.DistanceScaled(); !math.IsNaN(d) {
+			dist = d
+		} else if hasPos || hasPrev {
+			rec.SetDistanceScaled(dist)
+		}
+		if hasPos {
+			prevLat, prevLon, hasPrev = rec.PositionLat, rec.PositionLong, true
+		}
+	}
+}
+
+func timerEvent(t time.Time, eventType typedef.EventType) *mesgdef.Event {
+	return mesgdef.NewEvent(nil).
+		SetTimestamp(t).
+		SetEvent(typedef.EventTimer).
+		SetEventType(eventType)
+}
+
+// sportFromName maps the free-form activity types of GPX and the sports of TCX to FIT sports.
+func sportFromName(name string) (typedef.Sport, typedef.SubSport) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if sport := typedef.SportFromString(name); sport != typedef.SportInvalid {
+		return sport, typedef.SubSportGeneric
+	}
+	switch name {
+	case "run", "trail_run", "trailrun":
+		return typedef.SportRunning, typedef.SubSportGeneric
+	case "ride", "biking", "bike", "virtualride":
+		return typedef.SportCycling, typedef.SubSportGeneric
+	case "swim":
+		return typedef.SportSwimming, typedef.SubSportGeneric
+	case "hike":
+		return typedef.SportHiking, typedef.SubSportGeneric
+	case "walk":
+		return typedef.SportWalking, typedef.SubSportGeneric
+	}
+	return typedef.SportGeneric, typedef.SubSportGeneric
+}
+
+// parseTime parses the xsd:dateTime of both formats, which may come without a zone, meaning UTC.
+func parseTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
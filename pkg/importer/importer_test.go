@@ -0,0 +1,193 @@
+package importer
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/validate"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+const gpx = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="Test Watch" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+  <metadata><time>2026-10-18T07:59:00Z</time></metadata>
+  <trk>
+    <type>run</type>
+    <trkseg>
+      <trkpt lat="48.000" lon="16.0"><ele>200</ele><time>2026-10-18T08:00:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr><gpxtpx:cad>80</gpxtpx:cad></gpxtpx:TrackPointExtension></extensions>
+      </trkpt>
+      <trkpt lat="48.001" lon="16.0"><time>2026-10-18T08:00:30</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>140</gpxtpx:hr></gpxtpx:TrackPointExtension><power>250</power></extensions>
+      </trkpt>
+      <trkpt lat="48.002" lon="16.0"></trkpt>
+      <trkpt lat="48.002" lon="16.0"><time>2026-10-18T08:01:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+const tcx = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"
+  xmlns:ns3="http://www.garmin.com/xmlschemas/ActivityExtension/v2">
+  <Activities>
+    <Activity Sport="Biking">
+      <Id>2026-10-18T08:00:00Z</Id>
+      <Lap StartTime="2026-10-18T08:00:00Z">
+        <Track>
+          <Trackpoint><Time>2026-10-18T08:00:00Z</Time><DistanceMeters>0</DistanceMeters>
+            <HeartRateBpm><Value>130</Value></HeartRateBpm>
+            <Extensions><ns3:TPX><ns3:Watts>200</ns3:Watts></ns3:TPX></Extensions></Trackpoint>
+          <Trackpoint><Time>2026-10-18T08:01:00Z</Time><DistanceMeters>500</DistanceMeters></Trackpoint>
+        </Track>
+      </Lap>
+      <Lap StartTime="2026-10-18T08:01:00Z">
+        <Track>
+          <Trackpoint><Time>2026-10-18T08:02:00Z</Time><DistanceMeters>1000</DistanceMeters></Trackpoint>
+        </Track>
+      </Lap>
+      <Creator><Name>Test Bike</Name></Creator>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Format
+	}{
+		{name: "fit", data: "\x0e\x10\x00\x00\x00\x00\x00\x00.FIT\x00\x00", want: FormatFIT},
+		{name: "gpx", data: gpx, want: FormatGPX},
+		{name: "tcx", data: tcx, want: FormatTCX},
+		{name: "byte order mark and whitespace", data: "\xef\xbb\xbf\n  <gpx></gpx>", want: FormatGPX},
+		{name: "other xml", data: "<kml></kml>", want: FormatUnknown},
+		{name: "not xml", data: "hello", want: FormatUnknown},
+		{name: "empty", data: "", want: FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.data)); got != tt.want {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantRecords  int
+		wantLaps     int
+		wantSport    typedef.Sport
+		wantCreated  time.Time
+		wantProduct  string
+		wantDistance float64 // metres of the last record
+		wantPower    uint16  // of the second record
+		wantErr      error
+	}{
+		{
+			name:         "gpx",
+			data:         gpx,
+			wantRecords:  3,
+			wantLaps:     1,
+			wantSport:    typedef.SportRunning,
+			wantCreated:  time.Date(2026, 10, 18, 7, 59, 0, 0, time.UTC),
+			wantProduct:  "Test Watch",
+			wantDistance: 222,
+			wantPower:    250,
+		},
+		{
+			name:         "tcx",
+			data:         tcx,
+			wantRecords:  3,
+			wantLaps:     2,
+			wantSport:    typedef.SportCycling,
+			wantCreated:  time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+			wantProduct:  "Test Bike",
+			wantDistance: 1000,
+			wantPower:    0xFFFF,
+		},
+		{
+			name:    "gpx without times",
+			data:    `<gpx><trk><trkseg><trkpt lat="48" lon="16"></trkpt></trkseg></trk></gpx>`,
+			wantErr: ErrNoTrackPoints,
+		},
+		{
+			name:    "tcx without activities",
+			data:    `<TrainingCenterDatabase></TrainingCenterDatabase>`,
+			wantErr: ErrNoTrackPoints,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := ParseGPX
+			if Detect([]byte(tt.data)) == FormatTCX {
+				parse = ParseTCX
+			}
+			act, err := parse([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parse error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(act.Records) != tt.wantRecords || len(act.Laps) != tt.wantLaps || len(act.Sessions) != 1 {
+				t.Fatalf("%d records, %d laps, %d sessions, want %d, %d, 1", len(act.Records), len(act.Laps), len(act.Sessions), tt.wantRecords, tt.wantLaps)
+			}
+			if sport := act.Sessions[0].Sport; sport != tt.wantSport {
+				t.Errorf("sport = %v, want %v", sport, tt.wantSport)
+			}
+			if created := act.FileId.TimeCreated; !created.Equal(tt.wantCreated) {
+				t.Errorf("time_created = %v, want %v", created, tt.wantCreated)
+			}
+			if act.FileId.ProductName != tt.wantProduct {
+				t.Errorf("product_name = %q, want %q", act.FileId.ProductName, tt.wantProduct)
+			}
+			if d := act.Records[len(act.Records)-1].DistanceScaled(); math.Abs(d-tt.wantDistance) > 1 {
+				t.Errorf("distance = %g, want %g", d, tt.wantDistance)
+			}
+			if p := act.Records[1].Power; p != tt.wantPower {
+				t.Errorf("power = %d, want %d", p, tt.wantPower)
+			}
+		})
+	}
+}
+
+func TestToFIT(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantFIT bool // a new file is written, not the input passed through
+		wantErr bool
+	}{
+		{name: "gpx", data: gpx, wantFIT: true},
+		{name: "tcx", data: tcx, wantFIT: true},
+		{name: "broken gpx", data: `<gpx><trk>`, wantErr: true},
+		{name: "anything else is passed through", data: "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToFIT([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToFIT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !tt.wantFIT {
+				if string(got) != tt.data {
+					t.Errorf("ToFIT() = %q, want the input", got)
+				}
+				return
+			}
+			if report := validate.Validate(got); !report.Valid {
+				t.Errorf("ToFIT() is not a valid FIT file: %+v", report.Issues)
+			}
+		})
+	}
+}
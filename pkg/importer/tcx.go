@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+)
+
+// tcxDocument holds what we read from a Garmin Training Center Database v2 file, including the speed,
+// power and running cadence of the ActivityExtension v2 schema.
+type tcxDocument struct {
+	Activities []struct {
+		Sport   string   `xml:"Sport,attr"`
+		ID      string   `xml:"Id"`
+		Creator string   `xml:"Creator>Name"`
+		Laps    []tcxLap `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+type tcxLap struct {
+	StartTime string     `xml:"StartTime,attr"`
+	Points    []tcxPoint `xml:"Track>Trackpoint"`
+}
+
+type tcxPoint struct {
+	Time     string   `xml:"Time"`
+	Lat      *float64 `xml:"Position>LatitudeDegrees"`
+	Lon      *float64 `xml:"Position>LongitudeDegrees"`
+	Altitude *float64 `xml:"AltitudeMeters"`
+	Distance *float64 `xml:"DistanceMeters"`
+	HR       *uint8   `xml:"HeartRateBpm>Value"`
+	Cadence  *uint8   `xml:"Cadence"`
+	TPX      struct {
+		Speed      *float64 `xml:"Speed"`
+		Watts      *uint16  `xml:"Watts"`
+		RunCadence *uint8   `xml:"RunCadence"`
+	} `xml:"Extensions>TPX"`
+}
+
+// ParseTCX reads the activities of a TCX file into an activity, one session per TCX activity and one
+// lap per TCX lap. Trackpoints without a time are skipped, courses and workouts are ignored.
+func ParseTCX(data []byte) (*filedef.Activity, error) {
+	var doc tcxDocument
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("tcx: %w", err)
+	}
+
+	var creator string
+	tracks := make([]track, 0, len(doc.Activities))
+	for _, a := range doc.Activities {
+		t := track{}
+		t.sport, t.subSport = sportFromName(a.Sport)
+		for _, lap := range a.Laps {
+			if start, ok := parseTime(lap.StartTime); ok {
+				t.lapStarts = append(t.lapStarts, start)
+			}
+			for _, pt := range lap.Points {
+				if rec, ok := pt.record(); ok {
+					t.records = append(t.records, rec)
+				}
+			}
+		}
+		tracks = append(tracks, t)
+		if creator == "" {
+			creator = a.Creator
+		}
+	}
+
+	// The Id of a TCX activity is its start time.
+	var created time.Time
+	if len(doc.Activities) > 0 {
+		created, _ = parseTime(doc.Activities[0].ID)
+	}
+	act, err := build(tracks, creator, created)
+	if err != nil {
+		return nil, fmt.Errorf("tcx: %w", err)
+	}
+	return act, nil
+}
+
+func (pt tcxPoint) record() (*mesgdef.Record, bool) {
+	t, ok := parseTime(pt.Time)
+	if !ok {
+		return nil, false
+	}
+
+	rec := mesgdef.NewRecord(nil).SetTimestamp(t)
+	if pt.Lat != nil && pt.Lon != nil {
+		rec.SetPositionLatDegrees(*pt.Lat).SetPositionLongDegrees(*pt.Lon)
+	}
+	if pt.Altitude != nil {
+		rec.SetEnhancedAltitudeScaled(*pt.Altitude)
+	}
+	if pt.Distance != nil {
+		rec.SetDistanceScaled(*pt.Distance)
+	}
+	if pt.HR != nil {
+		rec.SetHeartRate(*pt.HR)
+	}
+	switch {
+	case pt.Cadence != nil:
+		rec.SetCadence(*pt.Cadence)
+	case pt.TPX.RunCadence != nil:
+		rec.SetCadence(*pt.TPX.RunCadence)
+	}
+	if pt.TPX.Speed != nil {
+		rec.SetEnhancedSpeedScaled(*pt.TPX.Speed)
+	}
+	if pt.TPX.Watts != nil {
+		rec.SetPower(*pt.TPX.Watts)
+	}
+	return rec, true
+}
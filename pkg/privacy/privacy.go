@@ -0,0 +1,300 @@
+// Package privacy hides positions an athlete doesn't want to share: everything inside privacy zones
+// and the first and last metres of a track.
+package privacy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/kyzrfranz/go-fitter/pkg/summary"
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/encoder"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/fieldnum"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+	"github.com/muktihari/fit/proto"
+)
+
+// Mode decides what happens to positions inside a privacy zone.
+type Mode string
+
+const (
+	ModeRemove Mode = "remove" // drop the position fields
+	ModeFuzz   Mode = "fuzz"   // snap positions to a grid as coarse as the zone's radius
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeRemove:
+		return ModeRemove, nil
+	case ModeFuzz:
+		return ModeFuzz, nil
+	}
+	return "", fmt.Errorf("unknown privacy mode %q", s)
+}
+
+// Policy describes which positions to hide. The hidden start and end of a track are always removed,
+// Mode only applies to zones.
+type Policy struct {
+	Zones     []Zone  `json:"zones,omitempty"`
+	HideStart float64 `json:"hide_start,omitempty"` // metres of track to hide after the first position
+	HideEnd   float64 `json:"hide_end,omitempty"`   // metres of track to hide before the last position
+	Mode      Mode    `json:"mode,omitempty"`
+}
+
+// Empty reports whether p leaves every position untouched.
+func (p Policy) Empty() bool {
+	return len(p.Zones) == 0 && p.HideStart <= 0 && p.HideEnd <= 0
+}
+
+// Redact decodes every FIT sequence from r, applies p to its messages and encodes them again.
+func Redact(r io.Reader, p Policy, decoderOptions ...decoder.Option) ([]byte, error) {
+	dec := decoder.New(r, decoderOptions...)
+
+	var buf bytes.Buffer
+	enc := encoder.New(&buf)
+	for dec.Next() {
+		fit, err := dec.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("decode failed: %w", err)
+		}
+		p.Apply(fit.Messages)
+		if err := activity.Encode(enc, fit); err != nil {
+			return nil, fmt.Errorf("encode failed: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// position is a pair of latitude and longitude fields of a message.
+type position struct{ lat, lon byte }
+
+// summaryFields are the position fields of a message summarizing the records between its start time and timestamp.
+type summaryFields struct {
+	startTime  byte
+	start, end position
+	nec, swc   *position
+}
+
+var summaries = map[typedef.MesgNum]summaryFields{
+	mesgnum.Lap: {
+		startTime: fieldnum.LapStartTime,
+		start:     position{fieldnum.LapStartPositionLat, fieldnum.LapStartPositionLong},
+		end:       position{fieldnum.LapEndPositionLat, fieldnum.LapEndPositionLong},
+	},
+	mesgnum.Session: {
+		startTime: fieldnum.SessionStartTime,
+		start:     position{fieldnum.SessionStartPositionLat, fieldnum.SessionStartPositionLong},
+		end:       position{fieldnum.SessionEndPositionLat, fieldnum.SessionEndPositionLong},
+		nec:       &position{fieldnum.SessionNecLat, fieldnum.SessionNecLong},
+		swc:       &position{fieldnum.SessionSwcLat, fieldnum.SessionSwcLong},
+	},
+	mesgnum.SegmentLap: {
+		startTime: fieldnum.SegmentLapStartTime,
+		start:     position{fieldnum.SegmentLapStartPositionLat, fieldnum.SegmentLapStartPositionLong},
+		end:       position{fieldnum.SegmentLapEndPositionLat, fieldnum.SegmentLapEndPositionLong},
+		nec:       &position{fieldnum.SegmentLapNecLat, fieldnum.SegmentLapNecLong},
+		swc:       &position{fieldnum.SegmentLapSwcLat, fieldnum.SegmentLapSwcLong},
+	},
+}
+
+// points are other messages with a position of their own, they are only checked against the zones.
+var points = map[typedef.MesgNum]position{
+	mesgnum.GpsMetadata: {fieldnum.GpsMetadataPositionLat, fieldnum.GpsMetadataPositionLong},
+	mesgnum.CoursePoint: {fieldnum.CoursePointPositionLat, fieldnum.CoursePointPositionLong},
+}
+
+// trackPoint is a record position after redaction.
+type trackPoint struct {
+	timestamp uint32
+	lat, lon  int32
+	shown     bool // the record still has a position
+	changed   bool // the position was removed or fuzzed
+}
+
+// Apply redacts the messages of one FIT sequence in place. Records are redacted first, laps and sessions
+// then take their start, end and bounding box from the records that are still shown.
+func (p Policy) Apply(mesgs []proto.Message) {
+	if p.Empty() {
+		return
+	}
+
+	track := p.redactRecords(mesgs)
+	for i := range mesgs {
+		m := &mesgs[i]
+		if f, ok := summaries[m.Num]; ok {
+			p.redactSummary(m, f, track)
+		} else if pos, ok := points[m.Num]; ok {
+			p.redactPoint(m, pos)
+		}
+	}
+}
+
+func (p Policy) redactRecords(mesgs []proto.Message) []trackPoint {
+	recordPosition := position{fieldnum.RecordPositionLat, fieldnum.RecordPositionLong}
+
+	var (
+		idx   []int
+		track []trackPoint
+		dist  []float64
+		total float64
+	)
+	for i := range mesgs {
+		if mesgs[i].Num != mesgnum.Record {
+			continue
+		}
+		lat, lon, ok := get(&mesgs[i], recordPosition)
+		if !ok {
+			continue
+		}
+		if n := len(track); n > 0 {
+			total += summary.Distance(track[n-1].lat, track[n-1].lon, lat, lon)
+		}
+		idx = append(idx, i)
+		track = append(track, trackPoint{timestamp: timestamp(&mesgs[i]), lat: lat, lon: lon, shown: true})
+		dist = append(dist, total)
+	}
+
+	for j := range track {
+		pt := &track[j]
+		m := &mesgs[idx[j]]
+		zone, inZone := p.zone(pt.lat, pt.lon)
+		switch {
+		case dist[j] < p.HideStart || total-dist[j] < p.HideEnd || (inZone && p.Mode != ModeFuzz):
+			remove(m, recordPosition)
+			pt.shown, pt.changed = false, true
+		case inZone:
+			pt.lat, pt.lon = fuzz(pt.lat, pt.lon, zone.Radius)
+			set(m, recordPosition, pt.lat, pt.lon)
+			pt.changed = true
+		}
+	}
+	return track
+}
+
+func (p Policy) redactSummary(m *proto.Message, f summaryFields, track []trackPoint) {
+	// Without any record positions there is nothing to derive from, treat them as plain points.
+	if len(track) == 0 {
+		for _, pos := range []*position{&f.start, &f.end, f.nec, f.swc} {
+			if pos != nil {
+				p.redactPoint(m, *pos)
+			}
+		}
+		return
+	}
+
+	from, to := m.FieldValueByNum(f.startTime).Uint32(), timestamp(m)
+	var (
+		shown   []trackPoint
+		changed bool
+	)
+	for _, pt := range track {
+		if pt.timestamp < from || pt.timestamp > to {
+			continue
+		}
+		changed = changed || pt.changed
+		if pt.shown {
+			shown = append(shown, pt)
+		}
+	}
+
+	if changed || p.hidden(m, f.start) || p.hidden(m, f.end) {
+		if len(shown) == 0 {
+			remove(m, f.start)
+			remove(m, f.end)
+		} else {
+			set(m, f.start, shown[0].lat, shown[0].lon)
+			set(m, f.end, shown[len(shown)-1].lat, shown[len(shown)-1].lon)
+		}
+	}
+
+	if f.nec == nil || !(changed || p.hidden(m, *f.nec) || p.hidden(m, *f.swc)) {
+		return
+	}
+	if len(shown) == 0 {
+		remove(m, *f.nec)
+		remove(m, *f.swc)
+		return
+	}
+	necLat, necLon, swcLat, swcLon := shown[0].lat, shown[0].lon, shown[0].lat, shown[0].lon
+	for _, pt := range shown[1:] {
+		necLat, necLon = max(necLat, pt.lat), max(necLon, pt.lon)
+		swcLat, swcLon = min(swcLat, pt.lat), min(swcLon, pt.lon)
+	}
+	set(m, *f.nec, necLat, necLon)
+	set(m, *f.swc, swcLat, swcLon)
+}
+
+func (p Policy) redactPoint(m *proto.Message, pos position) {
+	lat, lon, ok := get(m, pos)
+	if !ok {
+		return
+	}
+	if zone, inZone := p.zone(lat, lon); inZone {
+		if p.Mode == ModeFuzz {
+			lat, lon = fuzz(lat, lon, zone.Radius)
+			set(m, pos, lat, lon)
+		} else {
+			remove(m, pos)
+		}
+	}
+}
+
+// hidden reports whether the position pos of m lies within a zone.
+func (p Policy) hidden(m *proto.Message, pos position) bool {
+	lat, lon, ok := get(m, pos)
+	if !ok {
+		return false
+	}
+	_, inZone := p.zone(lat, lon)
+	return inZone
+}
+
+func (p Policy) zone(lat, lon int32) (Zone, bool) {
+	for _, z := range p.Zones {
+		if z.Contains(lat, lon) {
+			return z, true
+		}
+	}
+	return Zone{}, false
+}
+
+// fuzz snaps a position to the centre of its cell in a grid of cells size metres wide.
+func fuzz(lat, lon int32, size float64) (int32, int32) {
+	const metresPerDegree = 111195.0
+	latCell := size / metresPerDegree * semicirclesPerDegree
+	lonCell := latCell / max(math.Cos(float64(lat)/semicirclesPerDegree*math.Pi/180), 0.01)
+	snap := func(v int32, cell float64) int32 {
+		return int32(math.Floor(float64(v)/cell)*cell + cell/2)
+	}
+	return snap(lat, latCell), snap(lon, lonCell)
+}
+
+func get(m *proto.Message, pos position) (lat, lon int32, ok bool) {
+	lat = m.FieldValueByNum(pos.lat).Int32()
+	lon = m.FieldValueByNum(pos.lon).Int32()
+	return lat, lon, lat != basetype.Sint32Invalid && lon != basetype.Sint32Invalid
+}
+
+// set only updates fields that are present, it never adds any.
+func set(m *proto.Message, pos position, lat, lon int32) {
+	if f := m.FieldByNum(pos.lat); f != nil {
+		f.Value = proto.Int32(lat)
+	}
+	if f := m.FieldByNum(pos.lon); f != nil {
+		f.Value = proto.Int32(lon)
+	}
+}
+
+func remove(m *proto.Message, pos position) {
+	m.RemoveFieldByNum(pos.lat)
+	m.RemoveFieldByNum(pos.lon)
+}
+
+func timestamp(m *proto.Message) uint32 {
+	return m.FieldValueByNum(proto.FieldNumTimestamp).Uint32()
+}
@@ -0,0 +1,199 @@
+package privacy
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/untyped/fieldnum"
+	"github.com/muktihari/fit/proto"
+)
+
+const (
+	trackLat  = 48.0
+	trackLon  = 16.0
+	trackStep = 0.001 // degrees of latitude between records, about 111 m
+	trackLen  = 11
+)
+
+var trackStart = time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+// track returns a record every 111 m going north, followed by a lap and a session over all of them.
+func track() []proto.Message {
+	var mesgs []proto.Message
+	for i := range trackLen {
+		mesgs = append(mesgs, mesgdef.NewRecord(nil).
+			SetTimestamp(trackStart.Add(time.Duration(i)*time.Second)).
+			SetPositionLatDegrees(trackLat+float64(i)*trackStep).
+			SetPositionLongDegrees(trackLon).
+			ToMesg(nil))
+	}
+	end := trackStart.Add((trackLen - 1) * time.Second)
+	first, last := lat(&mesgs[0], fieldnum.RecordPositionLat), lat(&mesgs[trackLen-1], fieldnum.RecordPositionLat)
+	lon := lat(&mesgs[0], fieldnum.RecordPositionLong)
+	mesgs = append(mesgs,
+		mesgdef.NewLap(nil).SetStartTime(trackStart).SetTimestamp(end).
+			SetStartPositionLat(first).SetStartPositionLong(lon).SetEndPositionLat(last).SetEndPositionLong(lon).
+			ToMesg(nil),
+		mesgdef.NewSession(nil).SetStartTime(trackStart).SetTimestamp(end).
+			SetStartPositionLat(first).SetStartPositionLong(lon).SetEndPositionLat(last).SetEndPositionLong(lon).
+			SetNecLat(last).SetNecLong(lon).SetSwcLat(first).SetSwcLong(lon).
+			ToMesg(nil))
+	return mesgs
+}
+
+func lat(m *proto.Message, num byte) int32 {
+	return m.FieldValueByNum(num).Int32()
+}
+
+// pointLat is the latitude of the i-th record of track in semicircles.
+func pointLat(i int) int32 {
+	return int32((trackLat + float64(i)*trackStep) * semicirclesPerDegree)
+}
+
+func TestApply(t *testing.T) {
+	invalid := int32(basetype.Sint32Invalid)
+	tests := []struct {
+		name         string
+		policy       Policy
+		wantShown    []int // records that keep a position
+		wantMoved    []int // records whose position changed
+		wantLapStart int32
+		wantLapEnd   int32
+		wantNecLat   int32
+	}{
+		{
+			name:         "empty policy",
+			policy:       Policy{},
+			wantShown:    []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			wantLapStart: pointLat(0), wantLapEnd: pointLat(10), wantNecLat: pointLat(10),
+		},
+		{
+			name:         "hide start",
+			policy:       Policy{HideStart: 200},
+			wantShown:    []int{2, 3, 4, 5, 6, 7, 8, 9, 10},
+			wantLapStart: pointLat(2), wantLapEnd: pointLat(10), wantNecLat: pointLat(10),
+		},
+		{
+			name:         "hide end",
+			policy:       Policy{HideEnd: 200},
+			wantShown:    []int{0, 1, 2, 3, 4, 5, 6, 7, 8},
+			wantLapStart: pointLat(0), wantLapEnd: pointLat(8), wantNecLat: pointLat(8),
+		},
+		{
+			name:         "zone in the middle",
+			policy:       Policy{Zones: []Zone{{Lat: trackLat + 5*trackStep, Lon: trackLon, Radius: 150}}},
+			wantShown:    []int{0, 1, 2, 3, 7, 8, 9, 10},
+			wantLapStart: pointLat(0), wantLapEnd: pointLat(10), wantNecLat: pointLat(10),
+		},
+		{
+			name:         "zone at the start",
+			policy:       Policy{Zones: []Zone{{Lat: trackLat, Lon: trackLon, Radius: 50}}},
+			wantShown:    []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			wantLapStart: pointLat(1), wantLapEnd: pointLat(10), wantNecLat: pointLat(10),
+		},
+		{
+			name:         "everything hidden",
+			policy:       Policy{Zones: []Zone{{Lat: trackLat + 5*trackStep, Lon: trackLon, Radius: 1000}}},
+			wantShown:    nil,
+			wantLapStart: invalid, wantLapEnd: invalid, wantNecLat: invalid,
+		},
+		{
+			name:         "fuzzed zone",
+			policy:       Policy{Mode: ModeFuzz, Zones: []Zone{{Lat: trackLat + 10*trackStep, Lon: trackLon, Radius: 150}}},
+			wantShown:    []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			wantMoved:    []int{9, 10},
+			wantLapStart: pointLat(0), wantLapEnd: lat(fuzzed(10), fieldnum.RecordPositionLat),
+			wantNecLat: max(lat(fuzzed(9), fieldnum.RecordPositionLat), lat(fuzzed(10), fieldnum.RecordPositionLat)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mesgs := track()
+			tt.policy.Apply(mesgs)
+
+			for i := range trackLen {
+				got := lat(&mesgs[i], fieldnum.RecordPositionLat)
+				shown, moved := slices.Contains(tt.wantShown, i), slices.Contains(tt.wantMoved, i)
+				switch {
+				case !shown && got != invalid:
+					t.Errorf("record %d has a position", i)
+				case shown && got == invalid:
+					t.Errorf("record %d has no position", i)
+				case shown && moved == (got == pointLat(i)):
+					t.Errorf("record %d at %d, moved = %v", i, got, moved)
+				}
+			}
+
+			lap, session := &mesgs[trackLen], &mesgs[trackLen+1]
+			if got := lat(lap, fieldnum.LapStartPositionLat); got != tt.wantLapStart {
+				t.Errorf("lap start = %d, want %d", got, tt.wantLapStart)
+			}
+			if got := lat(lap, fieldnum.LapEndPositionLat); got != tt.wantLapEnd {
+				t.Errorf("lap end = %d, want %d", got, tt.wantLapEnd)
+			}
+			if got := lat(session, fieldnum.SessionNecLat); got != tt.wantNecLat {
+				t.Errorf("session nec = %d, want %d", got, tt.wantNecLat)
+			}
+		})
+	}
+}
+
+// fuzzed returns the i-th record of track after fuzzing with a 150 m grid.
+func fuzzed(i int) *proto.Message {
+	m := track()[i]
+	la, lo := fuzz(lat(&m, fieldnum.RecordPositionLat), lat(&m, fieldnum.RecordPositionLong), 150)
+	set(&m, position{fieldnum.RecordPositionLat, fieldnum.RecordPositionLong}, la, lo)
+	return &m
+}
+
+func TestParseZones(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Zone
+		wantErr bool
+	}{
+		{name: "one", in: "48.2,16.37,500", want: []Zone{{48.2, 16.37, 500}}},
+		{name: "several with spaces", in: "48.2, 16.37, 500; -33.9,151.2,200;", want: []Zone{{48.2, 16.37, 500}, {-33.9, 151.2, 200}}},
+		{name: "empty", in: "", want: nil},
+		{name: "missing radius", in: "48.2,16.37", wantErr: true},
+		{name: "not a number", in: "48.2,east,500", wantErr: true},
+		{name: "latitude out of range", in: "91,16.37,500", wantErr: true},
+		{name: "radius not positive", in: "48.2,16.37,0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseZones(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseZones(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseZones(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mode
+		wantErr bool
+	}{
+		{in: "", want: ModeRemove},
+		{in: "remove", want: ModeRemove},
+		{in: "fuzz", want: ModeFuzz},
+		{in: "blur", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMode(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseMode(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package privacy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kyzrfranz/go-fitter/pkg/summary"
+)
+
+const semicirclesPerDegree = (1 << 31) / 180.0
+
+// Zone is a circular area, e.g. around an athlete's home, whose positions are never shown.
+type Zone struct {
+	Lat    float64 `json:"lat"`    // degrees
+	Lon    float64 `json:"lon"`    // degrees
+	Radius float64 `json:"radius"` // metres
+}
+
+// ParseZone parses a zone written as "lat,lon,radius", with the position in degrees and the radius in metres.
+func ParseZone(s string) (Zone, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return Zone{}, fmt.Errorf("zone %q: want lat,lon,radius", s)
+	}
+	var v [3]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return Zone{}, fmt.Errorf("zone %q: %w", s, err)
+		}
+		v[i] = f
+	}
+	z := Zone{Lat: v[0], Lon: v[1], Radius: v[2]}
+	if math.Abs(z.Lat) > 90 || math.Abs(z.Lon) > 180 || z.Radius <= 0 {
+		return Zone{}, fmt.Errorf("zone %q: position out of range or radius not positive", s)
+	}
+	return z, nil
+}
+
+// ParseZones parses a list of zones separated by semicolons, e.g. "48.2,16.37,500;48.1,16.3,200".
+func ParseZones(s string) ([]Zone, error) {
+	var zones []Zone
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		z, err := ParseZone(part)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return zones, nil
+}
+
+func (z Zone) String() string {
+	return strconv.FormatFloat(z.Lat, 'f', -1, 64) + "," +
+		strconv.FormatFloat(z.Lon, 'f', -1, 64) + "," +
+		strconv.FormatFloat(z.Radius, 'f', -1, 64)
+}
+
+// Contains reports whether the position, in semicircles, lies within z.
+func (z Zone) Contains(lat, lon int32) bool {
+	zlat, zlon := z.semicircles()
+	return summary.Distance(zlat, zlon, lat, lon) <= z.Radius
+}
+
+func (z Zone) semicircles() (int32, int32) {
+	return int32(z.Lat * semicirclesPerDegree), int32(z.Lon * semicirclesPerDegree)
+}
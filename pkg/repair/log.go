@@ -0,0 +1,41 @@
+package repair
+
+// Log entry codes.
+const (
+	CodeHeaderInvalid   = "header_invalid"
+	CodeTruncated       = "truncated"
+	CodeCRCMismatch     = "crc_mismatch"
+	CodeDecodeFailed    = "decode_failed"
+	CodeSalvaged        = "salvaged"
+	CodeDroppedRecords  = "dropped_records"
+	CodeSortedRecords   = "sorted_records"
+	CodeAddedFileId     = "added_file_id"
+	CodeAddedLap        = "added_lap"
+	CodeAddedSession    = "added_session"
+	CodeAddedActivity   = "added_activity"
+	CodeRewrittenHeader = "rewritten_header"
+	CodeRebuiltHeader   = "rebuilt_header"
+)
+
+// Entry is a single step of the repair log.
+type Entry struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Result is the outcome of Repair.
+type Result struct {
+	FIT      []byte  `json:"-"`
+	Size     int     `json:"size"`     // Size of the repaired file in bytes
+	Messages int     `json:"messages"` // Number of messages salvaged from the input
+	Records  int     `json:"records"`  // Number of records in the repaired file
+	Changed  bool    `json:"changed"`  // Whether anything beyond re-encoding was necessary
+	Log      []Entry `json:"log"`
+}
+
+func (r *Result) add(code, message string) {
+	r.Log = append(r.Log, Entry{Code: code, Message: message})
+	if code != CodeSalvaged && code != CodeRewrittenHeader {
+		r.Changed = true
+	}
+}
@@ -0,0 +1,258 @@
+// Package repair salvages what can be decoded from a broken FIT file and writes it out as a new, valid one.
+package repair
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/kyzrfranz/go-fitter/pkg/summary"
+	"github.com/kyzrfranz/go-fitter/pkg/validate"
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/encoder"
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+	"github.com/muktihari/fit/proto"
+)
+
+var ErrNothingToSalvage = errors.New("no FIT messages could be salvaged")
+
+// Repair decodes every message it can from data, ignoring checksums and stopping at the first
+// undecodable byte, fills in missing file_id, lap, session and activity messages from the records
+// and encodes the result as a new FIT file with correct data sizes and CRCs. Chained sequences stay
+// chained, each one is repaired on its own.
+//
+// A header with a wrong size or signature keeps the decoder from reading anything, such a file is
+// decoded again behind a fresh header. Everything after the header is then taken for one sequence.
+//
+// Existing summary messages are kept as they are. Every problem found and every change made is
+// recorded in the returned Result's log.
+func Repair(data []byte) (Result, error) {
+	res := Result{Log: make([]Entry, 0)}
+	logProblems(&res, data)
+
+	fit, err := res.salvage(data)
+	if err == nil && res.Messages == 0 && !validHeader(data) {
+		for _, size := range headerSizes(data) {
+			if len(data) <= size {
+				continue
+			}
+			// An attempt that reads nothing leaves neither its log entries nor a change behind
+			n, changed := len(res.Log), res.Changed
+			res.add(CodeRebuiltHeader, fmt.Sprintf("read the data behind a %d byte header as a new sequence", size))
+			if fit, err = res.salvage(freshHeader(data, size)); err != nil || res.Messages > 0 {
+				break
+			}
+			res.Log, res.Changed = res.Log[:n], changed
+		}
+	}
+	if err != nil {
+		return res, err
+	}
+	if res.Messages == 0 {
+		return res, ErrNothingToSalvage
+	}
+
+	res.FIT = fit
+	res.Size = len(res.FIT)
+	return res, nil
+}
+
+// salvage repairs the sequences the decoder can read from data and returns them encoded.
+func (res *Result) salvage(data []byte) ([]byte, error) {
+	c := &collector{}
+	dec := decoder.New(bytes.NewReader(data),
+		decoder.WithIgnoreChecksum(),
+		decoder.WithMesgListener(c),
+		decoder.WithBroadcastOnly(),
+		decoder.WithBroadcastMesgCopy(),
+	)
+
+	// Chained sequences are repaired one by one and chained again in the output.
+	var buf bytes.Buffer
+	enc := encoder.New(&buf)
+	for seq := 0; dec.Next(); seq++ {
+		protocolVersion := proto.V1
+		if header, err := dec.PeekFileHeader(); err == nil {
+			protocolVersion = header.ProtocolVersion
+		}
+		_, decodeErr := dec.Decode()
+
+		mesgs, hasFileId, hasDevFields := c.take()
+		if len(mesgs) > 0 {
+			if hasDevFields {
+				protocolVersion = max(protocolVersion, proto.V2)
+			}
+			if err := res.repairSequence(enc, seq, mesgs, hasFileId, protocolVersion); err != nil {
+				return nil, err
+			}
+		}
+		if decodeErr != nil {
+			break
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// validHeader tells whether data starts with a header the decoder can read, whatever its data size and CRC.
+func validHeader(data []byte) bool {
+	return len(data) >= 12 && (data[0] == 12 || data[0] == 14) && string(data[8:12]) == ".FIT"
+}
+
+// headerSizes are the sizes the broken header of data may have, the one it claims first.
+func headerSizes(data []byte) []int {
+	if len(data) > 0 && (data[0] == 12 || data[0] == 14) {
+		return []int{int(data[0])}
+	}
+	return []int{14, 12}
+}
+
+// freshHeader replaces the first size bytes of data with a 14 byte header declaring the rest as data.
+// The protocol and profile version are kept if they look sane, the header CRC is left out.
+func freshHeader(data []byte, size int) []byte {
+	body := data[size:]
+	header := make([]byte, 14, 14+len(body))
+	header[0] = 14
+	header[1] = byte(proto.V1)
+	if v := proto.Version(data[1]); v.Major() >= proto.V1.Major() && v.Major() <= proto.Vmax.Major() {
+		header[1] = data[1]
+	}
+	copy(header[2:4], data[2:4])
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(body)))
+	copy(header[8:12], ".FIT")
+	return append(header, body...)
+}
+
+// repairSequence completes the messages salvaged from one FIT sequence and encodes them with enc.
+func (res *Result) repairSequence(enc *encoder.Encoder, seq int, mesgs []proto.Message, hasFileId bool, protocolVersion proto.Version) error {
+	res.Messages += len(mesgs)
+	res.add(CodeSalvaged, fmt.Sprintf("sequence %d: salvaged %d messages", seq, len(mesgs)))
+
+	act := filedef.NewActivity(mesgs...)
+	cleanRecords(res, seq, act)
+	if !hasFileId {
+		addFileId(res, seq, act)
+	}
+	regenerate(res, seq, act)
+
+	fit := act.ToFIT(nil)
+	fit.FileHeader = proto.FileHeader{Size: 14, ProtocolVersion: protocolVersion}
+	if err := activity.Encode(enc, &fit); err != nil {
+		return fmt.Errorf("encode failed: %w", err)
+	}
+	res.add(CodeRewrittenHeader, fmt.Sprintf("sequence %d: wrote %d data bytes with a fresh header and CRC", seq, fit.FileHeader.DataSize))
+
+	res.Records += len(act.Records)
+	return nil
+}
+
+// logProblems records what is structurally wrong with data, as found by the validator.
+func logProblems(res *Result, data []byte) {
+	report := validate.Validate(data, validate.WithMaxIssuesPerCode(5))
+	for _, issue := range report.Issues {
+		if issue.Severity != validate.SeverityError {
+			continue
+		}
+		switch issue.Code {
+		case validate.CodeHeaderInvalid, validate.CodeHeaderCRCMismatch:
+			res.add(CodeHeaderInvalid, issue.Message)
+		case validate.CodeTruncated:
+			res.add(CodeTruncated, issue.Message)
+		case validate.CodeFileCRCMismatch:
+			res.add(CodeCRCMismatch, issue.Message)
+		case validate.CodeDecodeFailed:
+			res.add(CodeDecodeFailed, issue.Message)
+		}
+	}
+}
+
+// cleanRecords drops records without a timestamp and puts the rest in chronological order.
+func cleanRecords(res *Result, seq int, act *filedef.Activity) {
+	n := len(act.Records)
+	act.Records = slices.DeleteFunc(act.Records, func(r *mesgdef.Record) bool { return r.Timestamp.IsZero() })
+	if dropped := n - len(act.Records); dropped > 0 {
+		res.add(CodeDroppedRecords, fmt.Sprintf("sequence %d: dropped %d records without timestamp", seq, dropped))
+	}
+
+	byTime := func(a, b *mesgdef.Record) int { return a.Timestamp.Compare(b.Timestamp) }
+	if !slices.IsSortedFunc(act.Records, byTime) {
+		slices.SortStableFunc(act.Records, byTime)
+		res.add(CodeSortedRecords, fmt.Sprintf("sequence %d: sorted records by timestamp", seq))
+	}
+}
+
+func addFileId(res *Result, seq int, act *filedef.Activity) {
+	var created time.Time
+	if len(act.Records) > 0 {
+		created = act.Records[0].Timestamp
+	}
+	act.FileId = *mesgdef.NewFileId(nil).
+		SetType(typedef.FileActivity).
+		SetManufacturer(typedef.ManufacturerDevelopment).
+		SetTimeCreated(created)
+	res.add(CodeAddedFileId, fmt.Sprintf("sequence %d: added missing file_id message", seq))
+}
+
+// regenerate adds the lap, session and activity messages a truncated recording never got to write.
+func regenerate(res *Result, seq int, act *filedef.Activity) {
+	if len(act.Records) == 0 {
+		return
+	}
+	sport, subSport := summary.Sport(act)
+	last := act.Records[len(act.Records)-1].Timestamp
+
+	if len(act.Laps) == 0 {
+		act.Laps = summary.Laps(act.Records, nil, sport, subSport)
+		res.add(CodeAddedLap, fmt.Sprintf("sequence %d: added a lap covering all records", seq))
+	} else if end := act.Laps[len(act.Laps)-1].Timestamp; last.After(end) {
+		i, _ := slices.BinarySearchFunc(act.Records, end, func(r *mesgdef.Record, t time.Time) int {
+			if r.Timestamp.After(t) {
+				return 1
+			}
+			return -1
+		})
+		lap := summary.Lap(act.Records[i:], len(act.Laps), sport, subSport)
+		act.Laps = append(act.Laps, lap)
+		res.add(CodeAddedLap, fmt.Sprintf("sequence %d: added a lap for %d records after the last lap", seq, len(act.Records)-i))
+	}
+
+	if len(act.Sessions) == 0 {
+		act.Sessions = []*mesgdef.Session{summary.Session(act.Records, 0, 0, len(act.Laps), sport, subSport)}
+		res.add(CodeAddedSession, fmt.Sprintf("sequence %d: added a session covering all records", seq))
+	}
+
+	if act.Activity == nil {
+		act.Activity = summary.Activity(act.Sessions)
+		res.add(CodeAddedActivity, fmt.Sprintf("sequence %d: added missing activity message", seq))
+	}
+}
+
+// collector keeps a copy of every decoded message. The decoder calls it synchronously.
+type collector struct {
+	mesgs     []proto.Message
+	fileId    bool
+	devFields bool
+}
+
+// take returns what was collected for the current sequence and starts over.
+func (c *collector) take() (mesgs []proto.Message, fileId, devFields bool) {
+	mesgs, fileId, devFields = c.mesgs, c.fileId, c.devFields
+	*c = collector{}
+	return mesgs, fileId, devFields
+}
+
+func (c *collector) OnMesg(mesg proto.Message) {
+	if mesg.Num == mesgnum.FileId {
+		c.fileId = true
+	}
+	if len(mesg.DeveloperFields) > 0 {
+		c.devFields = true
+	}
+	c.mesgs = append(c.mesgs, mesg)
+}
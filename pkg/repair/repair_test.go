@@ -0,0 +1,106 @@
+package repair
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/kyzrfranz/go-fitter/pkg/validate"
+)
+
+func sample(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../converters/testdata/activity_poolswim.fit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRepair(t *testing.T) {
+	data := sample(t)
+	modify := func(f func(b []byte) []byte) []byte {
+		return f(slices.Clone(data))
+	}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		wantCodes     []string
+		wantChanged   bool
+		wantSequences int
+		wantErr       error
+	}{
+		{
+			name:          "valid file is only re-encoded",
+			data:          data,
+			wantCodes:     []string{CodeSalvaged, CodeRewrittenHeader},
+			wantSequences: 1,
+		},
+		{
+			name:          "wrong file CRC",
+			data:          modify(func(b []byte) []byte { b[len(b)-1] ^= 0xff; return b }),
+			wantCodes:     []string{CodeCRCMismatch, CodeSalvaged},
+			wantChanged:   true,
+			wantSequences: 1,
+		},
+		{
+			name:          "truncated recording gets its summaries back",
+			data:          data[:len(data)/2],
+			wantCodes:     []string{CodeTruncated, CodeSalvaged, CodeAddedSession, CodeAddedActivity},
+			wantChanged:   true,
+			wantSequences: 1,
+		},
+		{
+			name:          "broken signature",
+			data:          modify(func(b []byte) []byte { copy(b[8:12], "XXXX"); return b }),
+			wantCodes:     []string{CodeHeaderInvalid, CodeRebuiltHeader, CodeSalvaged},
+			wantChanged:   true,
+			wantSequences: 1,
+		},
+		{
+			name:          "chained sequences stay chained",
+			data:          append(slices.Clone(data), data...),
+			wantCodes:     []string{CodeSalvaged, CodeRewrittenHeader},
+			wantSequences: 2,
+		},
+		{name: "garbage", data: []byte("not a FIT file at all"), wantErr: ErrNothingToSalvage},
+		{name: "empty", data: nil, wantErr: ErrNothingToSalvage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Repair(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Repair() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			codes := make([]string, len(res.Log))
+			for i, e := range res.Log {
+				codes[i] = e.Code
+			}
+			for _, code := range tt.wantCodes {
+				if !slices.Contains(codes, code) {
+					t.Errorf("log = %v, want %s", codes, code)
+				}
+			}
+			if res.Changed != tt.wantChanged {
+				t.Errorf("Changed = %v, want %v: %v", res.Changed, tt.wantChanged, codes)
+			}
+			if res.Size != len(res.FIT) || res.Records == 0 {
+				t.Errorf("Size = %d, Records = %d for %d bytes", res.Size, res.Records, len(res.FIT))
+			}
+
+			report := validate.Validate(res.FIT)
+			if !report.Valid {
+				t.Errorf("repaired file is not valid: %+v", report.Issues)
+			}
+			if len(report.Sequences) != tt.wantSequences {
+				t.Errorf("repaired file has %d sequences, want %d", len(report.Sequences), tt.wantSequences)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package summary
+
+import (
+	"math"
+	"time"
+
+	"github.com/kyzrfranz/go-fitter/pkg/activity"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+)
+
+const (
+	// PauseThreshold is the longest gap between two records that still counts as timer time.
+	PauseThreshold = 30 * time.Second
+
+	// elevationHysteresis filters altitude noise out of total ascent and descent, in metres.
+	elevationHysteresis = 1.0
+
+	earthRadius = 6371008.8 // mean earth radius in metres
+)
+
+// stats are the aggregates of a run of records that laps and sessions are built from.
+type stats struct {
+	start, end time.Time
+	elapsed    float64 // seconds
+	timer      float64 // seconds, without pauses longer than PauseThreshold
+	distance   float64 // metres
+
+	maxSpeed float64 // m/s, NaN when unknown
+
+	avgHeartRate, maxHeartRate uint8
+	avgCadence, maxCadence     uint8
+	avgPower, maxPower         uint16
+	normalizedPower            uint16
+
+	ascent, descent  uint16
+	minAlt, maxAlt   float64 // NaN when unknown
+	startLat, endLat int32
+	startLon, endLon int32
+	necLat, necLon   int32
+	swcLat, swcLon   int32
+}
+
+func compute(records []*mesgdef.Record) stats {
+	s := stats{
+		maxSpeed:     math.NaN(),
+		minAlt:       math.NaN(),
+		maxAlt:       math.NaN(),
+		avgHeartRate: basetype.Uint8Invalid, maxHeartRate: basetype.Uint8Invalid,
+		avgCadence: basetype.Uint8Invalid, maxCadence: basetype.Uint8Invalid,
+		avgPower: basetype.Uint16Invalid, maxPower: basetype.Uint16Invalid,
+		normalizedPower: basetype.Uint16Invalid,
+		ascent:          basetype.Uint16Invalid, descent: basetype.Uint16Invalid,
+		startLat: basetype.Sint32Invalid, startLon: basetype.Sint32Invalid,
+		endLat: basetype.Sint32Invalid, endLon: basetype.Sint32Invalid,
+		necLat: basetype.Sint32Invalid, necLon: basetype.Sint32Invalid,
+		swcLat: basetype.Sint32Invalid, swcLon: basetype.Sint32Invalid,
+	}
+	if len(records) == 0 {
+		return s
+	}
+
+	s.start = records[0].Timestamp
+	s.end = records[len(records)-1].Timestamp
+	s.elapsed = s.end.Sub(s.start).Seconds()
+
+	var (
+		hr, cad, pwr          accumulator
+		firstDist, lastDist   = math.NaN(), math.NaN()
+		pathDist              float64
+		prevLat, prevLon      = basetype.Sint32Invalid, basetype.Sint32Invalid
+		prevTime              time.Time
+		altRef                = math.NaN()
+		ascent, descent       float64
+		hasAltitude, hasPower bool
+	)
+
+	for _, rec := range records {
+		if !prevTime.IsZero() {
+			if gap := rec.Timestamp.Sub(prevTime); gap > 0 && gap <= PauseThreshold {
+				s.timer += gap.Seconds()
+			}
+		}
+		prevTime = rec.Timestamp
+
+		if d := rec.DistanceScaled(); !math.IsNaN(d) {
+			if math.IsNaN(firstDist) {
+				firstDist = d
+			}
+			lastDist = d
+		}
+
+		if speed := recordSpeed(rec); !math.IsNaN(speed) && (math.IsNaN(s.maxSpeed) || speed > s.maxSpeed) {
+			s.maxSpeed = speed
+		}
+
+		if rec.HeartRate != basetype.Uint8Invalid {
+			hr.add(float64(rec.HeartRate))
+		}
+		if rec.Cadence != basetype.Uint8Invalid {
+			cad.add(float64(rec.Cadence))
+		}
+		if rec.Power != basetype.Uint16Invalid {
+			pwr.add(float64(rec.Power))
+			hasPower = true
+		}
+
+		if alt := recordAltitude(rec); !math.IsNaN(alt) {
+			hasAltitude = true
+			s.minAlt = nanMin(s.minAlt, alt)
+			s.maxAlt = nanMax(s.maxAlt, alt)
+			switch {
+			case math.IsNaN(altRef):
+				altRef = alt
+			case alt-altRef >= elevationHysteresis:
+				ascent += alt - altRef
+				altRef = alt
+			case altRef-alt >= elevationHysteresis:
+				descent += altRef - alt
+				altRef = alt
+			}
+		}
+
+		if rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid {
+			if s.startLat == basetype.Sint32Invalid {
+				s.startLat, s.startLon = rec.PositionLat, rec.PositionLong
+				s.necLat, s.necLon = rec.PositionLat, rec.PositionLong
+				s.swcLat, s.swcLon = rec.PositionLat, rec.PositionLong
+			}
+			s.endLat, s.endLon = rec.PositionLat, rec.PositionLong
+			s.necLat, s.necLon = max(s.necLat, rec.PositionLat), max(s.necLon, rec.PositionLong)
+			s.swcLat, s.swcLon = min(s.swcLat, rec.PositionLat), min(s.swcLon, rec.PositionLong)
+			if prevLat != basetype.Sint32Invalid {
+				pathDist += Distance(prevLat, prevLon, rec.PositionLat, rec.PositionLong)
+			}
+			prevLat, prevLon = rec.PositionLat, rec.PositionLong
+		}
+	}
+
+	if !math.IsNaN(firstDist) {
+		s.distance = lastDist - firstDist
+	} else {
+		s.distance = pathDist
+	}
+	if s.timer == 0 {
+		s.timer = s.elapsed
+	}
+
+	if hr.count > 0 {
+		s.avgHeartRate, s.maxHeartRate = uint8(math.Round(hr.mean())), uint8(hr.max)
+	}
+	if cad.count > 0 {
+		s.avgCadence, s.maxCadence = uint8(math.Round(cad.mean())), uint8(cad.max)
+	}
+	if hasPower {
+		s.avgPower, s.maxPower = uint16(math.Round(pwr.mean())), uint16(pwr.max)
+		if np := activity.NormalizedPower(records); np > 0 {
+			s.normalizedPower = uint16(math.Round(np))
+		}
+	}
+	if hasAltitude {
+		s.ascent, s.descent = uint16(math.Round(ascent)), uint16(math.Round(descent))
+	}
+
+	return s
+}
+
+func (s stats) avgSpeed() float64 {
+	if s.timer <= 0 {
+		return math.NaN()
+	}
+	return s.distance / s.timer
+}
+
+type accumulator struct {
+	sum   float64
+	max   float64
+	count int
+}
+
+func (a *accumulator) add(v float64) {
+	a.sum += v
+	a.max = max(a.max, v)
+	a.count++
+}
+
+func (a *accumulator) mean() float64 {
+	return a.sum / float64(a.count)
+}
+
+func recordSpeed(rec *mesgdef.Record) float64 {
+	if v := rec.EnhancedSpeedScaled(); !math.IsNaN(v) {
+		return v
+	}
+	return rec.SpeedScaled()
+}
+
+func recordAltitude(rec *mesgdef.Record) float64 {
+	if v := rec.EnhancedAltitudeScaled(); !math.IsNaN(v) {
+		return v
+	}
+	return rec.AltitudeScaled()
+}
+
+func nanMin(a, b float64) float64 {
+	if math.IsNaN(a) || b < a {
+		return b
+	}
+	return a
+}
+
+func nanMax(a, b float64) float64 {
+	if math.IsNaN(a) || b > a {
+		return b
+	}
+	return a
+}
+
+// Distance returns the great-circle distance in metres between two positions in semicircles.
+func Distance(lat1, lon1, lat2, lon2 int32) float64 {
+	const toRad = math.Pi / (1 << 31)
+	phi1, phi2 := float64(lat1)*toRad, float64(lat2)*toRad
+	dPhi, dLambda := phi2-phi1, float64(lon2-lon1)*toRad
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,203 @@
+package summary
+
+import (
+	"slices"
+	"time"
+
+	"github.com/muktihari/fit/profile/filedef"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// Lap builds a lap message covering records.
+func Lap(records []*mesgdef.Record, index int, sport typedef.Sport, subSport typedef.SubSport) *mesgdef.Lap {
+	s := compute(records)
+
+	return mesgdef.NewLap(nil).
+		SetMessageIndex(typedef.MessageIndex(index)).
+		SetTimestamp(s.end).
+		SetStartTime(s.start).
+		SetEvent(typedef.EventLap).
+		SetEventType(typedef.EventTypeStop).
+		SetLapTrigger(typedef.LapTriggerManual).
+		SetSport(sport).
+		SetSubSport(subSport).
+		SetStartPositionLat(s.startLat).
+		SetStartPositionLong(s.startLon).
+		SetEndPositionLat(s.endLat).
+		SetEndPositionLong(s.endLon).
+		SetTotalElapsedTimeScaled(s.elapsed).
+		SetTotalTimerTimeScaled(s.timer).
+		SetTotalDistanceScaled(s.distance).
+		SetEnhancedAvgSpeedScaled(s.avgSpeed()).
+		SetEnhancedMaxSpeedScaled(s.maxSpeed).
+		SetEnhancedMinAltitudeScaled(s.minAlt).
+		SetEnhancedMaxAltitudeScaled(s.maxAlt).
+		SetAvgHeartRate(s.avgHeartRate).
+		SetMaxHeartRate(s.maxHeartRate).
+		SetAvgCadence(s.avgCadence).
+		SetMaxCadence(s.maxCadence).
+		SetAvgPower(s.avgPower).
+		SetMaxPower(s.maxPower).
+		SetNormalizedPower(s.normalizedPower).
+		SetTotalAscent(s.ascent).
+		SetTotalDescent(s.descent)
+}
+
+// Laps splits records into laps starting at each of starts. Records before the first start belong to the
+// first lap and laps without records are dropped.
+func Laps(records []*mesgdef.Record, starts []time.Time, sport typedef.Sport, subSport typedef.SubSport) []*mesgdef.Lap {
+	laps := make([]*mesgdef.Lap, 0, len(starts)+1)
+	for _, part := range Split(records, starts) {
+		if len(part) > 0 {
+			laps = append(laps, Lap(part, len(laps), sport, subSport))
+		}
+	}
+	return laps
+}
+
+// Split cuts chronologically ordered records at each of starts, which it sorts first. Part i holds the records
+// from starts[i-1] up to but excluding starts[i], so there is one part more than starts and parts may be empty.
+func Split(records []*mesgdef.Record, starts []time.Time) [][]*mesgdef.Record {
+	starts = slices.Clone(starts)
+	slices.SortFunc(starts, func(a, b time.Time) int { return a.Compare(b) })
+
+	parts := make([][]*mesgdef.Record, 0, len(starts)+1)
+	from := 0
+	for _, start := range starts {
+		to := from
+		for to < len(records) && records[to].Timestamp.Before(start) {
+			to++
+		}
+		parts = append(parts, records[from:to])
+		from = to
+	}
+	return append(parts, records[from:])
+}
+
+// Session builds a session message covering records, referring to laps[firstLap:firstLap+numLaps].
+func Session(records []*mesgdef.Record, index, firstLap, numLaps int, sport typedef.Sport, subSport typedef.SubSport) *mesgdef.Session {
+	s := compute(records)
+
+	return mesgdef.NewSession(nil).
+		SetMessageIndex(typedef.MessageIndex(index)).
+		SetTimestamp(s.end).
+		SetStartTime(s.start).
+		SetEvent(typedef.EventSession).
+		SetEventType(typedef.EventTypeStop).
+		SetTrigger(typedef.SessionTriggerActivityEnd).
+		SetSport(sport).
+		SetSubSport(subSport).
+		SetFirstLapIndex(uint16(firstLap)).
+		SetNumLaps(uint16(numLaps)).
+		SetStartPositionLat(s.startLat).
+		SetStartPositionLong(s.startLon).
+		SetEndPositionLat(s.endLat).
+		SetEndPositionLong(s.endLon).
+		SetNecLat(s.necLat).
+		SetNecLong(s.necLon).
+		SetSwcLat(s.swcLat).
+		SetSwcLong(s.swcLon).
+		SetTotalElapsedTimeScaled(s.elapsed).
+		SetTotalTimerTimeScaled(s.timer).
+		SetTotalDistanceScaled(s.distance).
+		SetEnhancedAvgSpeedScaled(s.avgSpeed()).
+		SetEnhancedMaxSpeedScaled(s.maxSpeed).
+		SetEnhancedMinAltitudeScaled(s.minAlt).
+		SetEnhancedMaxAltitudeScaled(s.maxAlt).
+		SetAvgHeartRate(s.avgHeartRate).
+		SetMaxHeartRate(s.maxHeartRate).
+		SetAvgCadence(s.avgCadence).
+		SetMaxCadence(s.maxCadence).
+		SetAvgPower(s.avgPower).
+		SetMaxPower(s.maxPower).
+		SetNormalizedPower(s.normalizedPower).
+		SetTotalAscent(s.ascent).
+		SetTotalDescent(s.descent)
+}
+
+// Activity builds the activity message for sessions.
+func Activity(sessions []*mesgdef.Session) *mesgdef.Activity {
+	act := mesgdef.NewActivity(nil).
+		SetNumSessions(uint16(len(sessions))).
+		SetType(typedef.ActivityManual).
+		SetEvent(typedef.EventActivity).
+		SetEventType(typedef.EventTypeStop)
+
+	var timer float64
+	for _, ses := range sessions {
+		if t := ses.TotalTimerTimeScaled(); t == t { // skip NaN
+			timer += t
+		}
+		if ses.Timestamp.After(act.Timestamp) {
+			act.SetTimestamp(ses.Timestamp)
+		}
+	}
+	act.SetTotalTimerTimeScaled(timer)
+	if len(sessions) > 1 {
+		act.SetType(typedef.ActivityAutoMultiSport)
+	}
+	return act
+}
+
+// Rebuild recomputes laps, sessions and the activity message of act from its records. The start times of the
+// existing sessions and laps are kept as boundaries, and so is the sport of each session.
+func Rebuild(act *filedef.Activity) {
+	type part struct {
+		start    time.Time
+		sport    typedef.Sport
+		subSport typedef.SubSport
+	}
+	parts := make([]part, 0, len(act.Sessions))
+	for _, ses := range act.Sessions {
+		parts = append(parts, part{start: ses.StartTime, sport: ses.Sport, subSport: ses.SubSport})
+	}
+	slices.SortFunc(parts, func(a, b part) int { return a.start.Compare(b.start) })
+	if len(parts) == 0 {
+		sport, subSport := Sport(act)
+		parts = append(parts, part{sport: sport, subSport: subSport})
+	}
+
+	sessionStarts := make([]time.Time, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		sessionStarts = append(sessionStarts, p.start)
+	}
+
+	laps := make([]*mesgdef.Lap, 0, len(act.Laps))
+	sessions := make([]*mesgdef.Session, 0, len(parts))
+	for i, records := range Split(act.Records, sessionStarts) {
+		if len(records) == 0 {
+			continue
+		}
+		first, last := records[0].Timestamp, records[len(records)-1].Timestamp
+		var lapStarts []time.Time
+		for _, lap := range act.Laps {
+			if lap.StartTime.After(first) && !lap.StartTime.After(last) {
+				lapStarts = append(lapStarts, lap.StartTime)
+			}
+		}
+
+		p := parts[i]
+		sessionLaps := Laps(records, lapStarts, p.sport, p.subSport)
+		for _, lap := range sessionLaps {
+			lap.SetMessageIndex(typedef.MessageIndex(len(laps)))
+			laps = append(laps, lap)
+		}
+		sessions = append(sessions, Session(records, len(sessions), len(laps)-len(sessionLaps), len(sessionLaps), p.sport, p.subSport))
+	}
+
+	act.Laps = laps
+	act.Sessions = sessions
+	act.Activity = Activity(sessions)
+}
+
+// Sport returns the sport of the first session, or of the first sport message, or generic.
+func Sport(act *filedef.Activity) (typedef.Sport, typedef.SubSport) {
+	if len(act.Sessions) > 0 {
+		return act.Sessions[0].Sport, act.Sessions[0].SubSport
+	}
+	if len(act.Sports) > 0 {
+		return act.Sports[0].Sport, act.Sports[0].SubSport
+	}
+	return typedef.SportGeneric, typedef.SubSportGeneric
+}
@@ -0,0 +1,168 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/muktihari/fit/kit/datetime"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/factory"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/fieldnum"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+)
+
+// minAbsoluteTime is the smallest FIT timestamp that is an absolute time; smaller values are
+// seconds since the device powered up.
+const minAbsoluteTime = 0x10000000
+
+// lintSequence checks the messages of one decoded sequence.
+func (v *validator) lintSequence(seq int, events []event) {
+	var s *Sequence
+	if seq < len(v.report.Sequences) {
+		s = &v.report.Sequences[seq]
+	}
+
+	var (
+		fileType      = typedef.FileInvalid
+		counts        = make(map[typedef.MesgNum]int)
+		descriptions  = make(map[devFieldRef]bool)
+		undescribed   = make(map[devFieldRef]bool)
+		unknownFields = make(map[typedef.MesgNum]map[byte]bool)
+		lastRecord    time.Time
+	)
+
+	for _, ev := range events {
+		for _, ref := range ev.devFields {
+			key := devFieldRef{developerDataIndex: ref.developerDataIndex, num: ref.num}
+			if !descriptions[key] && !undescribed[key] {
+				undescribed[key] = true
+				v.add(Issue{Severity: SeverityWarning, Code: CodeUndescribedDevField, Sequence: seq, Mesg: ref.mesgNum.String(),
+					Message: fmt.Sprintf("developer field %d of developer data index %d has no field_description", ref.num, ref.developerDataIndex)})
+			}
+		}
+		if ev.mesg == nil {
+			continue
+		}
+
+		mesg := ev.mesg
+		num := typedef.MesgNum(mesg.Num)
+		index := counts[num]
+		counts[num]++
+		name := num.String()
+		if s != nil {
+			s.Messages[name]++
+		}
+
+		// Devices write plenty of undocumented messages, so these are informational only.
+		if strings.HasPrefix(name, "MesgNumInvalid") {
+			if index == 0 {
+				v.add(Issue{Severity: SeverityInfo, Code: CodeUnknownMessage, Sequence: seq, Mesg: name,
+					Message: fmt.Sprintf("message number %d is not defined in the FIT profile", mesg.Num)})
+			}
+			continue
+		}
+
+		for i := range mesg.Fields {
+			if mesg.Fields[i].Name != factory.NameUnknown {
+				continue
+			}
+			if unknownFields[num] == nil {
+				unknownFields[num] = make(map[byte]bool)
+			}
+			if !unknownFields[num][mesg.Fields[i].Num] {
+				unknownFields[num][mesg.Fields[i].Num] = true
+				v.add(Issue{Severity: SeverityInfo, Code: CodeUnknownField, Sequence: seq, Mesg: name,
+					Message: fmt.Sprintf("field number %d is not defined in the FIT profile", mesg.Fields[i].Num)})
+			}
+		}
+
+		switch mesg.Num {
+		case mesgnum.FileId:
+			if fileType == typedef.FileInvalid {
+				fileType = typedef.File(mesg.FieldValueByNum(fieldnum.FileIdType).Uint8())
+				if s != nil {
+					s.FileType = fileType.String()
+				}
+			}
+		case mesgnum.FieldDescription:
+			fd := mesgdef.NewFieldDescription(mesg)
+			descriptions[devFieldRef{developerDataIndex: fd.DeveloperDataIndex, num: fd.FieldDefinitionNumber}] = true
+		case mesgnum.Record:
+			rec := mesgdef.NewRecord(mesg)
+			v.lintRecord(seq, index, rec, lastRecord)
+			if !rec.Timestamp.IsZero() {
+				lastRecord = rec.Timestamp
+			}
+		}
+	}
+
+	if counts[typedef.MesgNumFileId] == 0 && len(events) > 0 {
+		v.add(Issue{Severity: SeverityWarning, Code: CodeMissingFileId, Sequence: seq,
+			Message: "sequence has no file_id message"})
+	}
+
+	if fileType != typedef.FileActivity {
+		return
+	}
+	if counts[typedef.MesgNumRecord] == 0 {
+		v.add(Issue{Severity: SeverityWarning, Code: CodeNoRecords, Sequence: seq,
+			Message: "activity has no record messages"})
+	}
+	if counts[typedef.MesgNumSession] == 0 {
+		v.add(Issue{Severity: SeverityError, Code: CodeMissingSession, Sequence: seq,
+			Message: "activity has no session message"})
+	}
+	if counts[typedef.MesgNumLap] == 0 {
+		v.add(Issue{Severity: SeverityError, Code: CodeMissingLap, Sequence: seq,
+			Message: "activity has no lap message"})
+	}
+	if counts[typedef.MesgNumActivity] == 0 {
+		v.add(Issue{Severity: SeverityWarning, Code: CodeMissingActivity, Sequence: seq,
+			Message: "activity has no activity message"})
+	}
+}
+
+// lintRecord checks timestamps and plausibility of a single record.
+func (v *validator) lintRecord(seq, index int, rec *mesgdef.Record, last time.Time) {
+	issue := func(severity Severity, code, field, format string, args ...any) {
+		idx := index
+		v.add(Issue{Severity: severity, Code: code, Sequence: seq, Mesg: "record", Index: &idx, Field: field,
+			Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ts := rec.Timestamp; !ts.IsZero() {
+		switch {
+		case datetime.ToUint32(ts) < minAbsoluteTime:
+			issue(SeverityWarning, CodeInvalidValue, "timestamp", "timestamp %d is relative to device power up, not an absolute time", datetime.ToUint32(ts))
+		case !last.IsZero() && ts.Before(last):
+			issue(SeverityError, CodeTimestampRegression, "timestamp", "timestamp %s is before the previous record at %s",
+				ts.Format(time.RFC3339), last.Format(time.RFC3339))
+		case !last.IsZero() && ts.Sub(last) > v.options.gapThreshold:
+			issue(SeverityWarning, CodeRecordGap, "timestamp", "%s without records between %s and %s",
+				ts.Sub(last), last.Format(time.RFC3339), ts.Format(time.RFC3339))
+		}
+	}
+
+	if rec.PositionLat != basetype.Sint32Invalid && math.Abs(rec.PositionLatDegrees()) > 90 {
+		issue(SeverityError, CodeInvalidValue, "position_lat", "latitude %.6f is out of range", rec.PositionLatDegrees())
+	}
+	if rec.PositionLong != basetype.Sint32Invalid && math.Abs(rec.PositionLongDegrees()) > 180 {
+		issue(SeverityError, CodeInvalidValue, "position_long", "longitude %.6f is out of range", rec.PositionLongDegrees())
+	}
+	if (rec.PositionLat == basetype.Sint32Invalid) != (rec.PositionLong == basetype.Sint32Invalid) {
+		issue(SeverityWarning, CodeInvalidValue, "position", "record has only one of latitude and longitude")
+	}
+	if rec.HeartRate != basetype.Uint8Invalid && (rec.HeartRate < 20 || rec.HeartRate > 250) {
+		issue(SeverityWarning, CodeInvalidValue, "heart_rate", "heart rate %d bpm is implausible", rec.HeartRate)
+	}
+	if speed := rec.SpeedScaled(); !math.IsNaN(speed) && speed > 100 {
+		issue(SeverityWarning, CodeInvalidValue, "speed", "speed %.2f m/s is implausible", speed)
+	}
+	if rec.Power != basetype.Uint16Invalid && rec.Power > 3000 {
+		issue(SeverityWarning, CodeInvalidValue, "power", "power %d W is implausible", rec.Power)
+	}
+}
@@ -0,0 +1,34 @@
+package validate
+
+import "time"
+
+// Option is Validate's option.
+type Option func(o *options)
+
+type options struct {
+	gapThreshold     time.Duration // Record gaps longer than this are reported
+	maxIssuesPerCode int           // Further issues with the same code are only counted
+}
+
+func defaultOptions() *options {
+	return &options{
+		gapThreshold:     60 * time.Second,
+		maxIssuesPerCode: 20,
+	}
+}
+
+func WithGapThreshold(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.gapThreshold = d
+		}
+	}
+}
+
+func WithMaxIssuesPerCode(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxIssuesPerCode = n
+		}
+	}
+}
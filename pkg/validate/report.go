@@ -0,0 +1,87 @@
+package validate
+
+// Severity tells how bad an Issue is. Only errors make a Report invalid.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Issue codes, stable so clients can match on them.
+const (
+	CodeHeaderInvalid       = "header_invalid"
+	CodeHeaderCRCMismatch   = "header_crc_mismatch"
+	CodeFileCRCMismatch     = "file_crc_mismatch"
+	CodeTruncated           = "truncated"
+	CodeTrailingData        = "trailing_data"
+	CodeDecodeFailed        = "decode_failed"
+	CodeUnknownMessage      = "unknown_message"
+	CodeUnknownField        = "unknown_field"
+	CodeInvalidValue        = "invalid_value"
+	CodeTimestampRegression = "timestamp_regression"
+	CodeMissingFileId       = "missing_file_id"
+	CodeMissingSession      = "missing_session"
+	CodeMissingLap          = "missing_lap"
+	CodeMissingActivity     = "missing_activity"
+	CodeRecordGap           = "record_gap"
+	CodeUndescribedDevField = "developer_field_without_description"
+	CodeNoRecords           = "no_records"
+)
+
+// CRC states reported for file headers and sequences.
+const (
+	CRCOK         = "ok"
+	CRCMismatch   = "mismatch"
+	CRCNotPresent = "not_present" // header without CRC, or data cut off before the CRC
+)
+
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Sequence int      `json:"sequence"`                // index of the FIT sequence in a chained file
+	Offset   int64    `json:"offset,omitempty"`        // byte offset, where known
+	Mesg     string   `json:"message_type,omitempty"`  // e.g. "record"
+	Index    *int     `json:"message_index,omitempty"` // position among messages of the same type
+	Field    string   `json:"field,omitempty"`
+}
+
+// Header is the decoded file header of one FIT sequence.
+type Header struct {
+	Size            int    `json:"size"`
+	ProtocolVersion string `json:"protocol_version"`
+	ProfileVersion  uint16 `json:"profile_version"`
+	DataSize        uint32 `json:"data_size"`
+	DataType        string `json:"data_type"`
+	CRC             string `json:"crc_status"`
+}
+
+type Sequence struct {
+	Index    int            `json:"index"`
+	Offset   int64          `json:"offset"`
+	Header   Header         `json:"header"`
+	CRC      string         `json:"crc_status"`
+	FileType string         `json:"file_type,omitempty"`
+	Messages map[string]int `json:"messages"`
+}
+
+type Report struct {
+	Valid     bool       `json:"valid"`
+	Size      int        `json:"size"`
+	Sequences []Sequence `json:"sequences"`
+	Errors    int        `json:"errors"`
+	Warnings  int        `json:"warnings"`
+	Issues    []Issue    `json:"issues"`
+}
+
+func (r *Report) add(issue Issue) {
+	switch issue.Severity {
+	case SeverityError:
+		r.Errors++
+	case SeverityWarning:
+		r.Warnings++
+	}
+	r.Issues = append(r.Issues, issue)
+}
@@ -0,0 +1,231 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/kit/hash/crc16"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/proto"
+)
+
+// Validate inspects a FIT file and reports everything that is wrong or suspicious about it.
+//
+// It runs two passes: a structural one over the raw bytes (headers, data sizes and CRCs of every
+// chained sequence) and a semantic one over all messages that can be decoded, even from a broken file.
+func Validate(data []byte, opts ...Option) Report {
+	options := defaultOptions()
+	for i := range opts {
+		opts[i](options)
+	}
+
+	v := &validator{
+		options: options,
+		report: Report{
+			Size:      len(data),
+			Sequences: make([]Sequence, 0),
+			Issues:    make([]Issue, 0),
+		},
+		counts: make(map[string]int),
+	}
+
+	v.checkStructure(data)
+	v.checkMessages(data)
+	v.flushSuppressed()
+
+	v.report.Valid = v.report.Errors == 0
+	return v.report
+}
+
+type validator struct {
+	options *options
+	report  Report
+	counts  map[string]int // issues seen per code, to cap the report size
+}
+
+func (v *validator) add(issue Issue) {
+	v.counts[issue.Code]++
+	if v.counts[issue.Code] > v.options.maxIssuesPerCode {
+		if issue.Severity == SeverityError {
+			v.report.Errors++
+		} else if issue.Severity == SeverityWarning {
+			v.report.Warnings++
+		}
+		return
+	}
+	v.report.add(issue)
+}
+
+// flushSuppressed reports how many issues of each code were left out, in the order of their codes so the
+// same file always gets the same report.
+func (v *validator) flushSuppressed() {
+	for _, code := range slices.Sorted(maps.Keys(v.counts)) {
+		if n := v.counts[code]; n > v.options.maxIssuesPerCode {
+			v.report.add(Issue{
+				Severity: SeverityInfo,
+				Code:     code,
+				Message:  fmt.Sprintf("%d more %s issues not listed", n-v.options.maxIssuesPerCode, code),
+			})
+		}
+	}
+}
+
+// checkStructure walks the chained FIT sequences byte by byte.
+func (v *validator) checkStructure(data []byte) {
+	var offset int64
+	for seq := 0; offset < int64(len(data)); seq++ {
+		rest := data[offset:]
+		if len(rest) < 12 || (rest[0] != 12 && rest[0] != 14) || !bytes.Equal(rest[8:12], []byte(proto.DataTypeFIT)) {
+			if seq == 0 {
+				v.add(Issue{Severity: SeverityError, Code: CodeHeaderInvalid, Sequence: seq, Offset: offset,
+					Message: "file does not start with a FIT file header"})
+			} else {
+				v.add(Issue{Severity: SeverityWarning, Code: CodeTrailingData, Sequence: seq, Offset: offset,
+					Message: fmt.Sprintf("%d bytes after the last FIT sequence are not a FIT file", len(rest))})
+			}
+			return
+		}
+
+		size := int64(rest[0])
+		if int64(len(rest)) < size {
+			v.add(Issue{Severity: SeverityError, Code: CodeTruncated, Sequence: seq, Offset: offset,
+				Message: "file ends inside the file header"})
+			return
+		}
+
+		pv := proto.Version(rest[1])
+		s := Sequence{
+			Index:  seq,
+			Offset: offset,
+			Header: Header{
+				Size:            int(size),
+				ProtocolVersion: fmt.Sprintf("%d.%d", pv.Major(), pv.Minor()),
+				ProfileVersion:  binary.LittleEndian.Uint16(rest[2:4]),
+				DataSize:        binary.LittleEndian.Uint32(rest[4:8]),
+				DataType:        string(rest[8:12]),
+				CRC:             CRCNotPresent,
+			},
+			Messages: make(map[string]int),
+		}
+
+		if size == 14 {
+			if crc := binary.LittleEndian.Uint16(rest[12:14]); crc != 0 {
+				s.Header.CRC = CRCOK
+				if sum := crc16Sum(rest[:12]); sum != crc {
+					s.Header.CRC = CRCMismatch
+					v.add(Issue{Severity: SeverityError, Code: CodeHeaderCRCMismatch, Sequence: seq, Offset: offset,
+						Message: fmt.Sprintf("file header CRC is 0x%04X, computed 0x%04X", crc, sum)})
+				}
+			}
+		}
+		if pv.Major() > proto.V2.Major() {
+			v.add(Issue{Severity: SeverityWarning, Code: CodeHeaderInvalid, Sequence: seq, Offset: offset,
+				Message: fmt.Sprintf("unsupported protocol version %s", s.Header.ProtocolVersion)})
+		}
+		if s.Header.DataSize == 0 {
+			v.add(Issue{Severity: SeverityError, Code: CodeHeaderInvalid, Sequence: seq, Offset: offset,
+				Message: "file header declares no data"})
+		}
+
+		end := size + int64(s.Header.DataSize)
+		if int64(len(rest)) < end+2 {
+			s.CRC = CRCNotPresent
+			v.report.Sequences = append(v.report.Sequences, s)
+			v.add(Issue{Severity: SeverityError, Code: CodeTruncated, Sequence: seq, Offset: offset + int64(len(rest)),
+				Message: fmt.Sprintf("file header declares %d data bytes but only %d are present", s.Header.DataSize, max(int64(len(rest))-size, 0))})
+			return
+		}
+
+		s.CRC = CRCOK
+		if crc, sum := binary.LittleEndian.Uint16(rest[end:end+2]), crc16Sum(rest[:end]); crc != sum {
+			s.CRC = CRCMismatch
+			v.add(Issue{Severity: SeverityError, Code: CodeFileCRCMismatch, Sequence: seq, Offset: offset + end,
+				Message: fmt.Sprintf("file CRC is 0x%04X, computed 0x%04X", crc, sum)})
+		}
+
+		v.report.Sequences = append(v.report.Sequences, s)
+		offset += end + 2
+	}
+}
+
+// checkMessages decodes whatever it can, ignoring checksums, and lints the messages of each sequence.
+func (v *validator) checkMessages(data []byte) {
+	c := &collector{}
+	dec := decoder.New(bytes.NewReader(data),
+		decoder.WithIgnoreChecksum(),
+		decoder.WithMesgListener(c),
+		decoder.WithMesgDefListener(c),
+		decoder.WithBroadcastOnly(),
+		decoder.WithBroadcastMesgCopy(),
+	)
+
+	seq := 0
+	for dec.Next() {
+		_, err := dec.Decode()
+		v.lintSequence(seq, c.take())
+		if err != nil {
+			// Broken headers and truncation have already been reported with a better message by checkStructure.
+			reported := v.counts[CodeHeaderInvalid] > 0 || (isEOF(err) && v.counts[CodeTruncated] > 0)
+			if !reported {
+				v.add(Issue{Severity: SeverityError, Code: CodeDecodeFailed, Sequence: seq, Message: err.Error()})
+			}
+			return
+		}
+		seq++
+	}
+}
+
+func isEOF(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func crc16Sum(b []byte) uint16 {
+	h := crc16.New()
+	_, _ = h.Write(b)
+	return h.Sum16()
+}
+
+// devFieldRef identifies a developer field by developer data index and field number.
+type devFieldRef struct {
+	mesgNum            typedef.MesgNum
+	developerDataIndex uint8
+	num                uint8
+}
+
+// event is either a decoded message or the developer fields announced by a message definition.
+type event struct {
+	mesg      *proto.Message
+	devFields []devFieldRef
+}
+
+// collector gathers the events of the sequence currently being decoded, in order of arrival.
+// The decoder calls it synchronously.
+type collector struct {
+	events []event
+}
+
+func (c *collector) OnMesg(mesg proto.Message) { c.events = append(c.events, event{mesg: &mesg}) }
+
+// OnMesgDef copies what we need right away, the decoder reuses the definition's slices.
+func (c *collector) OnMesgDef(mesgDef proto.MessageDefinition) {
+	if len(mesgDef.DeveloperFieldDefinitions) == 0 {
+		return
+	}
+	refs := make([]devFieldRef, len(mesgDef.DeveloperFieldDefinitions))
+	for i, d := range mesgDef.DeveloperFieldDefinitions {
+		refs[i] = devFieldRef{mesgNum: mesgDef.MesgNum, developerDataIndex: d.DeveloperDataIndex, num: d.Num}
+	}
+	c.events = append(c.events, event{devFields: refs})
+}
+
+func (c *collector) take() []event {
+	events := c.events
+	c.events = nil
+	return events
+}
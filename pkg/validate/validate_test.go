@@ -0,0 +1,141 @@
+package validate
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/encoder"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/proto"
+)
+
+var t0 = time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+// activity returns the messages of a short activity, records at the given seconds after t0 followed by
+// a lap, a session and an activity message when summaries is set.
+func activity(seconds []int, summaries bool) []proto.Message {
+	mesgs := []proto.Message{
+		mesgdef.NewFileId(nil).SetType(typedef.FileActivity).SetManufacturer(typedef.ManufacturerDevelopment).
+			SetTimeCreated(t0).ToMesg(nil),
+	}
+	for _, s := range seconds {
+		mesgs = append(mesgs, mesgdef.NewRecord(nil).SetTimestamp(t0.Add(time.Duration(s)*time.Second)).
+			SetHeartRate(140).ToMesg(nil))
+	}
+	if summaries {
+		end := t0.Add(time.Duration(seconds[len(seconds)-1]) * time.Second)
+		mesgs = append(mesgs,
+			mesgdef.NewLap(nil).SetStartTime(t0).SetTimestamp(end).ToMesg(nil),
+			mesgdef.NewSession(nil).SetStartTime(t0).SetTimestamp(end).SetSport(typedef.SportRunning).ToMesg(nil),
+			mesgdef.NewActivity(nil).SetTimestamp(end).SetNumSessions(1).ToMesg(nil))
+	}
+	return mesgs
+}
+
+func encode(t *testing.T, mesgs []proto.Message, opts ...encoder.Option) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	opts = append([]encoder.Option{encoder.WithProtocolVersion(proto.V2)}, opts...)
+	if err := encoder.New(&buf, opts...).Encode(&proto.FIT{Messages: mesgs}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// unchecked lets the encoder write developer fields without a description, which its own validator refuses.
+type unchecked struct{}
+
+func (unchecked) Validate(*proto.Message) error { return nil }
+func (unchecked) Reset()                        {}
+
+// withDevField adds a developer field to the first record, and its description when described is set.
+func withDevField(mesgs []proto.Message, described bool) []proto.Message {
+	mesgs = slices.Clone(mesgs)
+	mesgs[1].DeveloperFields = []proto.DeveloperField{{Num: 0, DeveloperDataIndex: 0, Value: proto.Uint8(5)}}
+	if !described {
+		return mesgs
+	}
+	return slices.Insert(mesgs, 1,
+		mesgdef.NewDeveloperDataId(nil).SetDeveloperDataIndex(0).SetApplicationId(make([]byte, 16)).ToMesg(nil),
+		mesgdef.NewFieldDescription(nil).SetDeveloperDataIndex(0).SetFieldDefinitionNumber(0).
+			SetFitBaseTypeId(basetype.Uint8).SetFieldName([]string{"flow"}).ToMesg(nil))
+}
+
+func TestValidate(t *testing.T) {
+	valid := encode(t, activity([]int{0, 1, 2, 3}, true))
+	modify := func(f func(b []byte)) []byte {
+		b := slices.Clone(valid)
+		f(b)
+		return b
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantValid bool
+		wantCodes []string
+	}{
+		{name: "valid", data: valid, wantValid: true},
+		{name: "truncated", data: valid[:len(valid)-30], wantCodes: []string{CodeTruncated, CodeMissingSession, CodeMissingActivity}},
+		{name: "file CRC mismatch", data: modify(func(b []byte) { b[len(b)-1] ^= 0xff }), wantCodes: []string{CodeFileCRCMismatch}},
+		{name: "header CRC mismatch", data: modify(func(b []byte) { b[12] ^= 0xff }), wantCodes: []string{CodeHeaderCRCMismatch, CodeFileCRCMismatch}},
+		{name: "not a FIT file", data: []byte("hello, world"), wantCodes: []string{CodeHeaderInvalid}},
+		{name: "trailing data", data: append(slices.Clone(valid), "hello, world"...), wantValid: true, wantCodes: []string{CodeTrailingData}},
+		{name: "no session or lap", data: encode(t, activity([]int{0, 1, 2, 3}, false)),
+			wantCodes: []string{CodeMissingSession, CodeMissingLap, CodeMissingActivity}},
+		{name: "timestamps going back", data: encode(t, activity([]int{0, 2, 1, 3}, true)), wantCodes: []string{CodeTimestampRegression}},
+		{name: "undescribed developer field", data: encode(t, withDevField(activity([]int{0, 1, 2, 3}, true), false),
+			encoder.WithMessageValidator(unchecked{})),
+			wantValid: true, wantCodes: []string{CodeUndescribedDevField}},
+		{name: "described developer field", data: encode(t, withDevField(activity([]int{0, 1, 2, 3}, true), true)), wantValid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Validate(tt.data)
+			var codes []string
+			for _, issue := range report.Issues {
+				codes = append(codes, issue.Code)
+			}
+			if report.Valid != tt.wantValid {
+				t.Errorf("Valid = %v, want %v: %v", report.Valid, tt.wantValid, codes)
+			}
+			if !slices.Equal(codes, tt.wantCodes) {
+				t.Errorf("issues = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestValidateSuppressed(t *testing.T) {
+	// Every other record goes two minutes back in time, the one after it six minutes ahead
+	var seconds []int
+	for i := range 10 {
+		seconds = append(seconds, i*240+120, i*240)
+	}
+	data := encode(t, activity(seconds, true))
+
+	report := Validate(data, WithMaxIssuesPerCode(2))
+	var suppressed []string
+	for _, issue := range report.Issues {
+		if issue.Severity == SeverityInfo {
+			suppressed = append(suppressed, issue.Code)
+		}
+	}
+	if want := []string{CodeRecordGap, CodeTimestampRegression}; !slices.Equal(suppressed, want) {
+		t.Errorf("suppressed = %v, want %v", suppressed, want)
+	}
+	if report.Errors != 10 || len(report.Issues) != 2+2+2 {
+		t.Errorf("%d errors, %d issues listed, want 10 and 6: %+v", report.Errors, len(report.Issues), report.Issues)
+	}
+
+	for range 10 {
+		if again := Validate(data, WithMaxIssuesPerCode(2)); !reflect.DeepEqual(again, report) {
+			t.Fatalf("Validate() = %+v, then %+v", report, again)
+		}
+	}
+}